@@ -0,0 +1,126 @@
+package echo_test
+
+import (
+	"bytes"
+	"io/ioutil"
+	"net/http"
+	"testing"
+
+	"github.com/golang/protobuf/proto"
+	"github.com/stretchr/testify/assert"
+	"github.com/vmihailenco/msgpack"
+
+	. "github.com/webx-top/echo"
+	test "github.com/webx-top/echo/testing"
+)
+
+type protoUser struct {
+	Name string `protobuf:"bytes,1,opt,name=name,proto3" json:"name,omitempty"`
+	Age  int32  `protobuf:"varint,2,opt,name=age,proto3" json:"age,omitempty"`
+}
+
+func (m *protoUser) Reset()         { *m = protoUser{} }
+func (m *protoUser) String() string { return proto.CompactTextString(m) }
+func (*protoUser) ProtoMessage()    {}
+
+type msgpackUser struct {
+	Name string `msgpack:"name"`
+	Age  int    `msgpack:"age"`
+}
+
+func postBody(contentType string, body []byte) func(*http.Request) {
+	return func(req *http.Request) {
+		req.Header.Set(HeaderContentType, contentType)
+		req.Body = ioutil.NopCloser(bytes.NewReader(body))
+	}
+}
+
+func TestBindProtobuf(t *testing.T) {
+	e := New()
+	var bound *protoUser
+	e.Post(`/`, func(c Context) error {
+		m := &protoUser{}
+		if err := c.MustBind(m); err != nil {
+			return err
+		}
+		bound = m
+		m.Age++
+		return c.ProtoBuf(m)
+	})
+	e.RebuildRouter()
+
+	body, err := proto.Marshal(&protoUser{Name: `tom`, Age: 18})
+	assert.NoError(t, err)
+	for _, contentType := range []string{MIMEApplicationProtobuf, MIMEApplicationXProtobuf} {
+		bound = nil
+		rec := test.Request(POST, `/`, e, postBody(contentType, body))
+		assert.Equal(t, http.StatusOK, rec.Code, contentType)
+		assert.Equal(t, &protoUser{Name: `tom`, Age: 19}, bound, contentType)
+		assert.Equal(t, MIMEApplicationXProtobuf, rec.Header().Get(HeaderContentType))
+		rendered := &protoUser{}
+		assert.NoError(t, proto.Unmarshal(rec.Body.Bytes(), rendered))
+		assert.Equal(t, &protoUser{Name: `tom`, Age: 19}, rendered)
+	}
+}
+
+func TestBindMsgpack(t *testing.T) {
+	e := New()
+	var bound *msgpackUser
+	e.Post(`/`, func(c Context) error {
+		m := &msgpackUser{}
+		if err := c.MustBind(m); err != nil {
+			return err
+		}
+		bound = m
+		m.Age++
+		return c.MsgPack(m)
+	})
+	e.RebuildRouter()
+
+	body, err := msgpack.Marshal(&msgpackUser{Name: `tom`, Age: 18})
+	assert.NoError(t, err)
+	for _, contentType := range []string{MIMEApplicationMsgpack, MIMEApplicationXMsgpack} {
+		bound = nil
+		rec := test.Request(POST, `/`, e, postBody(contentType, body))
+		assert.Equal(t, http.StatusOK, rec.Code, contentType)
+		assert.Equal(t, &msgpackUser{Name: `tom`, Age: 19}, bound, contentType)
+		assert.Equal(t, MIMEApplicationMsgpack, rec.Header().Get(HeaderContentType))
+		rendered := &msgpackUser{}
+		assert.NoError(t, msgpack.Unmarshal(rec.Body.Bytes(), rendered))
+		assert.Equal(t, &msgpackUser{Name: `tom`, Age: 19}, rendered)
+	}
+}
+
+func TestRenderProtobufMsgpack(t *testing.T) {
+	e := New()
+	e.Get(`/`, func(c Context) error {
+		c.SetAuto(true)
+		if c.Format() == `protobuf` {
+			return c.Render(``, &protoUser{Name: `tom`, Age: 18})
+		}
+		return c.Render(``, &msgpackUser{Name: `tom`, Age: 18})
+	})
+	e.RebuildRouter()
+	accept := func(v string) func(*http.Request) {
+		return func(req *http.Request) {
+			req.Header.Set(HeaderAccept, v)
+		}
+	}
+
+	rec := test.Request(GET, `/`, e, accept(MIMEApplicationProtobuf))
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.Equal(t, MIMEApplicationXProtobuf, rec.Header().Get(HeaderContentType))
+	pu := &protoUser{}
+	assert.NoError(t, proto.Unmarshal(rec.Body.Bytes(), pu))
+	assert.Equal(t, &protoUser{Name: `tom`, Age: 18}, pu)
+
+	rec = test.Request(GET, `/`, e, accept(MIMEApplicationXMsgpack))
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.Equal(t, MIMEApplicationMsgpack, rec.Header().Get(HeaderContentType))
+	// 与 json 一样输出 Data 包装的数据
+	mu := &struct {
+		Data msgpackUser
+	}{}
+	assert.NoError(t, msgpack.Unmarshal(rec.Body.Bytes(), mu))
+	assert.Equal(t, msgpackUser{Name: `tom`, Age: 18}, mu.Data)
+}
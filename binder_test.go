@@ -57,3 +57,11 @@ func TestMapToStruct2(t *testing.T) {
 		IDs: `1,2,3`,
 	}, m)
 }
+
+func TestBindProtobufTarget(t *testing.T) {
+	// 绑定目标类型错误属于程序错误，不应作为 400 返回
+	err := bindProtobuf(&TestForm{}, nil)
+	assert.Equal(t, ErrNotProtoMessage, err)
+	_, ok := err.(*HTTPError)
+	assert.False(t, ok)
+}
@@ -44,22 +44,35 @@ const (
 	// Media types
 	//-------------
 
-	MIMEApplicationJSON                  = "application/json"
-	MIMEApplicationJSONCharsetUTF8       = MIMEApplicationJSON + "; " + CharsetUTF8
-	MIMEApplicationJavaScript            = "application/javascript"
-	MIMEApplicationJavaScriptCharsetUTF8 = MIMEApplicationJavaScript + "; " + CharsetUTF8
-	MIMEApplicationXML                   = "application/xml"
-	MIMEApplicationXMLCharsetUTF8        = MIMEApplicationXML + "; " + CharsetUTF8
-	MIMEApplicationForm                  = "application/x-www-form-urlencoded"
-	MIMEApplicationProtobuf              = "application/protobuf"
-	MIMEApplicationMsgpack               = "application/msgpack"
-	MIMETextHTML                         = "text/html"
-	MIMETextHTMLCharsetUTF8              = MIMETextHTML + "; " + CharsetUTF8
-	MIMETextPlain                        = "text/plain"
-	MIMETextPlainCharsetUTF8             = MIMETextPlain + "; " + CharsetUTF8
-	MIMEMultipartForm                    = "multipart/form-data"
-	MIMEOctetStream                      = "application/octet-stream"
-	MIMEEventStream                      = "text/event-stream"
+	MIMEApplicationJSON                   = "application/json"
+	MIMEApplicationJSONCharsetUTF8        = MIMEApplicationJSON + "; " + CharsetUTF8
+	MIMEApplicationJavaScript             = "application/javascript"
+	MIMEApplicationJavaScriptCharsetUTF8  = MIMEApplicationJavaScript + "; " + CharsetUTF8
+	MIMEApplicationXML                    = "application/xml"
+	MIMEApplicationXMLCharsetUTF8         = MIMEApplicationXML + "; " + CharsetUTF8
+	MIMEApplicationForm                   = "application/x-www-form-urlencoded"
+	MIMEApplicationJSONPatch              = "application/json-patch+json"
+	MIMEApplicationMergePatch             = "application/merge-patch+json"
+	MIMEApplicationProblemJSON            = "application/problem+json"
+	MIMEApplicationProblemJSONCharsetUTF8 = MIMEApplicationProblemJSON + "; " + CharsetUTF8
+	MIMEApplicationProblemXML             = "application/problem+xml"
+	MIMEApplicationProblemXMLCharsetUTF8  = MIMEApplicationProblemXML + "; " + CharsetUTF8
+	MIMEApplicationProtobuf               = "application/protobuf"
+	MIMEApplicationXProtobuf              = "application/x-protobuf"
+	MIMEApplicationMsgpack                = "application/msgpack"
+	MIMEApplicationXMsgpack               = "application/x-msgpack"
+	MIMETextCSV                           = "text/csv"
+	MIMETextCSVCharsetUTF8                = MIMETextCSV + "; " + CharsetUTF8
+	MIMEApplicationYAML                   = "application/x-yaml"
+	MIMEApplicationYAMLCharsetUTF8        = MIMEApplicationYAML + "; " + CharsetUTF8
+	MIMEApplicationNDJSON                 = "application/x-ndjson"
+	MIMETextHTML                          = "text/html"
+	MIMETextHTMLCharsetUTF8               = MIMETextHTML + "; " + CharsetUTF8
+	MIMETextPlain                         = "text/plain"
+	MIMETextPlainCharsetUTF8              = MIMETextPlain + "; " + CharsetUTF8
+	MIMEMultipartForm                     = "multipart/form-data"
+	MIMEOctetStream                       = "application/octet-stream"
+	MIMEEventStream                       = "text/event-stream"
 
 	//---------
 	// Charset
@@ -73,13 +86,19 @@ const (
 
 	HeaderAccept              = "Accept"
 	HeaderAcceptEncoding      = "Accept-Encoding"
+	HeaderAcceptLanguage      = "Accept-Language"
 	HeaderAllow               = "Allow"
 	HeaderAuthorization       = "Authorization"
 	HeaderContentDisposition  = "Content-Disposition"
 	HeaderContentEncoding     = "Content-Encoding"
 	HeaderContentLength       = "Content-Length"
+	HeaderContentRange        = "Content-Range"
 	HeaderContentType         = "Content-Type"
 	HeaderIfModifiedSince     = "If-Modified-Since"
+	HeaderIfUnmodifiedSince   = "If-Unmodified-Since"
+	HeaderIfMatch             = "If-Match"
+	HeaderIfNoneMatch         = "If-None-Match"
+	HeaderETag                = "ETag"
 	HeaderCookie              = "Cookie"
 	HeaderSetCookie           = "Set-Cookie"
 	HeaderLastModified        = "Last-Modified"
@@ -96,16 +115,19 @@ const (
 	HeaderServer              = "Server"
 	HeaderOrigin              = "Origin"
 	HeaderCacheControl        = "Cache-Control"
+	HeaderExpires             = "Expires"
 
 	// Access control
-	HeaderAccessControlRequestMethod    = "Access-Control-Request-Method"
-	HeaderAccessControlRequestHeaders   = "Access-Control-Request-Headers"
-	HeaderAccessControlAllowOrigin      = "Access-Control-Allow-Origin"
-	HeaderAccessControlAllowMethods     = "Access-Control-Allow-Methods"
-	HeaderAccessControlAllowHeaders     = "Access-Control-Allow-Headers"
-	HeaderAccessControlAllowCredentials = "Access-Control-Allow-Credentials"
-	HeaderAccessControlExposeHeaders    = "Access-Control-Expose-Headers"
-	HeaderAccessControlMaxAge           = "Access-Control-Max-Age"
+	HeaderAccessControlRequestMethod         = "Access-Control-Request-Method"
+	HeaderAccessControlRequestHeaders        = "Access-Control-Request-Headers"
+	HeaderAccessControlRequestPrivateNetwork = "Access-Control-Request-Private-Network"
+	HeaderAccessControlAllowOrigin           = "Access-Control-Allow-Origin"
+	HeaderAccessControlAllowMethods          = "Access-Control-Allow-Methods"
+	HeaderAccessControlAllowHeaders          = "Access-Control-Allow-Headers"
+	HeaderAccessControlAllowCredentials      = "Access-Control-Allow-Credentials"
+	HeaderAccessControlAllowPrivateNetwork   = "Access-Control-Allow-Private-Network"
+	HeaderAccessControlExposeHeaders         = "Access-Control-Expose-Headers"
+	HeaderAccessControlMaxAge                = "Access-Control-Max-Age"
 
 	// Security
 	HeaderStrictTransportSecurity = "Strict-Transport-Security"
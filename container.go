@@ -0,0 +1,168 @@
+/*
+
+   Copyright 2016-present Wenhui Shen <www.webx.top>
+
+   Licensed under the Apache License, Version 2.0 (the "License");
+   you may not use this file except in compliance with the License.
+   You may obtain a copy of the License at
+
+       http://www.apache.org/licenses/LICENSE-2.0
+
+   Unless required by applicable law or agreed to in writing, software
+   distributed under the License is distributed on an "AS IS" BASIS,
+   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+   See the License for the specific language governing permissions and
+   limitations under the License.
+
+*/
+
+package echo
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"reflect"
+	"sync"
+)
+
+// ErrServiceNotFound 容器中没有注册该名称的服务
+var ErrServiceNotFound = errors.New(`service not found`)
+
+// InjectTagName 结构体字段注入所使用的标签名，例如：`inject:"db"`
+var InjectTagName = `inject`
+
+// ScopedFactory 创建请求作用域服务的函数
+type ScopedFactory func(Context) (interface{}, error)
+
+type scopedProvider struct {
+	factory ScopedFactory
+	dispose func(interface{}) error
+}
+
+type scopedInstance struct {
+	name    string
+	value   interface{}
+	dispose func(interface{}) error
+}
+
+// NewContainer 创建依赖注入容器
+func NewContainer() *Container {
+	return &Container{
+		singletons: map[string]interface{}{},
+		scoped:     map[string]*scopedProvider{},
+	}
+}
+
+// Container 依赖注入容器。
+// 单例服务在整个应用中共享；请求作用域服务在每个请求中首次解析时创建，请求结束时释放
+type Container struct {
+	mu         sync.RWMutex
+	singletons map[string]interface{}
+	scoped     map[string]*scopedProvider
+}
+
+// Singleton 注册单例服务(重复注册会覆盖，便于在测试中替换)
+func (s *Container) Singleton(name string, value interface{}) *Container {
+	s.mu.Lock()
+	delete(s.scoped, name)
+	s.singletons[name] = value
+	s.mu.Unlock()
+	return s
+}
+
+// Scoped 注册请求作用域服务。
+// dispose 用于在请求结束时释放服务，未指定时如果服务实现了 io.Closer 则调用其 Close 方法
+func (s *Container) Scoped(name string, factory ScopedFactory, dispose ...func(interface{}) error) *Container {
+	p := &scopedProvider{factory: factory}
+	if len(dispose) > 0 {
+		p.dispose = dispose[0]
+	}
+	s.mu.Lock()
+	delete(s.singletons, name)
+	s.scoped[name] = p
+	s.mu.Unlock()
+	return s
+}
+
+// Remove 删除服务
+func (s *Container) Remove(name string) *Container {
+	s.mu.Lock()
+	delete(s.singletons, name)
+	delete(s.scoped, name)
+	s.mu.Unlock()
+	return s
+}
+
+// Has 是否注册了该名称的服务
+func (s *Container) Has(name string) bool {
+	s.mu.RLock()
+	_, ok := s.singletons[name]
+	if !ok {
+		_, ok = s.scoped[name]
+	}
+	s.mu.RUnlock()
+	return ok
+}
+
+func (s *Container) lookup(name string) (interface{}, *scopedProvider, bool) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	if v, ok := s.singletons[name]; ok {
+		return v, nil, true
+	}
+	if p, ok := s.scoped[name]; ok {
+		return nil, p, true
+	}
+	return nil, nil, false
+}
+
+// Inject 向结构体中带 `inject` 标签的导出字段注入服务。
+// 标签值为服务名称，为空时使用字段名称
+func Inject(c Context, ptr interface{}) error {
+	v := reflect.ValueOf(ptr)
+	if v.Kind() != reflect.Ptr || v.Elem().Kind() != reflect.Struct {
+		return fmt.Errorf(`inject: %T is not a pointer to struct`, ptr)
+	}
+	v = v.Elem()
+	t := v.Type()
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		name, ok := f.Tag.Lookup(InjectTagName)
+		if !ok || len(f.PkgPath) > 0 {
+			continue
+		}
+		if len(name) == 0 {
+			name = f.Name
+		}
+		svc, err := c.Resolve(name)
+		if err != nil {
+			return fmt.Errorf(`inject %s.%s: %v`, t.Name(), f.Name, err)
+		}
+		if svc == nil {
+			continue
+		}
+		sv := reflect.ValueOf(svc)
+		if !sv.Type().AssignableTo(f.Type) {
+			return fmt.Errorf(`inject %s.%s: %T is not assignable to %v`, t.Name(), f.Name, svc, f.Type)
+		}
+		v.Field(i).Set(sv)
+	}
+	return nil
+}
+
+func disposeScoped(instances []*scopedInstance) (err error) {
+	for i := len(instances) - 1; i >= 0; i-- {
+		inst := instances[i]
+		var e error
+		if inst.dispose != nil {
+			e = inst.dispose(inst.value)
+		} else if closer, ok := inst.value.(io.Closer); ok {
+			e = closer.Close()
+		}
+		if e != nil && err == nil {
+			err = fmt.Errorf(`dispose %s: %v`, inst.name, e)
+		}
+	}
+	return
+}
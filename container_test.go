@@ -0,0 +1,70 @@
+package echo_test
+
+import (
+	"errors"
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	. "github.com/webx-top/echo"
+	test "github.com/webx-top/echo/testing"
+)
+
+type testTenant struct {
+	Name   string
+	closed bool
+}
+
+func (t *testTenant) Close() error {
+	t.closed = true
+	return nil
+}
+
+type testInjected struct {
+	Tenant  *testTenant `inject:"tenant"`
+	Version string      `inject:""`
+	Other   string
+}
+
+func TestContainer(t *testing.T) {
+	e := New()
+	var created []*testTenant
+	e.Container().Singleton(`Version`, `v1`).Scoped(`tenant`, func(c Context) (interface{}, error) {
+		tenant := &testTenant{Name: c.Query(`tenant`)}
+		created = append(created, tenant)
+		return tenant, nil
+	})
+	e.Container().Scoped(`fail`, func(c Context) (interface{}, error) {
+		return nil, errors.New(`unavailable`)
+	})
+	e.Get(`/`, func(c Context) error {
+		v := &testInjected{}
+		if err := c.Inject(v); err != nil {
+			return err
+		}
+		tenant, _ := c.Resolve(`tenant`)
+		assert.True(t, tenant == v.Tenant)
+		assert.False(t, v.Tenant.closed)
+		return c.String(v.Tenant.Name + `:` + v.Version)
+	})
+	e.Get(`/fail`, func(c Context) error {
+		_, err := c.Resolve(`fail`)
+		assert.EqualError(t, err, `unavailable`)
+		_, err = c.Resolve(`none`)
+		assert.Equal(t, ErrServiceNotFound, err)
+		return c.NoContent(http.StatusNoContent)
+	})
+	e.RebuildRouter()
+
+	rec := test.Request(GET, `/?tenant=a`, e)
+	assert.Equal(t, `a:v1`, rec.Body.String())
+	rec = test.Request(GET, `/?tenant=b`, e)
+	assert.Equal(t, `b:v1`, rec.Body.String())
+	assert.Len(t, created, 2)
+	assert.True(t, created[0].closed)
+	assert.True(t, created[1].closed)
+
+	rec = test.Request(GET, `/fail`, e)
+	assert.Equal(t, http.StatusNoContent, rec.Code)
+}
@@ -8,6 +8,7 @@ import (
 	"time"
 
 	"github.com/admpub/events"
+	"github.com/golang/protobuf/proto"
 
 	"github.com/webx-top/echo/engine"
 	"github.com/webx-top/echo/logger"
@@ -29,6 +30,11 @@ type Context interface {
 	Commit() error
 	End(succeed bool) error
 
+	//Container
+	Resolve(name string) (interface{}, error)
+	Inject(ptr interface{}) error
+	Dispose() error
+
 	//Standard Context
 	StdContext() context.Context
 	SetStdContext(context.Context)
@@ -39,6 +45,7 @@ type Context interface {
 	SetTranslator(Translator)
 	Request() engine.Request
 	Response() engine.Response
+	SetResponse(engine.Response)
 	Handle(Context) error
 	Logger() logger.Logger
 	Object() *xContext
@@ -120,6 +127,11 @@ type Context interface {
 	JSONP(string, interface{}, ...int) error
 	XML(interface{}, ...int) error
 	XMLBlob([]byte, ...int) error
+	ProtoBuf(proto.Message, ...int) error
+	MsgPack(interface{}, ...int) error
+	YAML(interface{}, ...int) error
+	CSV(interface{}, ...int) error
+	NDJSON(interface{}, ...int) error
 	Stream(func(io.Writer) bool)
 	SSEvent(string, chan interface{}) error
 	File(string, ...http.FileSystem) error
@@ -136,6 +148,13 @@ type Context interface {
 	// via `If-Modified-Since` request header. It automatically sets `Content-Type`
 	// and `Last-Modified` response headers.
 	ServeContent(io.Reader, string, time.Time) error
+
+	// CheckPrecondition evaluates the conditional request headers (`If-Match`,
+	// `If-Unmodified-Since`, `If-None-Match`, `If-Modified-Since`) against the
+	// current `ETag` and last modification time of the resource.
+	// It returns `ErrPreconditionFailed` or `ErrNotModified` without writing the
+	// response; the default HTTP error handler sends the latter as a bodyless 304.
+	CheckPrecondition(etag string, lastModified time.Time) error
 	ServeCallbackContent(func(Context) (io.Reader, error), string, time.Time) error
 
 	//----------------
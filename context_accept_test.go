@@ -71,3 +71,40 @@ func TestAccept(t *testing.T) {
 	}
 	assert.Equal(t, Dump(expected, false), Dump(a, false))
 }
+
+func TestParseAcceptQuality(t *testing.T) {
+	r := ParseAcceptQuality(`en;q=0.5, zh-CN, fr;q=0`)
+	assert.Equal(t, 3, len(r))
+	assert.Equal(t, `zh-cn`, r[0].Value)
+	assert.Equal(t, 1.0, r[0].Q)
+	assert.Equal(t, `en`, r[1].Value)
+	assert.Equal(t, 0.5, r[1].Q)
+	assert.Equal(t, `fr`, r[2].Value)
+	assert.Equal(t, 0.0, r[2].Q)
+}
+
+func TestNegotiateFormat(t *testing.T) {
+	e := New()
+	cases := map[string]string{
+		`application/json, text/javascript, */*; q=0.01`:                  `json`,
+		`text/html,application/xhtml+xml,application/xml;q=0.9,*/*;q=0.8`: `html`,
+		`*/*`: `html`,
+		``:    `html`,
+		`application/xml;q=0.5, application/json;q=0.9`: `json`,
+		`application/vnd.example.v2+json`:               `json`,
+		`application/json;q=0, text/*`:                  `html`,
+		`application/x-ndjson, application/json;q=0.8`:  `ndjson`,
+	}
+	for accept, expected := range cases {
+		format, ok := e.NegotiateFormat(accept)
+		assert.True(t, ok, accept)
+		assert.Equal(t, expected, format, accept)
+	}
+	format, ok := e.NegotiateFormat(`application/pdf`)
+	assert.False(t, ok)
+	assert.Equal(t, `html`, format)
+
+	e.SetFormatWeight(`json`, 0.5)
+	format, _ = e.NegotiateFormat(`application/json, application/xml`)
+	assert.Equal(t, `xml`, format)
+}
@@ -40,11 +40,14 @@ type xContext struct {
 	sessionOptions      *SessionOptions
 	withFormatExtension bool
 	format              string
+	formatResolved      bool
 	code                int
 	preResponseHook     []func() error
 	dataEngine          Data
 	accept              *Accepts
 	auto                bool
+	scoped              []*scopedInstance
+	scopedIndex         map[string]*scopedInstance
 }
 
 // NewContext creates a Context object.
@@ -170,9 +173,12 @@ func (c *xContext) Reset(req engine.Request, res engine.Response) {
 	c.sessionOptions = nil
 	c.withFormatExtension = false
 	c.format = ""
+	c.formatResolved = false
 	c.code = 0
 	c.auto = false
 	c.preResponseHook = nil
+	c.scoped = nil
+	c.scopedIndex = nil
 	c.accept = nil
 	c.dataEngine = NewData(c)
 	// NOTE: Don't reset because it has to have length c.echo.maxParam at all times
@@ -215,6 +221,16 @@ func (c *xContext) SetValidator(v Validator) {
 	c.Validator = v
 }
 
+// Validate 验证数据，并使用当前语种翻译字段错误信息
+func (c *xContext) Validate(i interface{}, args ...string) ValidateResult {
+	r := c.Validator.Validate(i, args...)
+	if errs := r.Errors(); len(errs) > 0 {
+		errs.Translate(c)
+		r.SetError(errs[0])
+	}
+	return r
+}
+
 // SetRenderer registers an HTML template renderer.
 func (c *xContext) SetRenderer(r Renderer) {
 	c.renderer = r
@@ -238,6 +254,7 @@ func (c *xContext) ToStringSlice(v []string) param.StringSlice {
 
 func (c *xContext) SetFormat(format string) {
 	c.format = format
+	c.formatResolved = len(format) > 0
 }
 
 func (c *xContext) WithFormatExtension(on bool) {
@@ -0,0 +1,42 @@
+package echo
+
+// Resolve 从容器中解析服务。请求作用域服务在同一个请求中只创建一次
+func (c *xContext) Resolve(name string) (interface{}, error) {
+	if inst, ok := c.scopedIndex[name]; ok {
+		return inst.value, nil
+	}
+	value, provider, ok := c.echo.container.lookup(name)
+	if !ok {
+		return nil, ErrServiceNotFound
+	}
+	if provider == nil {
+		return value, nil
+	}
+	value, err := provider.factory(c)
+	if err != nil {
+		return nil, err
+	}
+	inst := &scopedInstance{name: name, value: value, dispose: provider.dispose}
+	if c.scopedIndex == nil {
+		c.scopedIndex = map[string]*scopedInstance{}
+	}
+	c.scopedIndex[name] = inst
+	c.scoped = append(c.scoped, inst)
+	return value, nil
+}
+
+// Inject 向结构体字段注入服务
+func (c *xContext) Inject(ptr interface{}) error {
+	return Inject(c, ptr)
+}
+
+// Dispose 按创建的相反顺序释放本次请求中创建的服务
+func (c *xContext) Dispose() error {
+	if len(c.scoped) == 0 {
+		return nil
+	}
+	err := disposeScoped(c.scoped)
+	c.scoped = nil
+	c.scopedIndex = nil
+	return err
+}
@@ -201,8 +201,9 @@ func (c *xContext) Method() string {
 }
 
 func (c *xContext) Format() string {
-	if len(c.format) == 0 {
+	if !c.formatResolved {
 		c.format = c.ResolveFormat()
+		c.formatResolved = true
 	}
 	return c.format
 }
@@ -272,6 +273,9 @@ func (c *xContext) ResolveContentType() string {
 // a Request.Format attribute, specifically `html`, `xml`, `json`, or `txt`,
 // returning a default of `html` when Accept header cannot be mapped to a
 // value above.
+// The Accept header is negotiated by q-value against the registered accept
+// formats and `Vary: Accept` is added to the response.
+// When `Echo.StrictAccept` is on and nothing is acceptable, an empty string is returned.
 func (c *xContext) ResolveFormat() string {
 	if format := c.Query(`format`); len(format) > 0 {
 		return format
@@ -282,17 +286,14 @@ func (c *xContext) ResolveFormat() string {
 			return strings.ToLower(urlPath[pos+1:])
 		}
 	}
-
-	info := c.Accept()
-	for _, accept := range info.Type {
-		if format, ok := c.echo.acceptFormats[accept.Mime]; ok {
-			return format
-		}
+	if c.response != nil {
+		AddVary(c.response.Header(), HeaderAccept)
 	}
-	if format, ok := c.echo.acceptFormats[`*`]; ok {
-		return format
+	format, ok := c.echo.NegotiateFormat(c.Header(HeaderAccept))
+	if !ok && c.echo.strictAccept {
+		return ``
 	}
-	return `html`
+	return format
 }
 
 func (c *xContext) Accept() *Accepts {
@@ -2,14 +2,20 @@ package echo
 
 import (
 	"bytes"
+	"encoding/csv"
 	"encoding/xml"
 	"io"
 	"net/http"
 	"os"
 	"path/filepath"
+	"reflect"
 	"time"
 	"unicode"
 
+	"github.com/golang/protobuf/proto"
+	"github.com/vmihailenco/msgpack"
+	"gopkg.in/yaml.v2"
+
 	"github.com/webx-top/echo/encoding/json"
 	"github.com/webx-top/echo/engine"
 )
@@ -19,11 +25,19 @@ func (c *xContext) Response() engine.Response {
 	return c.response
 }
 
+// SetResponse 替换当前响应(例如中间件使用 engine.BufferedResponse 缓冲输出)
+func (c *xContext) SetResponse(res engine.Response) {
+	c.response = res
+}
+
 // Render renders a template with data and sends a text/html response with status
 // code. Templates can be registered using `Echo.SetRenderer()`.
 func (c *xContext) Render(name string, data interface{}, codes ...int) (err error) {
 	if c.auto {
 		format := c.Format()
+		if len(format) == 0 {
+			return ErrNotAcceptable
+		}
 		if render, ok := c.echo.formatRenderers[format]; ok && render != nil {
 			switch v := data.(type) {
 			case Data: //Skip
@@ -139,6 +153,142 @@ func (c *xContext) XMLBlob(b []byte, codes ...int) (err error) {
 	return
 }
 
+// ProtoBuf sends a Protocol Buffers response with status code.
+func (c *xContext) ProtoBuf(m proto.Message, codes ...int) (err error) {
+	b, err := proto.Marshal(m)
+	if err != nil {
+		return err
+	}
+	c.response.Header().Set(HeaderContentType, MIMEApplicationXProtobuf)
+	err = c.Blob(b, codes...)
+	return
+}
+
+// MsgPack sends a MessagePack response with status code.
+func (c *xContext) MsgPack(i interface{}, codes ...int) (err error) {
+	b, err := msgpack.Marshal(i)
+	if err != nil {
+		return err
+	}
+	c.response.Header().Set(HeaderContentType, MIMEApplicationMsgpack)
+	err = c.Blob(b, codes...)
+	return
+}
+
+// YAML sends a YAML response with status code.
+func (c *xContext) YAML(i interface{}, codes ...int) (err error) {
+	b, err := yaml.Marshal(i)
+	if err != nil {
+		return err
+	}
+	c.response.Header().Set(HeaderContentType, MIMEApplicationYAMLCharsetUTF8)
+	err = c.Blob(b, codes...)
+	return
+}
+
+// CSV sends a CSV response with status code. The data can be a slice of
+// structs, maps or string slices, a channel or an `Iterator`; channels and
+// iterators are flushed to the client row by row without buffering the whole result.
+// The header row is taken from the `csv` tags of the first item.
+func (c *xContext) CSV(data interface{}, codes ...int) (err error) {
+	c.response.Header().Set(HeaderContentType, MIMETextCSVCharsetUTF8)
+	if err = c.writeStreamHeader(codes...); err != nil {
+		return
+	}
+	w := csv.NewWriter(c.response)
+	stream := isStreamSource(data)
+	var header []string
+	var n int
+	err = Iterate(data, func(item interface{}) error {
+		if n == 0 {
+			header = CSVHeader(item)
+			if len(header) > 0 {
+				if err := w.Write(header); err != nil {
+					return err
+				}
+			}
+		}
+		n++
+		if err := w.Write(CSVRecord(item, header)); err != nil {
+			return err
+		}
+		// channel 和 Iterator 逐行发送，slice 每 100 行发送一次
+		if stream || n%100 == 0 {
+			w.Flush()
+			if err := w.Error(); err != nil {
+				return err
+			}
+			c.flush()
+		}
+		return nil
+	})
+	w.Flush()
+	if err == nil {
+		err = w.Error()
+	}
+	return
+}
+
+// NDJSON sends a newline delimited JSON response with status code. Each item
+// of a slice, channel or `Iterator` is written as one line and flushed to the
+// client as soon as it is available.
+func (c *xContext) NDJSON(data interface{}, codes ...int) (err error) {
+	c.response.Header().Set(HeaderContentType, MIMEApplicationNDJSON)
+	if err = c.writeStreamHeader(codes...); err != nil {
+		return
+	}
+	return Iterate(data, func(item interface{}) error {
+		b, err := json.Marshal(item)
+		if err != nil {
+			return err
+		}
+		if _, err = c.response.Write(append(b, '\n')); err != nil {
+			return err
+		}
+		c.flush()
+		return nil
+	})
+}
+
+// flush 将已写入的数据立即发送到客户端
+func (c *xContext) flush() {
+	if flusher, ok := c.response.(http.Flusher); ok {
+		flusher.Flush()
+		return
+	}
+	if flusher, ok := c.response.Writer().(http.Flusher); ok {
+		flusher.Flush()
+	}
+}
+
+// isStreamSource 数据是否为逐条产生的 channel 或 Iterator
+func isStreamSource(data interface{}) bool {
+	switch data.(type) {
+	case Iterator, func() (interface{}, bool):
+		return true
+	case nil:
+		return false
+	}
+	return reflect.ValueOf(data).Kind() == reflect.Chan
+}
+
+func (c *xContext) writeStreamHeader(codes ...int) (err error) {
+	if len(codes) > 0 {
+		c.code = codes[0]
+	}
+	if c.code == 0 {
+		c.code = http.StatusOK
+	}
+	err = c.preResponse()
+	if err != nil {
+		return
+	}
+	c.response.Header().Del(HeaderContentLength)
+	c.response.WriteHeader(c.code)
+	c.response.KeepBody(false)
+	return
+}
+
 func (c *xContext) Stream(step func(w io.Writer) bool) {
 	c.response.Stream(step)
 }
@@ -273,3 +423,48 @@ func (c *xContext) Redirect(url string, codes ...int) error {
 	c.response.Redirect(url, code)
 	return nil
 }
+
+// CheckPrecondition 按照 RFC 7232 检查条件请求。
+// If-Match 或 If-Unmodified-Since 不满足时返回 ErrPreconditionFailed(412)，用于防止并发更新时丢失修改；
+// GET/HEAD 请求的 If-None-Match 或 If-Modified-Since 表明客户端缓存仍然有效时，只设置头部并返回 ErrNotModified，
+// 由 HTTPErrorHandler 输出不带内容的 304 响应
+func (c *xContext) CheckPrecondition(etag string, lastModified time.Time) error {
+	header := c.request.Header()
+	isRead := c.Method() == GET || c.Method() == HEAD
+	lastModified = lastModified.Truncate(time.Second)
+	if ifMatch := header.Get(HeaderIfMatch); len(ifMatch) > 0 {
+		if !ETagMatch(ifMatch, etag, false) {
+			return ErrPreconditionFailed
+		}
+	} else if since := parseHTTPTime(header.Get(HeaderIfUnmodifiedSince)); !since.IsZero() && !lastModified.IsZero() {
+		if lastModified.After(since) {
+			return ErrPreconditionFailed
+		}
+	}
+	var notModified bool
+	if ifNoneMatch := header.Get(HeaderIfNoneMatch); len(ifNoneMatch) > 0 {
+		if ETagMatch(ifNoneMatch, etag, true) {
+			if !isRead {
+				return ErrPreconditionFailed
+			}
+			notModified = true
+		}
+	} else if since := parseHTTPTime(header.Get(HeaderIfModifiedSince)); isRead && !since.IsZero() && !lastModified.IsZero() {
+		notModified = !lastModified.After(since)
+	}
+	if !isRead {
+		return nil
+	}
+	if len(etag) > 0 {
+		c.response.Header().Set(HeaderETag, etag)
+	}
+	if !lastModified.IsZero() {
+		c.response.Header().Set(HeaderLastModified, lastModified.UTC().Format(http.TimeFormat))
+	}
+	if notModified {
+		c.response.Header().Del(HeaderContentType)
+		c.response.Header().Del(HeaderContentLength)
+		return ErrNotModified
+	}
+	return nil
+}
@@ -44,6 +44,12 @@ type CookieOptions struct {
 	Secure   bool
 	HttpOnly bool
 	SameSite string // strict / lax
+
+	// 以下用于 Cookier.SetSigned/GetSigned
+	HashKeys   [][]byte         // 签名密钥，第一个用于签名，其余的旧密钥只用于验证
+	BlockKeys  [][]byte         // 加密密钥(AES-128/192/256)，为空时只签名不加密
+	Serializer CookieSerializer // 默认为 GobCookieSerializer
+	Codec      CookieCodec      // 设置后代替以上选项，例如 encoding/keyring.Keyring
 }
 
 func (c *CookieOptions) Clone() *CookieOptions {
@@ -55,6 +61,8 @@ func (c *CookieOptions) Clone() *CookieOptions {
 type Cookier interface {
 	Get(key string) string
 	Set(key string, val string, args ...interface{}) Cookier
+	SetSigned(key string, val interface{}, args ...interface{}) error
+	GetSigned(key string, dst interface{}) error
 }
 
 //NewCookier create a cookie instance
@@ -184,16 +192,7 @@ func (c *cookie) Set(key string, val string, args ...interface{}) Cookier {
 		cookie.Path(ppath)
 		fallthrough
 	case 1:
-		var liftTime int
-		switch v := args[0].(type) {
-		case int:
-			liftTime = v
-		case int64:
-			liftTime = int(v)
-		case time.Duration:
-			liftTime = int(v.Seconds())
-		}
-		cookie.Expires(liftTime)
+		cookie.Expires(lifeTime(args[0]))
 	}
 	if !found {
 		c.cookies = append(c.cookies, cookie)
@@ -206,3 +205,15 @@ func (c *cookie) Set(key string, val string, args ...interface{}) Cookier {
 	}
 	return c
 }
+
+func lifeTime(v interface{}) int {
+	switch t := v.(type) {
+	case int:
+		return t
+	case int64:
+		return int(t)
+	case time.Duration:
+		return int(t.Seconds())
+	}
+	return 0
+}
@@ -0,0 +1,327 @@
+/*
+
+   Copyright 2016 Wenhui Shen <www.webx.top>
+
+   Licensed under the Apache License, Version 2.0 (the "License");
+   you may not use this file except in compliance with the License.
+   You may obtain a copy of the License at
+
+       http://www.apache.org/licenses/LICENSE-2.0
+
+   Unless required by applicable law or agreed to in writing, software
+   distributed under the License is distributed on an "AS IS" BASIS,
+   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+   See the License for the specific language governing permissions and
+   limitations under the License.
+
+*/
+
+package echo
+
+import (
+	"bytes"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/base64"
+	"encoding/gob"
+	"encoding/json"
+	"errors"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+)
+
+var (
+	ErrCookieNotFound     = errors.New(`cookie: not found`)
+	ErrCookieNoKeys       = errors.New(`cookie: no signing keys`)
+	ErrCookieInvalidValue = errors.New(`cookie: invalid value`)
+	ErrCookieInvalidMAC   = errors.New(`cookie: the value is not valid`)
+	ErrCookieExpired      = errors.New(`cookie: expired`)
+	ErrCookieDecryption   = errors.New(`cookie: the value could not be decrypted`)
+)
+
+// CookieCodec 用于 Cookier.SetSigned/GetSigned 的编解码器。
+// 与 securecookie.Codec 相同，因此也可以使用 encoding/keyring.Keyring
+type CookieCodec interface {
+	Encode(name string, value interface{}) (string, error)
+	Decode(name string, value string, dst interface{}) error
+}
+
+// CookieSerializer 签名 cookie 的值的序列化方式
+type CookieSerializer interface {
+	Serialize(src interface{}) ([]byte, error)
+	Deserialize(src []byte, dst interface{}) error
+}
+
+var (
+	// GobCookieSerializer 使用 encoding/gob 序列化(默认)
+	GobCookieSerializer CookieSerializer = gobCookieSerializer{}
+
+	// JSONCookieSerializer 使用 encoding/json 序列化
+	JSONCookieSerializer CookieSerializer = jsonCookieSerializer{}
+)
+
+type gobCookieSerializer struct{}
+
+func (gobCookieSerializer) Serialize(src interface{}) ([]byte, error) {
+	buf := new(bytes.Buffer)
+	if err := gob.NewEncoder(buf).Encode(src); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+func (gobCookieSerializer) Deserialize(src []byte, dst interface{}) error {
+	return gob.NewDecoder(bytes.NewReader(src)).Decode(dst)
+}
+
+type jsonCookieSerializer struct{}
+
+func (jsonCookieSerializer) Serialize(src interface{}) ([]byte, error) {
+	return json.Marshal(src)
+}
+
+func (jsonCookieSerializer) Deserialize(src []byte, dst interface{}) error {
+	return json.Unmarshal(src, dst)
+}
+
+// NewCookieCodec 创建内置的编解码器。
+// hashKeys 用于 HMAC-SHA256 签名，blockKeys 用于 AES-GCM 加密(长度为 16、24 或 32 字节，为空时不加密)。
+// 第一个密钥用于编码，其余的旧密钥只用于解码，以便轮换密钥
+func NewCookieCodec(hashKeys [][]byte, blockKeys [][]byte, serializer CookieSerializer) CookieCodec {
+	if serializer == nil {
+		serializer = GobCookieSerializer
+	}
+	return &cookieCodec{
+		hashKeys:   hashKeys,
+		blockKeys:  blockKeys,
+		serializer: serializer,
+	}
+}
+
+// cookieCodec 编码后的格式为 "过期时间|base64(数据)|base64(签名)"，
+// 过期时间(Unix 时间戳，0 表示不过期)包含在签名中，解码时检查
+type cookieCodec struct {
+	hashKeys   [][]byte
+	blockKeys  [][]byte
+	serializer CookieSerializer
+}
+
+func (s *cookieCodec) Encode(name string, value interface{}) (string, error) {
+	return s.encode(name, value, 0)
+}
+
+func (s *cookieCodec) Decode(name string, value string, dst interface{}) error {
+	_, _, err := s.decode(name, value, dst)
+	return err
+}
+
+func (s *cookieCodec) encode(name string, value interface{}, expires int64) (string, error) {
+	if len(s.hashKeys) == 0 {
+		return ``, ErrCookieNoKeys
+	}
+	b, err := s.serializer.Serialize(value)
+	if err != nil {
+		return ``, err
+	}
+	if len(s.blockKeys) > 0 {
+		if b, err = encrypt(s.blockKeys[0], b, []byte(name)); err != nil {
+			return ``, err
+		}
+	}
+	payload := strconv.FormatInt(expires, 10) + `|` + base64.RawURLEncoding.EncodeToString(b)
+	mac := cookieMAC(s.hashKeys[0], name, payload)
+	return payload + `|` + base64.RawURLEncoding.EncodeToString(mac), nil
+}
+
+// decode 返回过期时间以及是否使用了旧密钥
+func (s *cookieCodec) decode(name string, value string, dst interface{}) (expires int64, stale bool, err error) {
+	if len(s.hashKeys) == 0 {
+		return 0, false, ErrCookieNoKeys
+	}
+	pos := strings.LastIndex(value, `|`)
+	if pos < 0 {
+		return 0, false, ErrCookieInvalidValue
+	}
+	payload := value[:pos]
+	mac, err := base64.RawURLEncoding.DecodeString(value[pos+1:])
+	if err != nil {
+		return 0, false, ErrCookieInvalidValue
+	}
+	verified := -1
+	for i, hashKey := range s.hashKeys {
+		if subtle.ConstantTimeCompare(mac, cookieMAC(hashKey, name, payload)) == 1 {
+			verified = i
+			break
+		}
+	}
+	if verified < 0 {
+		return 0, false, ErrCookieInvalidMAC
+	}
+	stale = verified > 0
+	parts := strings.SplitN(payload, `|`, 2)
+	if len(parts) != 2 {
+		return 0, false, ErrCookieInvalidValue
+	}
+	expires, err = strconv.ParseInt(parts[0], 10, 64)
+	if err != nil {
+		return 0, false, ErrCookieInvalidValue
+	}
+	if expires > 0 && expires < time.Now().Unix() {
+		return expires, false, ErrCookieExpired
+	}
+	b, err := base64.RawURLEncoding.DecodeString(parts[1])
+	if err != nil {
+		return 0, false, ErrCookieInvalidValue
+	}
+	if len(s.blockKeys) > 0 {
+		var plain []byte
+		err = ErrCookieDecryption
+		for i, blockKey := range s.blockKeys {
+			if plain, err = decrypt(blockKey, b, []byte(name)); err == nil {
+				stale = stale || i > 0
+				break
+			}
+		}
+		if err != nil {
+			return 0, false, err
+		}
+		b = plain
+	}
+	if err = s.serializer.Deserialize(b, dst); err != nil {
+		return 0, false, err
+	}
+	return expires, stale, nil
+}
+
+func cookieMAC(hashKey []byte, name string, payload string) []byte {
+	h := hmac.New(sha256.New, hashKey)
+	h.Write([]byte(name))
+	h.Write([]byte(`|`))
+	h.Write([]byte(payload))
+	return h.Sum(nil)
+}
+
+func newGCM(blockKey []byte) (cipher.AEAD, error) {
+	block, err := aes.NewCipher(blockKey)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
+
+func encrypt(blockKey []byte, plain []byte, additional []byte) ([]byte, error) {
+	gcm, err := newGCM(blockKey)
+	if err != nil {
+		return nil, err
+	}
+	nonce := make([]byte, gcm.NonceSize())
+	if _, err = io.ReadFull(rand.Reader, nonce); err != nil {
+		return nil, err
+	}
+	return gcm.Seal(nonce, nonce, plain, additional), nil
+}
+
+func decrypt(blockKey []byte, data []byte, additional []byte) ([]byte, error) {
+	gcm, err := newGCM(blockKey)
+	if err != nil {
+		return nil, err
+	}
+	if len(data) < gcm.NonceSize() {
+		return nil, ErrCookieDecryption
+	}
+	plain, err := gcm.Open(nil, data[:gcm.NonceSize()], data[gcm.NonceSize():], additional)
+	if err != nil {
+		return nil, ErrCookieDecryption
+	}
+	return plain, nil
+}
+
+// reencoder 由支持密钥轮换的编解码器(如 encoding/keyring.Keyring)实现，
+// 返回的序号大于 0 时表示使用了旧密钥
+type reencoder interface {
+	DecodeIndex(name string, value string, dst interface{}) (int, error)
+}
+
+// codec 返回 SetSigned/GetSigned 使用的编解码器
+func (c *CookieOptions) codec() CookieCodec {
+	if c.Codec != nil {
+		return c.Codec
+	}
+	return NewCookieCodec(c.HashKeys, c.BlockKeys, c.Serializer)
+}
+
+// SetSigned 签名(设置了 BlockKeys 时同时加密)并设置 cookie。
+// val 可以是任意可序列化的值，args 与 Set 相同。
+// 使用内置编解码器时，有效期(args[0] 或 CookieOptions.MaxAge)写入签名数据中，过期后 GetSigned 返回 ErrCookieExpired
+func (c *cookie) SetSigned(key string, val interface{}, args ...interface{}) error {
+	opts := c.context.CookieOptions()
+	maxAge := opts.MaxAge
+	if len(args) > 0 {
+		maxAge = lifeTime(args[0])
+	}
+	var (
+		encoded string
+		err     error
+	)
+	codec := opts.codec()
+	if cc, ok := codec.(*cookieCodec); ok {
+		var expires int64
+		if maxAge != 0 {
+			expires = time.Now().Unix() + int64(maxAge)
+		}
+		encoded, err = cc.encode(key, val, expires)
+	} else {
+		encoded, err = codec.Encode(key, val)
+	}
+	if err != nil {
+		return err
+	}
+	c.Set(key, encoded, args...)
+	return nil
+}
+
+// GetSigned 验证(解密)cookie 并将值解码到 dst。
+// 使用旧密钥编码的 cookie 会以最新的密钥重新写入
+func (c *cookie) GetSigned(key string, dst interface{}) error {
+	value := c.Get(key)
+	if len(value) == 0 {
+		return ErrCookieNotFound
+	}
+	codec := c.context.CookieOptions().codec()
+	switch cc := codec.(type) {
+	case *cookieCodec:
+		expires, stale, err := cc.decode(key, value, dst)
+		if err != nil || !stale {
+			return err
+		}
+		encoded, err := cc.encode(key, dst, expires)
+		if err != nil {
+			return err
+		}
+		if expires > 0 {
+			c.Set(key, encoded, int(expires-time.Now().Unix()))
+		} else {
+			c.Set(key, encoded)
+		}
+		return nil
+	case reencoder:
+		index, err := cc.DecodeIndex(key, value, dst)
+		if err != nil || index <= 0 {
+			return err
+		}
+		encoded, err := codec.Encode(key, dst)
+		if err != nil {
+			return err
+		}
+		c.Set(key, encoded)
+		return nil
+	default:
+		return codec.Decode(key, value, dst)
+	}
+}
@@ -0,0 +1,78 @@
+package echo_test
+
+import (
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	. "github.com/webx-top/echo"
+	test "github.com/webx-top/echo/testing"
+)
+
+type signedValue struct {
+	Name  string
+	Roles []string
+}
+
+func TestSignedCookie(t *testing.T) {
+	opts := &CookieOptions{
+		Path:      `/`,
+		HashKeys:  [][]byte{[]byte(`hash-key-1`)},
+		BlockKeys: [][]byte{[]byte(`0123456789abcdef`)},
+	}
+	e := New()
+	e.Use(func(h Handler) HandlerFunc {
+		return func(c Context) error {
+			c.SetSessionOptions(NewSessionOptions(`cookie`, `SID`, opts))
+			return h.Handle(c)
+		}
+	})
+	e.Get(`/set`, func(c Context) error {
+		return c.Cookie().SetSigned(`user`, &signedValue{Name: `admin`, Roles: []string{`a`, `b`}}, c.Queryx(`maxAge`).Int())
+	})
+	e.Get(`/get`, func(c Context) error {
+		var v signedValue
+		if err := c.Cookie().GetSigned(`user`, &v); err != nil {
+			return c.String(err.Error())
+		}
+		return c.String(v.Name + `:` + strings.Join(v.Roles, `,`))
+	})
+	e.RebuildRouter()
+	withCookie := func(cookies ...string) func(*http.Request) {
+		return func(req *http.Request) {
+			for _, h := range cookies {
+				req.Header.Add(`Cookie`, strings.SplitN(h, `;`, 2)[0])
+			}
+		}
+	}
+
+	set := test.Request(GET, `/set`, e).Header().Get(HeaderSetCookie)
+	assert.NotContains(t, set, `admin`)
+	assert.Equal(t, `admin:a,b`, test.Request(GET, `/get`, e, withCookie(set)).Body.String())
+
+	// 篡改
+	tampered := strings.Replace(set, `user=`, `user=0`, 1)
+	assert.Equal(t, ErrCookieInvalidMAC.Error(), test.Request(GET, `/get`, e, withCookie(tampered)).Body.String())
+
+	// 签名数据中的有效期
+	expired := test.Request(GET, `/set?maxAge=-1`, e).Header().Get(HeaderSetCookie)
+	assert.Equal(t, ErrCookieExpired.Error(), test.Request(GET, `/get`, e, withCookie(expired)).Body.String())
+
+	// 密钥轮换：旧密钥仍然可以解码，并以新密钥重新写入
+	opts.HashKeys = [][]byte{[]byte(`hash-key-2`), []byte(`hash-key-1`)}
+	opts.BlockKeys = [][]byte{[]byte(`fedcba9876543210`), []byte(`0123456789abcdef`)}
+	rec := test.Request(GET, `/get`, e, withCookie(set))
+	assert.Equal(t, `admin:a,b`, rec.Body.String())
+	renewed := rec.Header().Get(HeaderSetCookie)
+	assert.NotEmpty(t, renewed)
+	opts.HashKeys = opts.HashKeys[:1]
+	opts.BlockKeys = opts.BlockKeys[:1]
+	assert.Equal(t, `admin:a,b`, test.Request(GET, `/get`, e, withCookie(renewed)).Body.String())
+	assert.Equal(t, ErrCookieInvalidMAC.Error(), test.Request(GET, `/get`, e, withCookie(set)).Body.String())
+
+	// JSON 序列化
+	opts.Serializer = JSONCookieSerializer
+	set = test.Request(GET, `/set`, e).Header().Get(HeaderSetCookie)
+	assert.Equal(t, `admin:a,b`, test.Request(GET, `/get`, e, withCookie(set)).Body.String())
+}
@@ -161,6 +161,12 @@ func (d *RawData) SetError(err error, args ...int) Data {
 			d.SetCode(0)
 		}
 		d.Info = err.Error()
+		switch e := err.(type) {
+		case FieldErrors:
+			d.Zone = e
+		case *FieldError:
+			d.Zone = e.Field
+		}
 	} else {
 		d.SetCode(1)
 	}
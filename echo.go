@@ -37,11 +37,14 @@ type (
 		middlewareWrapper []func(interface{}) Middleware
 		acceptFormats     map[string]string //mime=>format
 		formatRenderers   map[string]func(ctx Context, data interface{}) error
+		formatWeights     map[string]float64
+		container         *Container
 		FuncMap           map[string]interface{}
 		RouteDebug        bool
 		MiddlewareDebug   bool
 		JSONPVarName      string
 		parseHeaderAccept bool
+		strictAccept      bool
 	}
 
 	Middleware interface {
@@ -133,11 +136,25 @@ func (e *Echo) Reset() *Echo {
 	e.middlewareWrapper = []func(interface{}) Middleware{}
 	e.acceptFormats = DefaultAcceptFormats
 	e.formatRenderers = DefaultFormatRenderers
+	e.formatWeights = map[string]float64{}
+	e.container = NewContainer()
 	e.FuncMap = make(map[string]interface{})
 	e.RouteDebug = false
 	e.MiddlewareDebug = false
 	e.JSONPVarName = `callback`
 	e.parseHeaderAccept = false
+	e.strictAccept = false
+	return e
+}
+
+// Container 返回依赖注入容器
+func (e *Echo) Container() *Container {
+	return e.container
+}
+
+// SetContainer 设置依赖注入容器
+func (e *Echo) SetContainer(container *Container) *Echo {
+	e.container = container
 	return e
 }
 
@@ -199,15 +216,22 @@ func (e *Echo) Logger() logger.Logger {
 func (e *Echo) DefaultHTTPErrorHandler(err error, c Context) {
 	code := http.StatusInternalServerError
 	msg := http.StatusText(code)
-	if he, ok := err.(*HTTPError); ok {
+	switch he := err.(type) {
+	case *HTTPError:
 		code = he.Code
 		msg = he.Message
+	case *ProblemError:
+		code = he.Status
+		msg = he.Error()
+	case FieldErrors, *FieldError:
+		code = http.StatusUnprocessableEntity
+		msg = he.Error()
 	}
 	if e.debug {
 		msg = err.Error()
 	}
 	if !c.Response().Committed() {
-		if c.Request().Method() == HEAD {
+		if c.Request().Method() == HEAD || code == http.StatusNotModified {
 			c.NoContent(code)
 		} else {
 			if code > 0 {
@@ -482,7 +506,18 @@ func (e *Echo) Add(method, path string, handler interface{}, middleware ...inter
 
 // MetaHandler Add meta information about endpoint
 func (e *Echo) MetaHandler(m H, handler interface{}) Handler {
-	return &MetaHandler{m, e.ValidHandler(handler)}
+	h := e.ValidHandler(handler)
+	if mt, ok := h.(Meta); ok {
+		if m == nil {
+			m = H{}
+		}
+		for k, v := range mt.Meta() {
+			if _, y := m[k]; !y {
+				m[k] = v
+			}
+		}
+	}
+	return &MetaHandler{m, h}
 }
 
 // RebuildRouter rebuild router
@@ -680,9 +715,8 @@ func (e *Echo) chainMiddlewareByHost(host string, router *Router) Handler {
 	} else if h.head != nil {
 		return h.head
 	}
-	handler := router.Handle(nil)
 	e.hosts[host].head = e.applyMiddleware(router.Handle(nil), e.middleware...)
-	return handler
+	return e.hosts[host].head
 }
 
 func (e *Echo) applyMiddleware(h Handler, middleware ...interface{}) Handler {
@@ -708,6 +742,9 @@ func (e *Echo) ServeHTTP(req engine.Request, res engine.Response) {
 	if err := handler.Handle(c); err != nil {
 		c.Error(err)
 	}
+	if err := c.Dispose(); err != nil {
+		e.logger.Error(err)
+	}
 
 	e.pool.Put(c)
 }
@@ -756,6 +793,27 @@ func (e *Echo) Stop() error {
 	return e.engine.Stop()
 }
 
+// FindRoute 在 host 对应的路由器中查找与 method 和 path 匹配的路由，不会修改当前请求的 Context。
+// 没有匹配的路由时返回 nil
+func (e *Echo) FindRoute(host, method, path string) *Route {
+	router, _, _, _ := e.findRouter(host)
+	c := e.pool.Get().(Context)
+	ctx := c.Object()
+	ctx.rid = -1
+	router.Find(method, path, c)
+	rid := ctx.rid
+	ctx.path = ""
+	ctx.pnames = nil
+	ctx.handler = NotFoundHandler
+	ctx.route = nil
+	ctx.rid = -1
+	e.pool.Put(c)
+	if rid < 0 || rid >= len(e.router.routes) {
+		return nil
+	}
+	return e.router.routes[rid]
+}
+
 func (e *Echo) findRouter(host string) (*Router, []string, []string, bool) {
 	if len(e.hosts) == 0 {
 		return e.router, nil, nil, false
@@ -251,6 +251,38 @@ func TestEchoRouter(t *testing.T) {
 	assert.Equal(t, "123", b)
 }
 
+func TestEchoFindRoute(t *testing.T) {
+	e := New()
+	e.Get("/users/:id", func(c Context) error {
+		// 查找其它路由不影响当前请求的路由
+		route := c.Echo().FindRoute(c.Request().Host(), POST, "/users")
+		if route == nil {
+			return c.String("nil:" + c.Route().Path)
+		}
+		return c.String(route.Path + ":" + c.Route().Path + ":" + c.Param("id"))
+	})
+	e.Post("/users", NotFoundHandler)
+	e.Host("api.example.com").Get("/users/:id", NotFoundHandler)
+	e.RebuildRouter()
+
+	route := e.FindRoute("", GET, "/users/1")
+	if assert.NotNil(t, route) {
+		assert.Equal(t, "/users/:id", route.Path)
+		assert.Empty(t, route.Host)
+	}
+	route = e.FindRoute("api.example.com", GET, "/users/1")
+	if assert.NotNil(t, route) {
+		assert.Equal(t, "api.example.com", route.Host)
+	}
+	assert.Nil(t, e.FindRoute("", GET, "/missing"))
+	assert.Nil(t, e.FindRoute("", PUT, "/users"))
+	assert.Nil(t, e.FindRoute("api.example.com", POST, "/users"))
+
+	c, b := request(GET, "/users/1", e)
+	assert.Equal(t, http.StatusOK, c)
+	assert.Equal(t, "/users:/users/:id:1", b)
+}
+
 func TestEchoMeta(t *testing.T) {
 	e := New()
 
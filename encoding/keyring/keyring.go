@@ -0,0 +1,247 @@
+// Package keyring 管理 securecookie 的密钥，支持密钥轮换：
+// 始终使用最新的密钥编码，使用所有未淘汰的密钥解码，被替换的密钥在宽限期之后淘汰。
+//
+// 会话存储引擎在下次保存会话时会使用最新的密钥重新编码，
+// 因此 echo.SessionOptions.RefreshInterval 应小于宽限期，以便活跃的会话在旧密钥淘汰之前完成更新
+package keyring
+
+import (
+	"bytes"
+	"errors"
+	"sync"
+	"time"
+
+	"github.com/admpub/securecookie"
+)
+
+var (
+	// DefaultGracePeriod 被替换的密钥在此时长之后淘汰
+	DefaultGracePeriod = time.Hour * 24 * 7
+
+	ErrNoKeys = errors.New(`keyring: no keys`)
+)
+
+type key struct {
+	hashKey  []byte
+	blockKey []byte
+	codec    securecookie.Codec
+	retireAt time.Time // 为零值时表示当前使用中的密钥
+}
+
+func (k *key) equal(hashKey []byte, blockKey []byte) bool {
+	return bytes.Equal(k.hashKey, hashKey) && bytes.Equal(k.blockKey, blockKey)
+}
+
+// New 创建密钥环。keyPairs 的格式与 securecookie.CodecsFromPairs 相同：
+// 依次为签名密钥和加密密钥(可以为 nil)，第一对为最新的密钥
+func New(keyPairs ...[]byte) *Keyring {
+	k := &Keyring{gracePeriod: DefaultGracePeriod}
+	k.SetKeys(keyPairs...)
+	return k
+}
+
+// Keyring 实现了 securecookie.Codec，可安全地并发使用
+type Keyring struct {
+	mutex       sync.RWMutex
+	keys        []*key
+	gracePeriod time.Duration
+	maxAge      int
+	onChange    []func()
+}
+
+// SetGracePeriod 设置被替换的密钥的宽限期
+func (k *Keyring) SetGracePeriod(d time.Duration) *Keyring {
+	k.mutex.Lock()
+	k.gracePeriod = d
+	k.mutex.Unlock()
+	return k
+}
+
+// SetMaxAge 设置编码数据的有效期(秒)，与 securecookie.SecureCookie.MaxAge 相同
+func (k *Keyring) SetMaxAge(maxAge int) *Keyring {
+	k.mutex.Lock()
+	k.maxAge = maxAge
+	for _, item := range k.keys {
+		setMaxAge(item.codec, maxAge)
+	}
+	k.mutex.Unlock()
+	return k
+}
+
+// OnChange 添加密钥变更时的回调函数
+func (k *Keyring) OnChange(fn func()) *Keyring {
+	k.mutex.Lock()
+	k.onChange = append(k.onChange, fn)
+	k.mutex.Unlock()
+	return k
+}
+
+// SetKeys 替换全部密钥，第一对为最新的密钥。
+// 不再出现的旧密钥在宽限期内仍然可以用于解码
+func (k *Keyring) SetKeys(keyPairs ...[]byte) {
+	now := time.Now()
+	k.mutex.Lock()
+	keys := make([]*key, 0, len(keyPairs)/2+len(k.keys))
+	for i := 0; i < len(keyPairs); i += 2 {
+		var blockKey []byte
+		if i+1 < len(keyPairs) {
+			blockKey = keyPairs[i+1]
+		}
+		item := k.newKey(keyPairs[i], blockKey)
+		if !containsKey(keys, item) {
+			keys = append(keys, item)
+		}
+	}
+	for _, old := range k.keys {
+		if containsKey(keys, old) {
+			continue
+		}
+		if old.retireAt.IsZero() {
+			old.retireAt = now.Add(k.gracePeriod)
+		}
+		if old.retireAt.After(now) {
+			keys = append(keys, old)
+		}
+	}
+	var current []*key
+	for _, item := range k.keys {
+		if item.retireAt.IsZero() || item.retireAt.After(now) {
+			current = append(current, item)
+		}
+	}
+	changed := !sameKeys(keys, current)
+	k.keys = keys
+	callbacks := k.onChange
+	k.mutex.Unlock()
+	if changed {
+		for _, fn := range callbacks {
+			fn()
+		}
+	}
+}
+
+// Rotate 添加一对新密钥作为最新的密钥，原来的密钥在宽限期之后淘汰
+func (k *Keyring) Rotate(hashKey []byte, blockKey []byte) {
+	k.SetKeys(hashKey, blockKey)
+}
+
+// Len 返回未淘汰的密钥数量
+func (k *Keyring) Len() int {
+	now := time.Now()
+	k.mutex.RLock()
+	defer k.mutex.RUnlock()
+	var n int
+	for _, item := range k.keys {
+		if item.retireAt.IsZero() || item.retireAt.After(now) {
+			n++
+		}
+	}
+	return n
+}
+
+// KeyPairs 返回未淘汰的密钥对，第一对为最新的密钥。
+// 用于只能在创建时设置密钥的存储引擎，这类引擎需要通过 OnChange 在密钥变更后重新创建
+func (k *Keyring) KeyPairs() [][]byte {
+	now := time.Now()
+	k.mutex.RLock()
+	defer k.mutex.RUnlock()
+	keyPairs := make([][]byte, 0, len(k.keys)*2)
+	for _, item := range k.keys {
+		if item.retireAt.IsZero() || item.retireAt.After(now) {
+			keyPairs = append(keyPairs, item.hashKey, item.blockKey)
+		}
+	}
+	return keyPairs
+}
+
+// Codecs 返回用于存储引擎的编解码器
+func (k *Keyring) Codecs() []securecookie.Codec {
+	return []securecookie.Codec{k}
+}
+
+// Encode 使用最新的密钥编码
+func (k *Keyring) Encode(name string, value interface{}) (string, error) {
+	k.mutex.RLock()
+	if len(k.keys) == 0 {
+		k.mutex.RUnlock()
+		return ``, ErrNoKeys
+	}
+	codec := k.keys[0].codec
+	k.mutex.RUnlock()
+	return codec.Encode(name, value)
+}
+
+// Decode 依次使用未淘汰的密钥解码
+func (k *Keyring) Decode(name string, value string, dst interface{}) error {
+	_, err := k.DecodeIndex(name, value, dst)
+	return err
+}
+
+// DecodeIndex 解码并返回所使用的密钥的序号，序号大于 0 时表示数据需要使用最新的密钥重新编码
+func (k *Keyring) DecodeIndex(name string, value string, dst interface{}) (int, error) {
+	codecs := k.codecs()
+	if len(codecs) == 0 {
+		return -1, ErrNoKeys
+	}
+	var err error
+	for i, codec := range codecs {
+		if err = codec.Decode(name, value, dst); err == nil {
+			return i, nil
+		}
+	}
+	return -1, err
+}
+
+func (k *Keyring) codecs() []securecookie.Codec {
+	now := time.Now()
+	k.mutex.RLock()
+	defer k.mutex.RUnlock()
+	codecs := make([]securecookie.Codec, 0, len(k.keys))
+	for _, item := range k.keys {
+		if item.retireAt.IsZero() || item.retireAt.After(now) {
+			codecs = append(codecs, item.codec)
+		}
+	}
+	return codecs
+}
+
+// newKey 创建当前使用中的密钥，已有的密钥复用其编解码器
+func (k *Keyring) newKey(hashKey []byte, blockKey []byte) *key {
+	for _, item := range k.keys {
+		if item.equal(hashKey, blockKey) {
+			return &key{hashKey: hashKey, blockKey: blockKey, codec: item.codec}
+		}
+	}
+	codec := securecookie.CodecsFromPairs(hashKey, blockKey)[0]
+	if k.maxAge != 0 {
+		setMaxAge(codec, k.maxAge)
+	}
+	return &key{hashKey: hashKey, blockKey: blockKey, codec: codec}
+}
+
+func setMaxAge(codec securecookie.Codec, maxAge int) {
+	if sc, ok := codec.(*securecookie.SecureCookie); ok {
+		sc.MaxAge(maxAge)
+	}
+}
+
+func containsKey(keys []*key, k *key) bool {
+	for _, item := range keys {
+		if item.equal(k.hashKey, k.blockKey) {
+			return true
+		}
+	}
+	return false
+}
+
+func sameKeys(a []*key, b []*key) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i, item := range a {
+		if !item.equal(b[i].hashKey, b[i].blockKey) || !item.retireAt.Equal(b[i].retireAt) {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,105 @@
+package keyring
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestKeyringRotate(t *testing.T) {
+	k := New([]byte(`hash-key-1`), nil)
+	oldEncoded, err := k.Encode(`name`, `value`)
+	assert.NoError(t, err)
+
+	var changed int
+	k.OnChange(func() { changed++ })
+	k.Rotate([]byte(`hash-key-2`), nil)
+	assert.Equal(t, 1, changed)
+	assert.Equal(t, 2, k.Len())
+
+	var value string
+	index, err := k.DecodeIndex(`name`, oldEncoded, &value)
+	assert.NoError(t, err)
+	assert.Equal(t, 1, index)
+	assert.Equal(t, `value`, value)
+
+	newEncoded, err := k.Encode(`name`, `value`)
+	assert.NoError(t, err)
+	index, err = k.DecodeIndex(`name`, newEncoded, &value)
+	assert.NoError(t, err)
+	assert.Equal(t, 0, index)
+
+	// 只有新密钥时旧数据不能解码
+	assert.Error(t, New([]byte(`hash-key-2`), nil).Decode(`name`, oldEncoded, &value))
+
+	// 宽限期之后淘汰旧密钥
+	k = New([]byte(`hash-key-1`), nil).SetGracePeriod(time.Millisecond)
+	k.Rotate([]byte(`hash-key-2`), nil)
+	assert.NoError(t, k.Decode(`name`, oldEncoded, &value))
+	time.Sleep(5 * time.Millisecond)
+	assert.Equal(t, 1, k.Len())
+	assert.Error(t, k.Decode(`name`, oldEncoded, &value))
+
+	// 设置相同的密钥不会触发变更
+	changed = 0
+	k.OnChange(func() { changed++ })
+	k.SetKeys([]byte(`hash-key-2`), nil)
+	assert.Equal(t, 0, changed)
+}
+
+func TestParseKeys(t *testing.T) {
+	keyPairs, err := ParseKeys("# comment\nhash1:block1\n\nbase64:aGFzaDI=:hex:626c6f636b32, hash3")
+	assert.NoError(t, err)
+	assert.Equal(t, [][]byte{
+		[]byte(`hash1`), []byte(`block1`),
+		[]byte(`hash2`), []byte(`block2`),
+		[]byte(`hash3`), nil,
+	}, keyPairs)
+
+	_, err = ParseKeys(`:block`)
+	assert.Error(t, err)
+	_, err = ParseKeys(`hex:zz`)
+	assert.Error(t, err)
+
+	os.Setenv(`ECHO_KEYRING_TEST`, `env-hash`)
+	defer os.Unsetenv(`ECHO_KEYRING_TEST`)
+	k, err := NewFromEnv(`ECHO_KEYRING_TEST`)
+	assert.NoError(t, err)
+	assert.Equal(t, 1, k.Len())
+}
+
+func TestWatchFile(t *testing.T) {
+	dir, err := ioutil.TempDir(``, `echo-keyring`)
+	assert.NoError(t, err)
+	defer os.RemoveAll(dir)
+	file := filepath.Join(dir, `keys`)
+	assert.NoError(t, ioutil.WriteFile(file, []byte(`hash-key-1`), 0600))
+	k, err := NewFromFile(file)
+	assert.NoError(t, err)
+	oldEncoded, _ := k.Encode(`name`, `value`)
+
+	reloaded := make(chan struct{}, 1)
+	k.OnChange(func() { reloaded <- struct{}{} })
+	stop := k.WatchFile(file, time.Millisecond)
+	defer stop()
+	future := time.Now().Add(time.Second)
+	assert.NoError(t, ioutil.WriteFile(file, []byte("hash-key-2\n"), 0600))
+	assert.NoError(t, os.Chtimes(file, future, future))
+	select {
+	case <-reloaded:
+	case <-time.After(time.Second):
+		t.Fatal(`keys were not reloaded`)
+	}
+	stop()
+
+	var value string
+	index, err := k.DecodeIndex(`name`, oldEncoded, &value)
+	assert.NoError(t, err)
+	assert.Equal(t, 1, index)
+	newEncoded, _ := k.Encode(`name`, `value`)
+	assert.NoError(t, New([]byte(`hash-key-2`), nil).Decode(`name`, newEncoded, &value))
+}
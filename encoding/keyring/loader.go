@@ -0,0 +1,163 @@
+package keyring
+
+import (
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"io/ioutil"
+	"log"
+	"os"
+	"strings"
+	"sync"
+	"time"
+)
+
+// ParseKeys 解析密钥文本。每行(或以逗号分隔)一对密钥，最新的在最前面，
+// 格式为 "签名密钥[:加密密钥]"，以 # 开头的行会被忽略。
+// 密钥可以使用 "base64:" 或 "hex:" 前缀，否则按原样使用
+func ParseKeys(text string) ([][]byte, error) {
+	var keyPairs [][]byte
+	text = strings.Replace(text, `,`, "\n", -1)
+	for _, line := range strings.Split(text, "\n") {
+		line = strings.TrimSpace(line)
+		if len(line) == 0 || strings.HasPrefix(line, `#`) {
+			continue
+		}
+		hashKey, blockKey := splitPair(line)
+		hash, err := decodeKey(hashKey)
+		if err != nil {
+			return nil, err
+		}
+		if len(hash) == 0 {
+			return nil, errors.New(`keyring: empty hash key`)
+		}
+		block, err := decodeKey(blockKey)
+		if err != nil {
+			return nil, err
+		}
+		keyPairs = append(keyPairs, hash, block)
+	}
+	return keyPairs, nil
+}
+
+// splitPair 以冒号分隔签名密钥和加密密钥，跳过编码前缀中的冒号
+func splitPair(line string) (string, string) {
+	var offset int
+	for _, prefix := range []string{`base64:`, `hex:`} {
+		if strings.HasPrefix(line, prefix) {
+			offset = len(prefix)
+			break
+		}
+	}
+	pos := strings.Index(line[offset:], `:`)
+	if pos < 0 {
+		return line, ``
+	}
+	pos += offset
+	return line[:pos], line[pos+1:]
+}
+
+func decodeKey(s string) ([]byte, error) {
+	switch {
+	case len(s) == 0:
+		return nil, nil
+	case strings.HasPrefix(s, `base64:`):
+		return base64.StdEncoding.DecodeString(strings.TrimPrefix(s, `base64:`))
+	case strings.HasPrefix(s, `hex:`):
+		return hex.DecodeString(strings.TrimPrefix(s, `hex:`))
+	default:
+		return []byte(s), nil
+	}
+}
+
+// LoadFile 从文件中读取密钥，格式见 ParseKeys
+func LoadFile(file string) ([][]byte, error) {
+	b, err := ioutil.ReadFile(file)
+	if err != nil {
+		return nil, err
+	}
+	return ParseKeys(string(b))
+}
+
+// LoadEnv 从环境变量中读取密钥，格式见 ParseKeys
+func LoadEnv(name string) ([][]byte, error) {
+	return ParseKeys(os.Getenv(name))
+}
+
+// NewFromFile 使用文件中的密钥创建密钥环
+func NewFromFile(file string) (*Keyring, error) {
+	keyPairs, err := LoadFile(file)
+	if err != nil {
+		return nil, err
+	}
+	if len(keyPairs) == 0 {
+		return nil, ErrNoKeys
+	}
+	return New(keyPairs...), nil
+}
+
+// NewFromEnv 使用环境变量中的密钥创建密钥环
+func NewFromEnv(name string) (*Keyring, error) {
+	keyPairs, err := LoadEnv(name)
+	if err != nil {
+		return nil, err
+	}
+	if len(keyPairs) == 0 {
+		return nil, ErrNoKeys
+	}
+	return New(keyPairs...), nil
+}
+
+// Watch 每隔 interval 调用 load 重新加载密钥，返回的函数用于停止。
+// 加载失败或没有密钥时保留原有的密钥
+func (k *Keyring) Watch(interval time.Duration, load func() ([][]byte, error)) (stop func()) {
+	quit := make(chan struct{})
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-quit:
+				return
+			case <-ticker.C:
+				k.reload(load)
+			}
+		}
+	}()
+	var once sync.Once
+	return func() {
+		once.Do(func() {
+			close(quit)
+		})
+	}
+}
+
+// WatchFile 在文件修改后重新加载密钥
+func (k *Keyring) WatchFile(file string, interval time.Duration) (stop func()) {
+	var modTime time.Time
+	if fi, err := os.Stat(file); err == nil {
+		modTime = fi.ModTime()
+	}
+	return k.Watch(interval, func() ([][]byte, error) {
+		fi, err := os.Stat(file)
+		if err != nil {
+			return nil, err
+		}
+		if fi.ModTime().Equal(modTime) {
+			return nil, nil
+		}
+		modTime = fi.ModTime()
+		return LoadFile(file)
+	})
+}
+
+func (k *Keyring) reload(load func() ([][]byte, error)) {
+	keyPairs, err := load()
+	if err != nil {
+		log.Println(`keyring: failed to reload keys:`, err)
+		return
+	}
+	if len(keyPairs) > 0 {
+		k.SetKeys(keyPairs...)
+	}
+}
@@ -0,0 +1,165 @@
+package engine
+
+import (
+	"bytes"
+	"io"
+	"net"
+	"net/http"
+)
+
+// NewBufferedResponse 创建缓冲响应。
+// 写入的状态码和内容会暂存在内存中，直到调用 Send 时才写入原始响应，
+// 因此中间件可以在输出前根据完整的响应内容修改状态码和头部
+func NewBufferedResponse(res Response) *BufferedResponse {
+	r := &BufferedResponse{Response: res}
+	r.writer = &r.buf
+	return r
+}
+
+// BufferedResponse 缓冲响应
+type BufferedResponse struct {
+	Response
+	buf         bytes.Buffer
+	writer      io.Writer
+	status      int
+	committed   bool
+	passthrough bool
+}
+
+// Original 返回原始响应
+func (r *BufferedResponse) Original() Response {
+	return r.Response
+}
+
+func (r *BufferedResponse) WriteHeader(code int) {
+	if r.committed {
+		return
+	}
+	r.status = code
+	r.committed = true
+}
+
+// KeepBody 为 false 时(例如 Stream、ServeContent 等流式输出)停止缓冲：
+// 已缓冲的状态码和内容立即写入原始响应，之后写入的内容直接输出
+func (r *BufferedResponse) KeepBody(on bool) {
+	if on || r.passthrough {
+		return
+	}
+	r.Send()
+	if r.writer == io.Writer(&r.buf) {
+		r.writer = r.Response
+	}
+}
+
+func (r *BufferedResponse) Write(b []byte) (int, error) {
+	if !r.committed {
+		if r.status == 0 {
+			r.status = http.StatusOK
+		}
+		r.WriteHeader(r.status)
+	}
+	return r.writer.Write(b)
+}
+
+// Status 返回缓冲的状态码
+func (r *BufferedResponse) Status() int {
+	if r.passthrough {
+		return r.Response.Status()
+	}
+	if r.status == 0 {
+		return http.StatusOK
+	}
+	return r.status
+}
+
+func (r *BufferedResponse) Size() int64 {
+	if r.passthrough {
+		return r.Response.Size()
+	}
+	return int64(r.buf.Len())
+}
+
+func (r *BufferedResponse) Committed() bool {
+	return r.committed
+}
+
+func (r *BufferedResponse) SetWriter(w io.Writer) {
+	r.writer = w
+}
+
+func (r *BufferedResponse) Writer() io.Writer {
+	return r.writer
+}
+
+// Body 返回缓冲的内容
+func (r *BufferedResponse) Body() []byte {
+	return r.buf.Bytes()
+}
+
+// Reset 清空缓冲的状态码和内容
+func (r *BufferedResponse) Reset() {
+	r.buf.Reset()
+	r.status = 0
+	r.committed = false
+}
+
+// Passthrough 是否已经绕过缓冲直接输出(例如 ServeFile、Stream、Redirect 等)
+func (r *BufferedResponse) Passthrough() bool {
+	return r.passthrough
+}
+
+func (r *BufferedResponse) Error(errMsg string, args ...int) {
+	if len(args) > 0 {
+		r.status = args[0]
+	} else {
+		r.status = http.StatusInternalServerError
+	}
+	r.Write(Str2bytes(errMsg))
+}
+
+func (r *BufferedResponse) Hijack(fn func(net.Conn)) {
+	r.bypass()
+	r.Response.Hijack(fn)
+}
+
+func (r *BufferedResponse) Redirect(url string, code int) {
+	r.bypass()
+	r.Response.Redirect(url, code)
+}
+
+func (r *BufferedResponse) NotFound() {
+	r.bypass()
+	r.Response.NotFound()
+}
+
+func (r *BufferedResponse) ServeFile(file string) {
+	r.bypass()
+	r.Response.ServeFile(file)
+}
+
+func (r *BufferedResponse) Stream(step func(io.Writer) bool) {
+	r.bypass()
+	r.Response.Stream(step)
+}
+
+func (r *BufferedResponse) bypass() {
+	r.passthrough = true
+	r.committed = true
+}
+
+// Send 将缓冲的状态码和内容写入原始响应
+func (r *BufferedResponse) Send() error {
+	if r.passthrough {
+		return nil
+	}
+	r.passthrough = true
+	if !r.committed {
+		return nil
+	}
+	r.Response.WriteHeader(r.status)
+	if r.buf.Len() == 0 {
+		return nil
+	}
+	_, err := r.Response.Write(r.buf.Bytes())
+	return err
+}
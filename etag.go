@@ -0,0 +1,80 @@
+/*
+
+   Copyright 2016-present Wenhui Shen <www.webx.top>
+
+   Licensed under the Apache License, Version 2.0 (the "License");
+   you may not use this file except in compliance with the License.
+   You may obtain a copy of the License at
+
+       http://www.apache.org/licenses/LICENSE-2.0
+
+   Unless required by applicable law or agreed to in writing, software
+   distributed under the License is distributed on an "AS IS" BASIS,
+   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+   See the License for the specific language governing permissions and
+   limitations under the License.
+
+*/
+
+package echo
+
+import (
+	"crypto/sha1"
+	"encoding/hex"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// GenerateETag 根据内容生成 ETag。weak 为 true 时生成弱 ETag(`W/"..."`)
+func GenerateETag(body []byte, weak bool) string {
+	sum := sha1.Sum(body)
+	etag := `"` + strconv.FormatInt(int64(len(body)), 16) + `-` + hex.EncodeToString(sum[:10]) + `"`
+	if weak {
+		return `W/` + etag
+	}
+	return etag
+}
+
+// ETagMatch 检查 If-Match 或 If-None-Match 头部的值是否与 etag 匹配。
+// weak 为 true 时使用弱比较(忽略 `W/` 前缀，用于 If-None-Match)；
+// 否则使用强比较(弱 ETag 永远不匹配，用于 If-Match)。
+// `*` 匹配资源的任何当前表示(RFC 7232 §3.1)，即使 etag 为空，因此只应对已存在的资源调用
+func ETagMatch(header string, etag string, weak bool) bool {
+	header = strings.TrimSpace(header)
+	if header == `*` {
+		return true
+	}
+	if len(etag) == 0 {
+		return false
+	}
+	if !weak && strings.HasPrefix(etag, `W/`) {
+		return false
+	}
+	etag = strings.TrimPrefix(etag, `W/`)
+	for _, v := range strings.Split(header, `,`) {
+		v = strings.TrimSpace(v)
+		if strings.HasPrefix(v, `W/`) {
+			if !weak {
+				continue
+			}
+			v = v[2:]
+		}
+		if v == etag {
+			return true
+		}
+	}
+	return false
+}
+
+func parseHTTPTime(v string) time.Time {
+	if len(v) == 0 {
+		return time.Time{}
+	}
+	t, err := http.ParseTime(v)
+	if err != nil {
+		return time.Time{}
+	}
+	return t
+}
@@ -0,0 +1,78 @@
+package echo_test
+
+import (
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	. "github.com/webx-top/echo"
+	test "github.com/webx-top/echo/testing"
+)
+
+func TestETagMatch(t *testing.T) {
+	assert.True(t, ETagMatch(`"a", W/"b"`, `"b"`, true))
+	assert.False(t, ETagMatch(`"a", W/"b"`, `"b"`, false))
+	assert.False(t, ETagMatch(`"b"`, `W/"b"`, false))
+	assert.True(t, ETagMatch(`*`, `"b"`, false))
+	// * 匹配任何当前表示，即使没有 ETag
+	assert.True(t, ETagMatch(`*`, ``, false))
+	assert.False(t, ETagMatch(`"b"`, ``, true))
+	assert.Equal(t, `W/`+GenerateETag([]byte(`echo`), false), GenerateETag([]byte(`echo`), true))
+}
+
+func TestCheckPrecondition(t *testing.T) {
+	e := New()
+	etag := `"v2"`
+	modified := time.Date(2018, 1, 2, 3, 4, 5, 0, time.UTC)
+	handler := func(c Context) error {
+		if err := c.CheckPrecondition(etag, modified); err != nil {
+			return err
+		}
+		return c.String(`ok`)
+	}
+	e.Get(`/`, handler)
+	e.Put(`/`, handler)
+	e.Head(`/`, handler)
+	e.RebuildRouter()
+	header := func(k, v string) func(*http.Request) {
+		return func(req *http.Request) {
+			req.Header.Set(k, v)
+		}
+	}
+
+	rec := test.Request(GET, `/`, e)
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.Equal(t, etag, rec.Header().Get(HeaderETag))
+	assert.Equal(t, `Tue, 02 Jan 2018 03:04:05 GMT`, rec.Header().Get(HeaderLastModified))
+
+	rec = test.Request(GET, `/`, e, header(HeaderIfNoneMatch, `"v1", "v2"`))
+	assert.Equal(t, http.StatusNotModified, rec.Code)
+	assert.Empty(t, rec.Body.String())
+
+	rec = test.Request(GET, `/`, e, header(HeaderIfModifiedSince, `Tue, 02 Jan 2018 03:04:05 GMT`))
+	assert.Equal(t, http.StatusNotModified, rec.Code)
+
+	rec = test.Request(PUT, `/`, e, header(HeaderIfMatch, `"v1"`))
+	assert.Equal(t, http.StatusPreconditionFailed, rec.Code)
+
+	rec = test.Request(PUT, `/`, e, header(HeaderIfMatch, `"v2"`))
+	assert.Equal(t, http.StatusOK, rec.Code)
+
+	rec = test.Request(PUT, `/`, e, header(HeaderIfUnmodifiedSince, `Mon, 01 Jan 2018 00:00:00 GMT`))
+	assert.Equal(t, http.StatusPreconditionFailed, rec.Code)
+
+	rec = test.Request(PUT, `/`, e, header(HeaderIfNoneMatch, `*`))
+	assert.Equal(t, http.StatusPreconditionFailed, rec.Code)
+
+	// 304 由 HTTPErrorHandler 输出，处理器不会重复写入
+	rec = test.Request(HEAD, `/`, e, header(HeaderIfNoneMatch, `"v2"`))
+	assert.Equal(t, http.StatusNotModified, rec.Code)
+	assert.Equal(t, etag, rec.Header().Get(HeaderETag))
+
+	// 没有 ETag 的资源也匹配 If-Match: *
+	etag = ``
+	rec = test.Request(PUT, `/`, e, header(HeaderIfMatch, `*`))
+	assert.Equal(t, http.StatusOK, rec.Code)
+}
@@ -158,7 +158,7 @@ func (g *Group) Echo() *Echo {
 
 // MetaHandler Add meta information about endpoint
 func (g *Group) MetaHandler(m H, handler interface{}) Handler {
-	return &MetaHandler{m, g.echo.ValidHandler(handler)}
+	return g.echo.MetaHandler(m, handler)
 }
 
 func (g *Group) Add(method, path string, h interface{}, middleware ...interface{}) *Route {
@@ -31,6 +31,7 @@ import (
 
 	"github.com/webx-top/com"
 	"github.com/webx-top/echo"
+	"github.com/webx-top/echo/encoding/keyring"
 	"github.com/webx-top/echo/engine"
 	"github.com/webx-top/echo/engine/fasthttp"
 	"github.com/webx-top/echo/engine/standard"
@@ -633,7 +634,18 @@ func (s *Application) Run(args ...interface{}) {
 
 // InitCodec 初始化 加密/解密 接口
 func (s *Application) InitCodec(hashKey []byte, blockKey []byte) {
-	s.Codec = codec.New(hashKey, blockKey)
+	s.Codec = keyring.New(hashKey, blockKey)
+}
+
+// InitKeyring 使用密钥环作为 加密/解密 接口，以支持密钥轮换
+func (s *Application) InitKeyring(k *keyring.Keyring) {
+	s.Codec = k
+}
+
+// Keyring 返回密钥环，Codec 不是密钥环时返回 nil
+func (s *Application) Keyring() *keyring.Keyring {
+	k, _ := s.Codec.(*keyring.Keyring)
+	return k
 }
 
 // Pprof 启用pprof
@@ -136,6 +136,20 @@ func (c *Context) SecCookie(key string, value interface{}) {
 	if len(cookieValue) == 0 {
 		return
 	}
+	if k := c.Application.Keyring(); k != nil {
+		index, err := k.DecodeIndex(key, cookieValue, value)
+		if err != nil {
+			c.Application.Core.Logger().Error(err)
+			return
+		}
+		// 使用旧密钥编码的 cookie 以最新的密钥重新写入
+		if index > 0 {
+			if rv := reflect.ValueOf(value); rv.Kind() == reflect.Ptr && !rv.IsNil() {
+				c.SetSecCookie(key, rv.Elem().Interface())
+			}
+		}
+		return
+	}
 	if c.Application.Codec != nil {
 		err := c.Application.Codec.Decode(key, cookieValue, value)
 		if err != nil {
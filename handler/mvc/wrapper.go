@@ -382,6 +382,9 @@ func (a *Wrapper) Exec(ctx echo.Context, t reflect.Type, action string) error {
 			return nil
 		}
 	}
+	if err := ctx.Inject(ac); err != nil {
+		return err
+	}
 	if err := ac.(Initer).Init(ctx); err != nil {
 		return err
 	}
@@ -0,0 +1,213 @@
+/*
+
+   Copyright 2016-present Wenhui Shen <www.webx.top>
+
+   Licensed under the Apache License, Version 2.0 (the "License");
+   you may not use this file except in compliance with the License.
+   You may obtain a copy of the License at
+
+       http://www.apache.org/licenses/LICENSE-2.0
+
+   Unless required by applicable law or agreed to in writing, software
+   distributed under the License is distributed on an "AS IS" BASIS,
+   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+   See the License for the specific language governing permissions and
+   limitations under the License.
+
+*/
+
+package echo
+
+import (
+	"fmt"
+	"reflect"
+	"sort"
+	"time"
+
+	"github.com/webx-top/tagfast"
+)
+
+// Iterator 数据迭代器。第二个返回值为 false 时表示已经没有数据
+type Iterator func() (interface{}, bool)
+
+// IsIterable 是否为可逐条输出的数据(slice、array、channel 或 Iterator)
+func IsIterable(data interface{}) bool {
+	switch data.(type) {
+	case Iterator, func() (interface{}, bool):
+		return true
+	case nil:
+		return false
+	}
+	switch reflect.Indirect(reflect.ValueOf(data)).Kind() {
+	case reflect.Slice, reflect.Array, reflect.Chan:
+		return reflect.TypeOf(data) != reflect.TypeOf([]byte(nil))
+	}
+	return false
+}
+
+// Iterate 逐条遍历 slice、array、channel 或 Iterator 中的元素。
+// channel 会一直读取到被关闭为止，因此可以用来流式输出大量数据
+func Iterate(data interface{}, fn func(interface{}) error) error {
+	switch v := data.(type) {
+	case Iterator:
+		return iterateFunc(v, fn)
+	case func() (interface{}, bool):
+		return iterateFunc(v, fn)
+	case nil:
+		return nil
+	}
+	rv := reflect.Indirect(reflect.ValueOf(data))
+	switch rv.Kind() {
+	case reflect.Slice, reflect.Array:
+		for i := 0; i < rv.Len(); i++ {
+			if err := fn(rv.Index(i).Interface()); err != nil {
+				return err
+			}
+		}
+	case reflect.Chan:
+		for {
+			item, ok := rv.Recv()
+			if !ok {
+				break
+			}
+			if err := fn(item.Interface()); err != nil {
+				return err
+			}
+		}
+	default:
+		return fn(data)
+	}
+	return nil
+}
+
+func iterateFunc(next func() (interface{}, bool), fn func(interface{}) error) error {
+	for {
+		item, ok := next()
+		if !ok {
+			return nil
+		}
+		if err := fn(item); err != nil {
+			return err
+		}
+	}
+}
+
+// CSVTimeFormat CSV 中时间值的格式
+var CSVTimeFormat = `2006-01-02 15:04:05`
+
+// CSVHeader 根据第一条数据生成 CSV 表头。
+// 结构体字段名可以通过 `csv:"名称"` 标签修改，`csv:"-"` 表示忽略该字段
+func CSVHeader(item interface{}) []string {
+	switch v := item.(type) {
+	case []string:
+		return nil
+	case map[string]interface{}:
+		return sortedKeys(v)
+	case H:
+		return sortedKeys(v)
+	case map[string]string:
+		keys := make([]string, 0, len(v))
+		for k := range v {
+			keys = append(keys, k)
+		}
+		sort.Strings(keys)
+		return keys
+	}
+	t := reflect.TypeOf(item)
+	if t == nil {
+		return nil
+	}
+	if t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	if t.Kind() != reflect.Struct {
+		return nil
+	}
+	var header []string
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		if len(f.PkgPath) > 0 {
+			continue
+		}
+		name := tagfast.Value(t, f, `csv`)
+		if name == `-` {
+			continue
+		}
+		if len(name) == 0 {
+			name = f.Name
+		}
+		header = append(header, name)
+	}
+	return header
+}
+
+// CSVRecord 将一条数据转换为 CSV 记录。header 为 nil 时按原始顺序输出
+func CSVRecord(item interface{}, header []string) []string {
+	switch v := item.(type) {
+	case []string:
+		return v
+	case map[string]interface{}:
+		return mapRecord(v, header)
+	case H:
+		return mapRecord(v, header)
+	case map[string]string:
+		record := make([]string, len(header))
+		for i, k := range header {
+			record[i] = v[k]
+		}
+		return record
+	}
+	rv := reflect.Indirect(reflect.ValueOf(item))
+	if rv.Kind() != reflect.Struct {
+		return []string{csvValue(item)}
+	}
+	t := rv.Type()
+	var record []string
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		if len(f.PkgPath) > 0 || tagfast.Value(t, f, `csv`) == `-` {
+			continue
+		}
+		record = append(record, csvValue(rv.Field(i).Interface()))
+	}
+	return record
+}
+
+func mapRecord(m map[string]interface{}, header []string) []string {
+	record := make([]string, len(header))
+	for i, k := range header {
+		record[i] = csvValue(m[k])
+	}
+	return record
+}
+
+func sortedKeys(m map[string]interface{}) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+func csvValue(v interface{}) string {
+	switch r := v.(type) {
+	case nil:
+		return ``
+	case string:
+		return r
+	case time.Time:
+		if r.IsZero() {
+			return ``
+		}
+		return r.Format(CSVTimeFormat)
+	case *time.Time:
+		if r == nil || r.IsZero() {
+			return ``
+		}
+		return r.Format(CSVTimeFormat)
+	case fmt.Stringer:
+		return r.String()
+	}
+	return fmt.Sprint(v)
+}
@@ -0,0 +1,58 @@
+package echo
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type testCSVRow struct {
+	ID      int       `csv:"id"`
+	Name    string    `csv:"name"`
+	Secret  string    `csv:"-"`
+	Created time.Time `csv:"created"`
+	private string
+}
+
+func TestCSVRecord(t *testing.T) {
+	row := &testCSVRow{ID: 1, Name: `echo`, Secret: `x`, Created: time.Date(2018, 1, 2, 3, 4, 5, 0, time.UTC)}
+	header := CSVHeader(row)
+	assert.Equal(t, []string{`id`, `name`, `created`}, header)
+	assert.Equal(t, []string{`1`, `echo`, `2018-01-02 03:04:05`}, CSVRecord(row, header))
+
+	m := H{`b`: 2, `a`: `1`}
+	header = CSVHeader(m)
+	assert.Equal(t, []string{`a`, `b`}, header)
+	assert.Equal(t, []string{`1`, `2`}, CSVRecord(m, header))
+}
+
+func TestIterate(t *testing.T) {
+	ch := make(chan int, 3)
+	ch <- 1
+	ch <- 2
+	ch <- 3
+	close(ch)
+	var items []interface{}
+	err := Iterate(ch, func(item interface{}) error {
+		items = append(items, item)
+		return nil
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, []interface{}{1, 2, 3}, items)
+
+	var i int
+	next := Iterator(func() (interface{}, bool) {
+		i++
+		return i, i <= 2
+	})
+	items = nil
+	Iterate(next, func(item interface{}) error {
+		items = append(items, item)
+		return nil
+	})
+	assert.Equal(t, []interface{}{1, 2}, items)
+	assert.True(t, IsIterable([]string{`a`}))
+	assert.False(t, IsIterable(H{}))
+	assert.False(t, IsIterable([]byte(`a`)))
+}
@@ -0,0 +1,419 @@
+/*
+
+   Copyright 2016-present Wenhui Shen <www.webx.top>
+
+   Licensed under the Apache License, Version 2.0 (the "License");
+   you may not use this file except in compliance with the License.
+   You may obtain a copy of the License at
+
+       http://www.apache.org/licenses/LICENSE-2.0
+
+   Unless required by applicable law or agreed to in writing, software
+   distributed under the License is distributed on an "AS IS" BASIS,
+   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+   See the License for the specific language governing permissions and
+   limitations under the License.
+
+*/
+
+package cache
+
+import (
+	"bytes"
+	"encoding/gob"
+	"net/http"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/webx-top/echo"
+	"github.com/webx-top/echo/engine"
+)
+
+type (
+	// Entry 缓存的响应
+	Entry struct {
+		Status  int
+		Header  map[string][]string
+		Body    []byte
+		Tags    []string
+		Vary    []string // 不为空且 Status 为 0 时表示这是 Vary 标记，实际内容按 Vary 头部的值另行保存
+		Created time.Time
+	}
+
+	// Store 缓存存储引擎。Get 在缓存不存在或已过期时返回 nil, nil
+	Store interface {
+		Get(key string) (*Entry, error)
+		Set(key string, entry *Entry, ttl time.Duration) error
+		Delete(key string) error
+		InvalidateTags(tags ...string) error
+	}
+
+	// Config defines the config for Cache middleware.
+	Config struct {
+		// Skipper defines a function to skip middleware.
+		Skipper echo.Skipper `json:"-"`
+
+		// Store 存储引擎。为 nil 时使用 StoreName 注册的引擎，都没有时使用内存存储
+		Store Store `json:"-"`
+
+		// StoreName 通过 Reg 注册的存储引擎名称
+		StoreName string `json:"storeName"`
+
+		// TTL 响应中没有 Cache-Control max-age 时的缓存时长
+		// Optional. Default value 5 minutes.
+		TTL time.Duration `json:"ttl"`
+
+		// Statuses 允许缓存的状态码
+		// Optional. Default value [200].
+		Statuses []int `json:"statuses"`
+
+		// KeyPrefix 缓存键前缀
+		KeyPrefix string `json:"keyPrefix"`
+
+		// KeyGenerator 生成缓存键(不含 Vary 部分)
+		// Optional. Default value DefaultKeyGenerator.
+		KeyGenerator func(echo.Context) string `json:"-"`
+
+		// StatusHeader 输出缓存命中状态(HIT/MISS)的头部名称，设为 "-" 时不输出
+		// Optional. Default value "X-Cache".
+		StatusHeader string `json:"statusHeader"`
+	}
+)
+
+const tagsKey = `__cacheTags`
+
+var (
+	// DefaultConfig is the default Cache middleware config.
+	DefaultConfig = &Config{
+		Skipper:      echo.DefaultSkipper,
+		TTL:          5 * time.Minute,
+		Statuses:     []int{http.StatusOK},
+		KeyGenerator: DefaultKeyGenerator,
+		StatusHeader: `X-Cache`,
+	}
+
+	// DefaultMaxEntries 默认内存存储的最大条目数
+	DefaultMaxEntries = 1000
+
+	// skipHeaders 不缓存的响应头
+	skipHeaders = map[string]struct{}{
+		`Connection`:        {},
+		`Keep-Alive`:        {},
+		`Transfer-Encoding`: {},
+		`Set-Cookie`:        {},
+		`Age`:               {},
+	}
+
+	stores = map[string]Store{}
+)
+
+func init() {
+	gob.Register(&Entry{})
+}
+
+// Reg 注册存储引擎
+func Reg(name string, store Store) {
+	stores[name] = store
+}
+
+// Get 获取存储引擎
+func Get(name string) Store {
+	if store, ok := stores[name]; ok {
+		return store
+	}
+	return nil
+}
+
+// Del 删除存储引擎
+func Del(name string) {
+	if _, ok := stores[name]; ok {
+		delete(stores, name)
+	}
+}
+
+// Tag 为当前请求的响应设置标签，之后可以通过 Store.InvalidateTags 按标签删除缓存
+func Tag(c echo.Context, tags ...string) {
+	existing, _ := c.Internal().Get(tagsKey).([]string)
+	c.Internal().Set(tagsKey, append(existing, tags...))
+}
+
+// DefaultKeyGenerator 根据请求方式、主机名、路径和排序后的查询参数生成缓存键
+func DefaultKeyGenerator(c echo.Context) string {
+	req := c.Request()
+	method := req.Method()
+	if method == echo.HEAD {
+		method = echo.GET
+	}
+	key := method + ` ` + req.Host() + req.URL().Path()
+	if query := req.URL().Query(); len(query) > 0 {
+		normalized := make(url.Values, len(query))
+		for k, values := range query {
+			values = append([]string(nil), values...)
+			sort.Strings(values)
+			normalized[k] = values
+		}
+		key += `?` + normalized.Encode()
+	}
+	return key
+}
+
+// Cache returns a middleware which caches responses of GET and HEAD requests.
+func Cache(config ...*Config) echo.MiddlewareFunc {
+	if len(config) < 1 || config[0] == nil {
+		return CacheWithConfig(DefaultConfig)
+	}
+	return CacheWithConfig(config[0])
+}
+
+// CacheWithConfig returns a Cache middleware with config.
+// See: `Cache()`.
+func CacheWithConfig(config *Config) echo.MiddlewareFunc {
+	// Defaults
+	if config.Skipper == nil {
+		config.Skipper = DefaultConfig.Skipper
+	}
+	if config.TTL == 0 {
+		config.TTL = DefaultConfig.TTL
+	}
+	if len(config.Statuses) == 0 {
+		config.Statuses = DefaultConfig.Statuses
+	}
+	if config.KeyGenerator == nil {
+		config.KeyGenerator = DefaultConfig.KeyGenerator
+	}
+	if len(config.StatusHeader) == 0 {
+		config.StatusHeader = DefaultConfig.StatusHeader
+	}
+	statusHeader := config.StatusHeader
+	if statusHeader == `-` {
+		statusHeader = ``
+	}
+	if config.Store == nil && len(config.StoreName) > 0 {
+		config.Store = Get(config.StoreName)
+	}
+	if config.Store == nil {
+		config.Store = NewMemoryStore(DefaultMaxEntries)
+	}
+	store := config.Store
+
+	return func(h echo.Handler) echo.Handler {
+		return echo.HandlerFunc(func(c echo.Context) error {
+			if config.Skipper(c) {
+				return h.Handle(c)
+			}
+			req := c.Request()
+			if req.Method() != echo.GET && req.Method() != echo.HEAD {
+				return h.Handle(c)
+			}
+			reqCC := ParseCacheControl(req.Header().Get(echo.HeaderCacheControl))
+			if reqCC.Has(`no-store`) {
+				return h.Handle(c)
+			}
+			key := config.KeyPrefix + config.KeyGenerator(c)
+			// 带身份凭证的请求只使用明确允许共享缓存(public 或 s-maxage)的响应
+			authorized := len(req.Header().Get(echo.HeaderAuthorization)) > 0 || len(req.Header().Get(echo.HeaderCookie)) > 0
+			if !reqCC.Has(`no-cache`) && reqCC[`max-age`] != `0` {
+				entry, err := lookup(store, key, req.Header())
+				if err != nil {
+					c.Logger().Error(err)
+				} else if entry != nil && (!authorized || entry.shared()) {
+					replay(c.Response(), entry, statusHeader, req.Method() == echo.HEAD)
+					return nil
+				}
+			}
+
+			res := c.Response()
+			buf := engine.NewBufferedResponse(res)
+			c.SetResponse(buf)
+			err := h.Handle(c)
+			c.SetResponse(res)
+			if err != nil {
+				if buf.Committed() {
+					buf.Send()
+				}
+				return err
+			}
+			if buf.Passthrough() || !buf.Committed() {
+				return buf.Send()
+			}
+			if req.Method() == echo.GET && config.cacheable(buf.Status()) {
+				if ttl, ok := responseTTL(res.Header(), config.TTL, authorized); ok {
+					tags, _ := c.Internal().Get(tagsKey).([]string)
+					if err := save(store, key, req.Header(), res.Header(), buf.Status(), buf.Body(), tags, ttl); err != nil {
+						c.Logger().Error(err)
+					}
+				}
+			}
+			if len(statusHeader) > 0 {
+				res.Header().Set(statusHeader, `MISS`)
+			}
+			return buf.Send()
+		})
+	}
+}
+
+func (config *Config) cacheable(status int) bool {
+	for _, s := range config.Statuses {
+		if s == status {
+			return true
+		}
+	}
+	return false
+}
+
+// CacheControl Cache-Control 头部的指令
+type CacheControl map[string]string
+
+// ParseCacheControl 解析 Cache-Control 头部
+func ParseCacheControl(header string) CacheControl {
+	cc := CacheControl{}
+	for _, part := range strings.Split(header, `,`) {
+		part = strings.TrimSpace(part)
+		if len(part) == 0 {
+			continue
+		}
+		kv := strings.SplitN(part, `=`, 2)
+		name := strings.ToLower(strings.TrimSpace(kv[0]))
+		if len(kv) == 2 {
+			cc[name] = strings.Trim(strings.TrimSpace(kv[1]), `"`)
+		} else {
+			cc[name] = ``
+		}
+	}
+	return cc
+}
+
+// Has 是否包含指令
+func (cc CacheControl) Has(name string) bool {
+	_, ok := cc[name]
+	return ok
+}
+
+// Duration 获取时长类指令(如 max-age)的值
+func (cc CacheControl) Duration(name string) (time.Duration, bool) {
+	v, ok := cc[name]
+	if !ok {
+		return 0, false
+	}
+	seconds, err := strconv.ParseInt(v, 10, 64)
+	if err != nil || seconds < 0 {
+		return 0, false
+	}
+	return time.Duration(seconds) * time.Second, true
+}
+
+// Shared 是否明确允许共享缓存(public 或 s-maxage)
+func (cc CacheControl) Shared() bool {
+	return cc.Has(`public`) || cc.Has(`s-maxage`)
+}
+
+// responseTTL 根据响应头决定缓存时长，第二个返回值为 false 时不缓存。
+// authorized 为 true (请求带有 Authorization 或 Cookie)时，只缓存明确允许共享缓存的响应
+func responseTTL(header engine.Header, defaultTTL time.Duration, authorized bool) (time.Duration, bool) {
+	if len(header.Get(echo.HeaderSetCookie)) > 0 || strings.TrimSpace(header.Get(echo.HeaderVary)) == `*` {
+		return 0, false
+	}
+	cc := ParseCacheControl(header.Get(echo.HeaderCacheControl))
+	if cc.Has(`no-store`) || cc.Has(`no-cache`) || cc.Has(`private`) {
+		return 0, false
+	}
+	if authorized && !cc.Shared() {
+		return 0, false
+	}
+	if ttl, ok := cc.Duration(`s-maxage`); ok {
+		return ttl, ttl > 0
+	}
+	if ttl, ok := cc.Duration(`max-age`); ok {
+		return ttl, ttl > 0
+	}
+	return defaultTTL, defaultTTL > 0
+}
+
+func varyFields(header engine.Header) []string {
+	var fields []string
+	for _, v := range header.Std()[echo.HeaderVary] {
+		for _, f := range strings.Split(v, `,`) {
+			f = http.CanonicalHeaderKey(strings.TrimSpace(f))
+			if len(f) > 0 {
+				fields = append(fields, f)
+			}
+		}
+	}
+	sort.Strings(fields)
+	return fields
+}
+
+func variantKey(key string, vary []string, reqHeader engine.Header) string {
+	var b bytes.Buffer
+	b.WriteString(key)
+	for _, field := range vary {
+		b.WriteString(`|`)
+		b.WriteString(field)
+		b.WriteString(`:`)
+		b.WriteString(reqHeader.Get(field))
+	}
+	return b.String()
+}
+
+func lookup(store Store, key string, reqHeader engine.Header) (*Entry, error) {
+	entry, err := store.Get(key)
+	if err != nil || entry == nil {
+		return nil, err
+	}
+	if entry.Status == 0 && len(entry.Vary) > 0 {
+		return store.Get(variantKey(key, entry.Vary, reqHeader))
+	}
+	return entry, nil
+}
+
+func save(store Store, key string, reqHeader engine.Header, header engine.Header, status int, body []byte, tags []string, ttl time.Duration) error {
+	entry := &Entry{
+		Status:  status,
+		Header:  map[string][]string{},
+		Body:    append([]byte(nil), body...),
+		Tags:    tags,
+		Created: time.Now(),
+	}
+	for k, v := range header.Std() {
+		if _, ok := skipHeaders[k]; ok {
+			continue
+		}
+		entry.Header[k] = append([]string(nil), v...)
+	}
+	vary := varyFields(header)
+	if len(vary) == 0 {
+		return store.Set(key, entry, ttl)
+	}
+	marker := &Entry{Vary: vary, Tags: tags, Created: entry.Created}
+	if err := store.Set(key, marker, ttl); err != nil {
+		return err
+	}
+	return store.Set(variantKey(key, vary, reqHeader), entry, ttl)
+}
+
+// shared 缓存的响应是否明确允许共享缓存
+func (entry *Entry) shared() bool {
+	return ParseCacheControl(http.Header(entry.Header).Get(echo.HeaderCacheControl)).Shared()
+}
+
+// replay 输出缓存的响应，HEAD 请求不输出响应体
+func replay(res engine.Response, entry *Entry, statusHeader string, head bool) {
+	header := res.Header()
+	for k, values := range entry.Header {
+		header.Del(k)
+		for _, v := range values {
+			header.Add(k, v)
+		}
+	}
+	header.Set(`Age`, strconv.FormatInt(int64(time.Since(entry.Created)/time.Second), 10))
+	if len(statusHeader) > 0 {
+		header.Set(statusHeader, `HIT`)
+	}
+	res.WriteHeader(entry.Status)
+	if !head && len(entry.Body) > 0 {
+		res.Write(entry.Body)
+	}
+}
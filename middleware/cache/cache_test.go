@@ -0,0 +1,157 @@
+package cache
+
+import (
+	"io/ioutil"
+	"net/http"
+	"os"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/webx-top/echo"
+	test "github.com/webx-top/echo/testing"
+)
+
+func testStore(t *testing.T, store Store) {
+	entry, err := store.Get(`a`)
+	assert.NoError(t, err)
+	assert.Nil(t, entry)
+
+	assert.NoError(t, store.Set(`a`, &Entry{Status: 200, Body: []byte(`A`), Tags: []string{`user:1`}}, time.Minute))
+	assert.NoError(t, store.Set(`b`, &Entry{Status: 200, Body: []byte(`B`), Tags: []string{`user:1`, `list`}}, time.Minute))
+	assert.NoError(t, store.Set(`c`, &Entry{Status: 200, Body: []byte(`C`)}, time.Millisecond))
+	entry, err = store.Get(`a`)
+	assert.NoError(t, err)
+	assert.Equal(t, []byte(`A`), entry.Body)
+
+	time.Sleep(5 * time.Millisecond)
+	entry, _ = store.Get(`c`)
+	assert.Nil(t, entry)
+
+	assert.NoError(t, store.InvalidateTags(`user:1`))
+	entry, _ = store.Get(`a`)
+	assert.Nil(t, entry)
+	entry, _ = store.Get(`b`)
+	assert.Nil(t, entry)
+}
+
+func TestMemoryStore(t *testing.T) {
+	testStore(t, NewMemoryStore(10))
+
+	store := NewMemoryStore(2)
+	store.Set(`a`, &Entry{}, 0)
+	store.Set(`b`, &Entry{}, 0)
+	store.Get(`a`)
+	store.Set(`c`, &Entry{}, 0)
+	assert.Equal(t, 2, store.Len())
+	entry, _ := store.Get(`b`)
+	assert.Nil(t, entry)
+	entry, _ = store.Get(`a`)
+	assert.NotNil(t, entry)
+}
+
+func TestFileStore(t *testing.T) {
+	dir, err := ioutil.TempDir(``, `echo-cache-test`)
+	assert.NoError(t, err)
+	defer os.RemoveAll(dir)
+	store, err := NewFileStore(dir)
+	assert.NoError(t, err)
+	testStore(t, store)
+}
+
+func TestCacheMiddleware(t *testing.T) {
+	e := echo.New()
+	var hits int
+	e.Use(Cache(&Config{Store: NewMemoryStore(10)}))
+	e.Get(`/`, func(c echo.Context) error {
+		hits++
+		Tag(c, `home`)
+		echo.AddVary(c.Response().Header(), echo.HeaderAcceptLanguage)
+		return c.String(strconv.Itoa(hits) + `:` + c.Header(echo.HeaderAcceptLanguage))
+	})
+	e.Get(`/private`, func(c echo.Context) error {
+		hits++
+		c.Response().Header().Set(echo.HeaderCacheControl, `private`)
+		return c.String(strconv.Itoa(hits))
+	})
+	e.RebuildRouter()
+	lang := func(v string) func(*http.Request) {
+		return func(req *http.Request) {
+			req.Header.Set(echo.HeaderAcceptLanguage, v)
+		}
+	}
+
+	rec := test.Request(echo.GET, `/?b=2&a=1`, e, lang(`en`))
+	assert.Equal(t, `1:en`, rec.Body.String())
+	assert.Equal(t, `MISS`, rec.Header().Get(`X-Cache`))
+	rec = test.Request(echo.GET, `/?a=1&b=2`, e, lang(`en`))
+	assert.Equal(t, `1:en`, rec.Body.String())
+	assert.Equal(t, `HIT`, rec.Header().Get(`X-Cache`))
+	assert.Equal(t, echo.MIMETextPlainCharsetUTF8, rec.Header().Get(echo.HeaderContentType))
+	rec = test.Request(echo.GET, `/?a=1&b=2`, e, lang(`zh`))
+	assert.Equal(t, `2:zh`, rec.Body.String())
+	rec = test.Request(echo.GET, `/?a=1&b=2`, e, func(req *http.Request) {
+		req.Header.Set(echo.HeaderAcceptLanguage, `en`)
+		req.Header.Set(echo.HeaderCacheControl, `no-cache`)
+	})
+	assert.Equal(t, `3:en`, rec.Body.String())
+
+	rec = test.Request(echo.GET, `/private`, e)
+	assert.Equal(t, `4`, rec.Body.String())
+	rec = test.Request(echo.GET, `/private`, e)
+	assert.Equal(t, `5`, rec.Body.String())
+}
+
+func TestCacheAuthorized(t *testing.T) {
+	e := echo.New()
+	var hits int
+	e.Use(Cache(&Config{Store: NewMemoryStore(10)}))
+	e.Get(`/`, func(c echo.Context) error {
+		hits++
+		return c.String(strconv.Itoa(hits))
+	})
+	e.Get(`/public`, func(c echo.Context) error {
+		hits++
+		c.Response().Header().Set(echo.HeaderCacheControl, `public, max-age=60`)
+		return c.String(strconv.Itoa(hits))
+	})
+	e.RebuildRouter()
+	auth := func(req *http.Request) {
+		req.Header.Set(echo.HeaderAuthorization, `Bearer user1`)
+	}
+	cookie := func(req *http.Request) {
+		req.Header.Set(echo.HeaderCookie, `SID=user2`)
+	}
+
+	// 带凭证的请求不写入缓存
+	assert.Equal(t, `1`, test.Request(echo.GET, `/`, e, auth).Body.String())
+	assert.Equal(t, `2`, test.Request(echo.GET, `/`, e, cookie).Body.String())
+	// 匿名请求写入的缓存不会提供给带凭证的请求
+	assert.Equal(t, `3`, test.Request(echo.GET, `/`, e).Body.String())
+	assert.Equal(t, `3`, test.Request(echo.GET, `/`, e).Body.String())
+	rec := test.Request(echo.GET, `/`, e, auth)
+	assert.Equal(t, `4`, rec.Body.String())
+	assert.Equal(t, `MISS`, rec.Header().Get(`X-Cache`))
+
+	// 明确声明 public 的响应可以共享
+	assert.Equal(t, `5`, test.Request(echo.GET, `/public`, e, auth).Body.String())
+	rec = test.Request(echo.GET, `/public`, e, cookie)
+	assert.Equal(t, `5`, rec.Body.String())
+	assert.Equal(t, `HIT`, rec.Header().Get(`X-Cache`))
+}
+
+func TestCacheHead(t *testing.T) {
+	e := echo.New()
+	e.Use(Cache(&Config{Store: NewMemoryStore(10)}))
+	e.Get(`/`, func(c echo.Context) error {
+		return c.String(`body`)
+	})
+	e.RebuildRouter()
+
+	assert.Equal(t, `body`, test.Request(echo.GET, `/`, e).Body.String())
+	rec := test.Request(echo.HEAD, `/`, e)
+	assert.Equal(t, `HIT`, rec.Header().Get(`X-Cache`))
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.Empty(t, rec.Body.String())
+}
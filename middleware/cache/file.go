@@ -0,0 +1,153 @@
+package cache
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/sha1"
+	"encoding/gob"
+	"encoding/hex"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+)
+
+// NewFileStore 创建文件存储。dir 为空时使用系统临时目录下的 echo-cache 目录
+func NewFileStore(dir string) (*FileStore, error) {
+	if len(dir) == 0 {
+		dir = filepath.Join(os.TempDir(), `echo-cache`)
+	}
+	if err := os.MkdirAll(filepath.Join(dir, `tags`), os.ModePerm); err != nil {
+		return nil, err
+	}
+	return &FileStore{dir: dir}, nil
+}
+
+// FileStore 文件存储。每个条目保存为一个文件，标签索引保存在 tags 目录中
+type FileStore struct {
+	dir string
+	mu  sync.Mutex
+}
+
+type fileItem struct {
+	Expires time.Time
+	Entry   *Entry
+}
+
+func hashName(s string) string {
+	sum := sha1.Sum([]byte(s))
+	return hex.EncodeToString(sum[:])
+}
+
+func (s *FileStore) entryPath(key string) string {
+	name := hashName(key)
+	return filepath.Join(s.dir, name[0:2], name+`.cache`)
+}
+
+func (s *FileStore) tagPath(tag string) string {
+	return filepath.Join(s.dir, `tags`, hashName(tag))
+}
+
+func (s *FileStore) Get(key string) (*Entry, error) {
+	file := s.entryPath(key)
+	b, err := ioutil.ReadFile(file)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	item := &fileItem{}
+	if err = gob.NewDecoder(bytes.NewReader(b)).Decode(item); err != nil {
+		os.Remove(file)
+		return nil, err
+	}
+	if !item.Expires.IsZero() && time.Now().After(item.Expires) {
+		os.Remove(file)
+		return nil, nil
+	}
+	return item.Entry, nil
+}
+
+func (s *FileStore) Set(key string, entry *Entry, ttl time.Duration) error {
+	item := &fileItem{Entry: entry}
+	if ttl > 0 {
+		item.Expires = time.Now().Add(ttl)
+	}
+	var buf bytes.Buffer
+	if err := gob.NewEncoder(&buf).Encode(item); err != nil {
+		return err
+	}
+	file := s.entryPath(key)
+	if err := os.MkdirAll(filepath.Dir(file), os.ModePerm); err != nil {
+		return err
+	}
+	// 先写入临时文件再重命名，避免并发读取到不完整的内容
+	tmp := file + `.` + hashName(time.Now().String())[0:8] + `.tmp`
+	if err := ioutil.WriteFile(tmp, buf.Bytes(), 0600); err != nil {
+		return err
+	}
+	if err := os.Rename(tmp, file); err != nil {
+		os.Remove(tmp)
+		return err
+	}
+	if len(entry.Tags) == 0 {
+		return nil
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, tag := range entry.Tags {
+		f, err := os.OpenFile(s.tagPath(tag), os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0600)
+		if err != nil {
+			return err
+		}
+		_, err = f.WriteString(key + "\n")
+		f.Close()
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (s *FileStore) Delete(key string) error {
+	err := os.Remove(s.entryPath(key))
+	if os.IsNotExist(err) {
+		return nil
+	}
+	return err
+}
+
+func (s *FileStore) InvalidateTags(tags ...string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, tag := range tags {
+		file := s.tagPath(tag)
+		f, err := os.Open(file)
+		if err != nil {
+			if os.IsNotExist(err) {
+				continue
+			}
+			return err
+		}
+		scanner := bufio.NewScanner(f)
+		scanner.Buffer(make([]byte, 0, 4096), 1<<20)
+		for scanner.Scan() {
+			if err = s.Delete(scanner.Text()); err != nil {
+				break
+			}
+		}
+		if err == nil {
+			err = scanner.Err()
+		}
+		f.Close()
+		if err != nil {
+			return err
+		}
+		if err = os.Remove(file); err != nil && !os.IsNotExist(err) {
+			return err
+		}
+	}
+	return nil
+}
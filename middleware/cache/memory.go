@@ -0,0 +1,116 @@
+package cache
+
+import (
+	"container/list"
+	"sync"
+	"time"
+)
+
+// NewMemoryStore 创建内存存储。maxEntries 为最多保存的条目数，超过后淘汰最近最少使用的条目(<=0 时不限制)
+func NewMemoryStore(maxEntries int) *MemoryStore {
+	return &MemoryStore{
+		maxEntries: maxEntries,
+		ll:         list.New(),
+		items:      map[string]*list.Element{},
+		tags:       map[string]map[string]struct{}{},
+	}
+}
+
+// MemoryStore 基于 LRU 的内存存储
+type MemoryStore struct {
+	mu         sync.Mutex
+	maxEntries int
+	ll         *list.List
+	items      map[string]*list.Element
+	tags       map[string]map[string]struct{}
+}
+
+type memoryItem struct {
+	key     string
+	entry   *Entry
+	expires time.Time
+}
+
+func (s *MemoryStore) Get(key string) (*Entry, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	el, ok := s.items[key]
+	if !ok {
+		return nil, nil
+	}
+	item := el.Value.(*memoryItem)
+	if !item.expires.IsZero() && time.Now().After(item.expires) {
+		s.removeElement(el)
+		return nil, nil
+	}
+	s.ll.MoveToFront(el)
+	return item.entry, nil
+}
+
+func (s *MemoryStore) Set(key string, entry *Entry, ttl time.Duration) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if el, ok := s.items[key]; ok {
+		s.removeElement(el)
+	}
+	item := &memoryItem{key: key, entry: entry}
+	if ttl > 0 {
+		item.expires = time.Now().Add(ttl)
+	}
+	s.items[key] = s.ll.PushFront(item)
+	for _, tag := range entry.Tags {
+		keys, ok := s.tags[tag]
+		if !ok {
+			keys = map[string]struct{}{}
+			s.tags[tag] = keys
+		}
+		keys[key] = struct{}{}
+	}
+	for s.maxEntries > 0 && s.ll.Len() > s.maxEntries {
+		s.removeElement(s.ll.Back())
+	}
+	return nil
+}
+
+func (s *MemoryStore) Delete(key string) error {
+	s.mu.Lock()
+	if el, ok := s.items[key]; ok {
+		s.removeElement(el)
+	}
+	s.mu.Unlock()
+	return nil
+}
+
+func (s *MemoryStore) InvalidateTags(tags ...string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, tag := range tags {
+		for key := range s.tags[tag] {
+			if el, ok := s.items[key]; ok {
+				s.removeElement(el)
+			}
+		}
+		delete(s.tags, tag)
+	}
+	return nil
+}
+
+// Len 当前的条目数
+func (s *MemoryStore) Len() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.ll.Len()
+}
+
+func (s *MemoryStore) removeElement(el *list.Element) {
+	item := s.ll.Remove(el).(*memoryItem)
+	delete(s.items, item.key)
+	for _, tag := range item.entry.Tags {
+		if keys, ok := s.tags[tag]; ok {
+			delete(keys, item.key)
+			if len(keys) == 0 {
+				delete(s.tags, tag)
+			}
+		}
+	}
+}
@@ -0,0 +1,160 @@
+package redis
+
+import (
+	"bytes"
+	"encoding/gob"
+	"time"
+
+	"github.com/gomodule/redigo/redis"
+	"github.com/webx-top/echo/middleware/cache"
+)
+
+func New(opts *RedisOptions) cache.Store {
+	return NewRedisStore(opts)
+}
+
+func Reg(store cache.Store, args ...string) {
+	name := `redis`
+	if len(args) > 0 {
+		name = args[0]
+	}
+	cache.Reg(name, store)
+}
+
+func RegWithOptions(opts *RedisOptions, args ...string) cache.Store {
+	store := New(opts)
+	Reg(store, args...)
+	return store
+}
+
+type RedisOptions struct {
+	Size      int    `json:"size"`
+	Network   string `json:"network"`
+	Address   string `json:"address"`
+	Password  string `json:"password"`
+	DB        int    `json:"db"`
+	KeyPrefix string `json:"keyPrefix"`
+}
+
+// size: maximum number of idle connections.
+// network: tcp or udp
+// address: host:port
+// password: redis-password
+func NewRedisStore(opts *RedisOptions) *RedisStore {
+	network := opts.Network
+	if len(network) == 0 {
+		network = `tcp`
+	}
+	prefix := opts.KeyPrefix
+	if len(prefix) == 0 {
+		prefix = `echo_cache_`
+	}
+	pool := &redis.Pool{
+		MaxIdle:     opts.Size,
+		IdleTimeout: 240 * time.Second,
+		Dial: func() (redis.Conn, error) {
+			c, err := redis.Dial(network, opts.Address, redis.DialPassword(opts.Password), redis.DialDatabase(opts.DB))
+			if err != nil {
+				return nil, err
+			}
+			return c, nil
+		},
+		TestOnBorrow: func(c redis.Conn, t time.Time) error {
+			_, err := c.Do("PING")
+			return err
+		},
+	}
+	return NewRedisStoreWithPool(pool, prefix)
+}
+
+func NewRedisStoreWithPool(pool *redis.Pool, prefix string) *RedisStore {
+	return &RedisStore{Pool: pool, prefix: prefix}
+}
+
+// RedisStore stores cached responses in redis. Keys of each tag are kept in a redis set.
+type RedisStore struct {
+	Pool   *redis.Pool
+	prefix string
+}
+
+func (s *RedisStore) Get(key string) (*cache.Entry, error) {
+	conn := s.Pool.Get()
+	defer conn.Close()
+	b, err := redis.Bytes(conn.Do("GET", s.prefix+key))
+	if err != nil {
+		if err == redis.ErrNil {
+			return nil, nil
+		}
+		return nil, err
+	}
+	entry := &cache.Entry{}
+	if err = gob.NewDecoder(bytes.NewReader(b)).Decode(entry); err != nil {
+		return nil, err
+	}
+	return entry, nil
+}
+
+func (s *RedisStore) Set(key string, entry *cache.Entry, ttl time.Duration) error {
+	var buf bytes.Buffer
+	if err := gob.NewEncoder(&buf).Encode(entry); err != nil {
+		return err
+	}
+	conn := s.Pool.Get()
+	defer conn.Close()
+	args := []interface{}{s.prefix + key, buf.Bytes()}
+	ms := int64(ttl / time.Millisecond)
+	if ms > 0 {
+		args = append(args, "PX", ms)
+	}
+	if _, err := conn.Do("SET", args...); err != nil {
+		return err
+	}
+	for _, tag := range entry.Tags {
+		tagKey := s.prefix + `tag:` + tag
+		if _, err := conn.Do("SADD", tagKey, key); err != nil {
+			return err
+		}
+		if ms <= 0 {
+			continue
+		}
+		// 标签集合的过期时间不短于其中任何一个条目
+		remaining, err := redis.Int64(conn.Do("PTTL", tagKey))
+		if err != nil {
+			return err
+		}
+		if remaining < ms {
+			if _, err = conn.Do("PEXPIRE", tagKey, ms); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+func (s *RedisStore) Delete(key string) error {
+	conn := s.Pool.Get()
+	defer conn.Close()
+	_, err := conn.Do("DEL", s.prefix+key)
+	return err
+}
+
+func (s *RedisStore) InvalidateTags(tags ...string) error {
+	conn := s.Pool.Get()
+	defer conn.Close()
+	for _, tag := range tags {
+		tagKey := s.prefix + `tag:` + tag
+		keys, err := redis.Strings(conn.Do("SMEMBERS", tagKey))
+		if err != nil {
+			return err
+		}
+		args := make([]interface{}, 0, len(keys)+1)
+		for _, key := range keys {
+			args = append(args, s.prefix+key)
+		}
+		args = append(args, tagKey)
+		if _, err = conn.Do("DEL", args...); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -1,20 +1,49 @@
 package middleware
 
 import (
-	"bufio"
+	"compress/flate"
 	"compress/gzip"
 	"io"
 	"io/ioutil"
-	"net"
 	"net/http"
 	"strings"
+	"sync"
 
+	"github.com/andybalholm/brotli"
+	"github.com/klauspost/compress/zstd"
 	"github.com/webx-top/echo"
 	"github.com/webx-top/echo/engine"
 )
 
 type (
+	// CompressConfig defines the config for Compress middleware.
+	// 各字段为零值时使用 DefaultCompressConfig 中的默认值
+	CompressConfig struct {
+		// Skipper defines a function to skip middleware.
+		Skipper echo.Skipper `json:"-"`
+
+		// Compression level for gzip and deflate.
+		// Optional. Default value -1.
+		Level int `json:"level"`
+
+		// Levels 各编码的压缩级别，优先于 Level。未设置时 br 和 zstd 使用各自的默认级别
+		Levels map[string]int `json:"levels"`
+
+		// MinLength 响应内容达到此长度(字节)时才压缩，为负数时不限制长度
+		// Optional. Default value 1024.
+		MinLength int `json:"minLength"`
+
+		// Encodings 支持的编码，q 值相同时按此顺序优先
+		// Optional. Default value ["br", "zstd", "gzip", "deflate"].
+		Encodings []string `json:"encodings"`
+
+		// ContentTypes 允许压缩的内容类型。支持 `text/*` 形式的前缀、`*+json` 形式的后缀，`*` 表示所有类型
+		// Optional. Default value DefaultCompressContentTypes.
+		ContentTypes []string `json:"contentTypes"`
+	}
+
 	// GzipConfig defines the config for Gzip middleware.
+	// 各字段为零值时使用默认值，默认压缩所有长度和类型的响应
 	GzipConfig struct {
 		// Skipper defines a function to skip middleware.
 		Skipper echo.Skipper `json:"-"`
@@ -22,60 +51,192 @@ type (
 		// Gzip compression level.
 		// Optional. Default value -1.
 		Level int `json:"level"`
+
+		// MinLength 响应内容达到此长度(字节)时才压缩
+		// Optional. Default value 0.
+		MinLength int `json:"minLength"`
+
+		// ContentTypes 允许压缩的内容类型，格式同 CompressConfig.ContentTypes
+		// Optional. Default value ["*"].
+		ContentTypes []string `json:"contentTypes"`
+	}
+
+	// CompressWriter 可复用的压缩器
+	CompressWriter interface {
+		io.WriteCloser
+		Flush() error
+		Reset(io.Writer)
 	}
 
-	gzipWriter struct {
-		io.Writer
+	// CompressorFunc 按压缩级别创建压缩器，level 为 -1 时使用默认级别
+	CompressorFunc func(w io.Writer, level int) (CompressWriter, error)
+
+	compressResponse struct {
 		engine.Response
+		encoding    string
+		pool        *sync.Pool
+		minLength   int
+		allowed     func(string) bool
+		writer      CompressWriter
+		buf         []byte
+		status      int
+		wroteHeader bool
+		decided     bool
 	}
 )
 
 var (
+	// DefaultCompressConfig is the default Compress middleware config.
+	DefaultCompressConfig = &CompressConfig{
+		Skipper:      echo.DefaultSkipper,
+		Level:        -1,
+		MinLength:    1024,
+		Encodings:    []string{`br`, `zstd`, `gzip`, `deflate`},
+		ContentTypes: DefaultCompressContentTypes,
+	}
+
 	// DefaultGzipConfig is the default Gzip middleware config.
 	DefaultGzipConfig = &GzipConfig{
 		Skipper: echo.DefaultSkipper,
 		Level:   -1,
 	}
-)
 
-func (w *gzipWriter) WriteHeader(code int) {
-	if code == http.StatusNoContent {
-		w.Header().Del(echo.HeaderContentEncoding)
+	// DefaultCompressContentTypes 默认允许压缩的内容类型(不含已压缩的图片、音视频和归档文件)
+	DefaultCompressContentTypes = []string{
+		`text/*`,
+		`*+json`,
+		`*+xml`,
+		`application/json`,
+		`application/javascript`,
+		`application/x-javascript`,
+		`application/xml`,
+		`application/wasm`,
+		`application/graphql`,
+		`application/vnd.ms-fontobject`,
+		`application/x-font-ttf`,
+		`font/ttf`,
+		`font/otf`,
+		`image/svg+xml`,
+		`image/x-icon`,
+		`image/bmp`,
 	}
-	w.Header().Del(echo.HeaderContentLength)
-	w.WriteHeader(code)
-}
 
-func (w *gzipWriter) Write(b []byte) (int, error) {
-	if len(w.Header().Get(echo.HeaderContentType)) == 0 {
-		w.Header().Set(echo.HeaderContentType, http.DetectContentType(b))
+	// Compressors 已注册的压缩器
+	Compressors = map[string]CompressorFunc{
+		`br`: func(w io.Writer, level int) (CompressWriter, error) {
+			if level < 0 {
+				level = brotli.DefaultCompression
+			}
+			return brotli.NewWriterLevel(w, level), nil
+		},
+		`zstd`: func(w io.Writer, level int) (CompressWriter, error) {
+			encLevel := zstd.SpeedDefault
+			if level > 0 {
+				encLevel = zstd.EncoderLevelFromZstd(level)
+			}
+			return zstd.NewWriter(w, zstd.WithEncoderLevel(encLevel), zstd.WithEncoderConcurrency(1))
+		},
+		`gzip`: func(w io.Writer, level int) (CompressWriter, error) {
+			return gzip.NewWriterLevel(w, level)
+		},
+		`deflate`: func(w io.Writer, level int) (CompressWriter, error) {
+			return flate.NewWriter(w, level)
+		},
 	}
-	return w.Writer.Write(b)
+)
+
+// RegisterCompressor 注册压缩器
+func RegisterCompressor(encoding string, fn CompressorFunc) {
+	Compressors[encoding] = fn
 }
 
-func (w *gzipWriter) Flush() {
-	w.Writer.(*gzip.Writer).Flush()
-	if flusher, ok := w.Response.(http.Flusher); ok {
-		flusher.Flush()
-		return
-	}
-	if flusher, ok := w.StdResponseWriter().(http.Flusher); ok {
-		flusher.Flush()
+// Compress returns a middleware which compresses HTTP response using
+// the best encoding accepted by the client.
+func Compress(config ...*CompressConfig) echo.MiddlewareFunc {
+	if len(config) < 1 || config[0] == nil {
+		return CompressWithConfig(DefaultCompressConfig)
 	}
+	return CompressWithConfig(config[0])
 }
 
-func (w *gzipWriter) Hijack() (net.Conn, *bufio.ReadWriter, error) {
-	if hijacker, ok := w.Response.(http.Hijacker); ok {
-		return hijacker.Hijack()
+// CompressWithConfig return Compress middleware with config.
+// See: `Compress()`.
+func CompressWithConfig(config *CompressConfig) echo.MiddlewareFunc {
+	// Defaults
+	if config.Skipper == nil {
+		config.Skipper = DefaultCompressConfig.Skipper
+	}
+	if config.Level == 0 {
+		config.Level = DefaultCompressConfig.Level
+	}
+	if config.MinLength == 0 {
+		config.MinLength = DefaultCompressConfig.MinLength
+	}
+	minLength := config.MinLength
+	if minLength < 0 {
+		minLength = 0
+	}
+	if len(config.Encodings) == 0 {
+		config.Encodings = DefaultCompressConfig.Encodings
+	}
+	if len(config.ContentTypes) == 0 {
+		config.ContentTypes = DefaultCompressConfig.ContentTypes
+	}
+	pools := map[string]*sync.Pool{}
+	for _, encoding := range config.Encodings {
+		fn, ok := Compressors[encoding]
+		if !ok {
+			panic("echo: unsupported compression encoding: " + encoding)
+		}
+		level, ok := config.Levels[encoding]
+		if !ok {
+			level = -1
+			if encoding == `gzip` || encoding == `deflate` {
+				level = config.Level
+			}
+		}
+		w, err := fn(ioutil.Discard, level)
+		if err != nil {
+			panic(err)
+		}
+		pool := &sync.Pool{New: func() interface{} {
+			w, _ := fn(ioutil.Discard, level)
+			return w
+		}}
+		pool.Put(w)
+		pools[encoding] = pool
+	}
+	allowed := func(contentType string) bool {
+		return compressibleType(config.ContentTypes, contentType)
 	}
-	return w.StdResponseWriter().(http.Hijacker).Hijack()
-}
 
-func (w *gzipWriter) CloseNotify() <-chan bool {
-	if closeNotifiler, ok := w.Response.(http.CloseNotifier); ok {
-		return closeNotifiler.CloseNotify()
+	return func(h echo.Handler) echo.Handler {
+		return echo.HandlerFunc(func(c echo.Context) error {
+			if config.Skipper(c) {
+				return h.Handle(c)
+			}
+			encoding := negotiateEncoding(c.Request().Header().Get(echo.HeaderAcceptEncoding), config.Encodings)
+			res := c.Response()
+			w := &compressResponse{
+				Response:  res,
+				encoding:  encoding,
+				minLength: minLength,
+				allowed:   allowed,
+			}
+			if len(encoding) > 0 {
+				w.pool = pools[encoding]
+			} else {
+				// 不压缩时无需缓冲，只是在输出前判断是否需要设置 Vary
+				w.minLength = 0
+			}
+			c.SetResponse(w)
+			defer func() {
+				c.SetResponse(res)
+				w.close()
+			}()
+			return h.Handle(c)
+		})
 	}
-	return w.StdResponseWriter().(http.CloseNotifier).CloseNotify()
 }
 
 // Gzip returns a middleware which compresses HTTP response using gzip compression
@@ -90,45 +251,221 @@ func Gzip(config ...*GzipConfig) echo.MiddlewareFunc {
 // GzipWithConfig return Gzip middleware with config.
 // See: `Gzip()`.
 func GzipWithConfig(config *GzipConfig) echo.MiddlewareFunc {
-	// Defaults
-	if config.Skipper == nil {
-		config.Skipper = DefaultGzipConfig.Skipper
+	minLength := config.MinLength
+	if minLength <= 0 {
+		minLength = -1
 	}
-	if config.Level == 0 {
-		config.Level = DefaultGzipConfig.Level
+	contentTypes := config.ContentTypes
+	if len(contentTypes) == 0 {
+		contentTypes = []string{`*`}
 	}
-	scheme := `gzip`
+	return CompressWithConfig(&CompressConfig{
+		Skipper:      config.Skipper,
+		Level:        config.Level,
+		MinLength:    minLength,
+		Encodings:    []string{`gzip`},
+		ContentTypes: contentTypes,
+	})
+}
 
-	return func(h echo.Handler) echo.Handler {
-		return echo.HandlerFunc(func(c echo.Context) error {
-			if config.Skipper(c) {
-				return h.Handle(c)
+// negotiateEncoding 根据 Accept-Encoding 选择 q 值最高的编码，q 值相同时按 supported 的顺序
+func negotiateEncoding(header string, supported []string) string {
+	if len(header) == 0 {
+		return ``
+	}
+	accepts := echo.ParseAcceptQuality(header)
+	var (
+		best  string
+		bestQ float64
+	)
+	for _, encoding := range supported {
+		if q := encodingQuality(accepts, encoding); q > bestQ {
+			best = encoding
+			bestQ = q
+		}
+	}
+	return best
+}
+
+// encodingQuality 返回客户端对编码的 q 值，不接受时返回 0
+func encodingQuality(accepts []*echo.AcceptQuality, encoding string) float64 {
+	wildcard := 0.0
+	var hasWildcard bool
+	for _, a := range accepts {
+		value := a.Value
+		if value == `x-gzip` {
+			value = `gzip`
+		}
+		if value == encoding {
+			return a.Q
+		}
+		if value == `*` && !hasWildcard {
+			wildcard = a.Q
+			hasWildcard = true
+		}
+	}
+	return wildcard
+}
+
+func compressibleType(allowed []string, contentType string) bool {
+	if i := strings.Index(contentType, `;`); i >= 0 {
+		contentType = contentType[:i]
+	}
+	contentType = strings.ToLower(strings.TrimSpace(contentType))
+	if len(contentType) == 0 {
+		return false
+	}
+	for _, t := range allowed {
+		switch {
+		case t == `*` || t == `*/*`:
+			return true
+		case strings.HasSuffix(t, `/*`):
+			if strings.HasPrefix(contentType, t[:len(t)-1]) {
+				return true
 			}
-			resp := c.Response()
-			resp.Header().Add(echo.HeaderVary, echo.HeaderAcceptEncoding)
-			if strings.Contains(c.Request().Header().Get(echo.HeaderAcceptEncoding), scheme) {
-				resp.Header().Add(echo.HeaderContentEncoding, scheme)
-				rw := resp.Writer()
-				w, err := gzip.NewWriterLevel(rw, config.Level)
-				if err != nil {
-					return err
-				}
-				defer func() {
-					if resp.Size() == 0 {
-						if resp.Header().Get(echo.HeaderContentEncoding) == scheme {
-							resp.Header().Del(echo.HeaderContentEncoding)
-						}
-						// We have to reset response to it's pristine state when
-						// nothing is written to body or error is returned.
-						// See issue #424, #407.
-						resp.SetWriter(rw)
-						w.Reset(ioutil.Discard)
-					}
-					w.Close()
-				}()
-				resp.SetWriter(&gzipWriter{Writer: w, Response: resp})
+		case strings.HasPrefix(t, `*`):
+			if strings.HasSuffix(contentType, t[1:]) {
+				return true
 			}
-			return h.Handle(c)
-		})
+		case t == contentType:
+			return true
+		}
+	}
+	return false
+}
+
+func (w *compressResponse) WriteHeader(code int) {
+	if w.decided {
+		w.Response.WriteHeader(code)
+		return
+	}
+	if w.wroteHeader {
+		return
+	}
+	w.status = code
+	w.wroteHeader = true
+}
+
+func (w *compressResponse) Write(b []byte) (int, error) {
+	if !w.wroteHeader && !w.decided {
+		w.WriteHeader(http.StatusOK)
+	}
+	if w.decided {
+		if w.writer != nil {
+			return w.writer.Write(b)
+		}
+		return w.Response.Write(b)
+	}
+	w.buf = append(w.buf, b...)
+	if len(w.buf) >= w.minLength {
+		if err := w.decide(true); err != nil {
+			return 0, err
+		}
+	}
+	return len(b), nil
+}
+
+func (w *compressResponse) Status() int {
+	if !w.decided && w.wroteHeader {
+		return w.status
+	}
+	return w.Response.Status()
+}
+
+func (w *compressResponse) Size() int64 {
+	if !w.decided {
+		return int64(len(w.buf))
+	}
+	return w.Response.Size()
+}
+
+func (w *compressResponse) Committed() bool {
+	return w.wroteHeader || w.Response.Committed()
+}
+
+// decide 确定是否压缩并输出暂存的状态码和内容
+func (w *compressResponse) decide(compress bool) error {
+	w.decided = true
+	if w.Response.Committed() {
+		if len(w.buf) > 0 {
+			_, err := w.Response.Write(w.buf)
+			w.buf = nil
+			return err
+		}
+		return nil
+	}
+	header := w.Response.Header()
+	if len(header.Get(echo.HeaderContentType)) == 0 && len(w.buf) > 0 {
+		header.Set(echo.HeaderContentType, http.DetectContentType(w.buf))
+	}
+	status := w.status
+	if status == 0 {
+		status = http.StatusOK
+	}
+	eligible := status >= http.StatusOK &&
+		status != http.StatusNoContent &&
+		status != http.StatusNotModified &&
+		status != http.StatusPartialContent &&
+		len(header.Get(echo.HeaderContentEncoding)) == 0 &&
+		len(header.Get(echo.HeaderContentRange)) == 0 &&
+		w.allowed(header.Get(echo.HeaderContentType))
+	if eligible {
+		echo.AddVary(header, echo.HeaderAcceptEncoding)
+	}
+	if eligible && compress && w.pool != nil {
+		if writer, ok := w.pool.Get().(CompressWriter); ok {
+			writer.Reset(w.Response)
+			w.writer = writer
+			header.Set(echo.HeaderContentEncoding, w.encoding)
+			header.Del(echo.HeaderContentLength)
+			if etag := header.Get(echo.HeaderETag); len(etag) > 0 && !strings.HasPrefix(etag, `W/`) {
+				header.Set(echo.HeaderETag, `W/`+etag)
+			}
+		}
+	}
+	w.Response.WriteHeader(status)
+	if len(w.buf) == 0 {
+		return nil
+	}
+	var err error
+	if w.writer != nil {
+		_, err = w.writer.Write(w.buf)
+	} else {
+		_, err = w.Response.Write(w.buf)
+	}
+	w.buf = nil
+	return err
+}
+
+func (w *compressResponse) close() {
+	if !w.decided {
+		if !w.wroteHeader && !w.Response.Committed() {
+			return
+		}
+		// 内容长度未达到 MinLength
+		w.decide(w.minLength <= 0 && len(w.buf) > 0)
+	}
+	if w.writer == nil {
+		return
+	}
+	w.writer.Close()
+	w.writer.Reset(ioutil.Discard)
+	w.pool.Put(w.writer)
+	w.writer = nil
+}
+
+func (w *compressResponse) Flush() {
+	if !w.decided {
+		w.decide(len(w.buf) > 0)
+	}
+	if w.writer != nil {
+		w.writer.Flush()
+	}
+	if flusher, ok := w.Response.(http.Flusher); ok {
+		flusher.Flush()
+		return
+	}
+	if flusher, ok := w.StdResponseWriter().(http.Flusher); ok {
+		flusher.Flush()
 	}
 }
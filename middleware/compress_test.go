@@ -0,0 +1,186 @@
+package middleware
+
+import (
+	"compress/gzip"
+	"io/ioutil"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/andybalholm/brotli"
+	"github.com/stretchr/testify/assert"
+	"github.com/webx-top/echo"
+	test "github.com/webx-top/echo/testing"
+)
+
+func acceptEncoding(value string) func(*http.Request) {
+	return func(req *http.Request) {
+		req.Header.Set(echo.HeaderAcceptEncoding, value)
+	}
+}
+
+func TestNegotiateEncoding(t *testing.T) {
+	all := []string{`br`, `zstd`, `gzip`, `deflate`}
+	assert.Equal(t, `br`, negotiateEncoding(`gzip, deflate, br`, all))
+	assert.Equal(t, `gzip`, negotiateEncoding(`gzip;q=1, br;q=0.5`, all))
+	assert.Equal(t, `deflate`, negotiateEncoding(`gzip;q=0.2, deflate;q=0.8`, all))
+	assert.Equal(t, ``, negotiateEncoding(`identity`, all))
+	assert.Equal(t, ``, negotiateEncoding(`gzip;q=0`, all))
+	assert.Equal(t, `zstd`, negotiateEncoding(`*;q=0.1, br;q=0`, all))
+	assert.Equal(t, `gzip`, negotiateEncoding(`x-gzip`, all))
+	assert.Equal(t, `gzip`, negotiateEncoding(`br, gzip`, []string{`gzip`}))
+}
+
+func TestCompress(t *testing.T) {
+	e := echo.New()
+	e.Use(Compress())
+	big := strings.Repeat(`hello world `, 200)
+	e.Get(`/big`, func(c echo.Context) error {
+		return c.String(big)
+	})
+	e.Get(`/small`, func(c echo.Context) error {
+		return c.String(`hi`)
+	})
+	e.Get(`/png`, func(c echo.Context) error {
+		c.Response().Header().Set(echo.HeaderContentType, `image/png`)
+		return c.Blob([]byte(big))
+	})
+	e.Get(`/encoded`, func(c echo.Context) error {
+		c.Response().Header().Set(echo.HeaderContentType, echo.MIMETextPlain)
+		c.Response().Header().Set(echo.HeaderContentEncoding, `gzip`)
+		return c.Blob([]byte(big))
+	})
+	e.Get(`/empty`, func(c echo.Context) error {
+		return c.NoContent(http.StatusNoContent)
+	})
+	e.RebuildRouter()
+
+	rec := test.Request(echo.GET, `/big`, e, acceptEncoding(`gzip`))
+	assert.Equal(t, `gzip`, rec.Header().Get(echo.HeaderContentEncoding))
+	assert.Equal(t, echo.HeaderAcceptEncoding, rec.Header().Get(echo.HeaderVary))
+	r, err := gzip.NewReader(rec.Body)
+	assert.NoError(t, err)
+	b, _ := ioutil.ReadAll(r)
+	assert.Equal(t, big, string(b))
+
+	// 按 Encodings 的顺序优先，q 值优先于顺序
+	rec = test.Request(echo.GET, `/big`, e, acceptEncoding(`gzip, br`))
+	assert.Equal(t, `br`, rec.Header().Get(echo.HeaderContentEncoding))
+	b, _ = ioutil.ReadAll(brotli.NewReader(rec.Body))
+	assert.Equal(t, big, string(b))
+	rec = test.Request(echo.GET, `/big`, e, acceptEncoding(`gzip, br;q=0.5`))
+	assert.Equal(t, `gzip`, rec.Header().Get(echo.HeaderContentEncoding))
+
+	// 未达到 MinLength
+	rec = test.Request(echo.GET, `/small`, e, acceptEncoding(`gzip`))
+	assert.Empty(t, rec.Header().Get(echo.HeaderContentEncoding))
+	assert.Equal(t, `hi`, rec.Body.String())
+
+	// 不压缩的内容类型
+	rec = test.Request(echo.GET, `/png`, e, acceptEncoding(`gzip`))
+	assert.Empty(t, rec.Header().Get(echo.HeaderContentEncoding))
+	assert.Empty(t, rec.Header().Get(echo.HeaderVary))
+	assert.Equal(t, big, rec.Body.String())
+
+	// 已经编码的响应
+	rec = test.Request(echo.GET, `/encoded`, e, acceptEncoding(`br`))
+	assert.Equal(t, `gzip`, rec.Header().Get(echo.HeaderContentEncoding))
+	assert.Equal(t, big, rec.Body.String())
+
+	// 客户端不支持压缩时仍然需要 Vary
+	rec = test.Request(echo.GET, `/big`, e)
+	assert.Empty(t, rec.Header().Get(echo.HeaderContentEncoding))
+	assert.Equal(t, echo.HeaderAcceptEncoding, rec.Header().Get(echo.HeaderVary))
+	assert.Equal(t, big, rec.Body.String())
+
+	rec = test.Request(echo.GET, `/empty`, e, acceptEncoding(`gzip`))
+	assert.Equal(t, http.StatusNoContent, rec.Code)
+	assert.Empty(t, rec.Header().Get(echo.HeaderContentEncoding))
+}
+
+func TestCompressMinLength(t *testing.T) {
+	chunks := func(c echo.Context) error {
+		c.Response().Header().Set(echo.HeaderContentType, echo.MIMETextPlain)
+		c.Response().Write([]byte(`hello `))
+		c.Response().Write([]byte(`world`))
+		return nil
+	}
+
+	// 分多次写入，累计达到 MinLength 后才压缩
+	e := echo.New()
+	e.Use(Compress(&CompressConfig{MinLength: 10}))
+	e.Get(`/`, chunks)
+	e.RebuildRouter()
+	rec := test.Request(echo.GET, `/`, e, acceptEncoding(`gzip`))
+	assert.Equal(t, `gzip`, rec.Header().Get(echo.HeaderContentEncoding))
+	r, err := gzip.NewReader(rec.Body)
+	assert.NoError(t, err)
+	b, _ := ioutil.ReadAll(r)
+	assert.Equal(t, `hello world`, string(b))
+
+	e = echo.New()
+	e.Use(Compress(&CompressConfig{MinLength: 12}))
+	e.Get(`/`, chunks)
+	e.RebuildRouter()
+	rec = test.Request(echo.GET, `/`, e, acceptEncoding(`gzip`))
+	assert.Empty(t, rec.Header().Get(echo.HeaderContentEncoding))
+	assert.Equal(t, `hello world`, rec.Body.String())
+
+	// 为负数时压缩所有响应
+	e = echo.New()
+	e.Use(Compress(&CompressConfig{MinLength: -1}))
+	e.Get(`/`, func(c echo.Context) error {
+		return c.String(`hi`)
+	})
+	e.RebuildRouter()
+	rec = test.Request(echo.GET, `/`, e, acceptEncoding(`gzip`))
+	assert.Equal(t, `gzip`, rec.Header().Get(echo.HeaderContentEncoding))
+
+	// 为 0 时使用默认值
+	e = echo.New()
+	e.Use(Compress(&CompressConfig{}))
+	e.Get(`/`, func(c echo.Context) error {
+		return c.String(`hi`)
+	})
+	e.RebuildRouter()
+	rec = test.Request(echo.GET, `/`, e, acceptEncoding(`gzip`))
+	assert.Empty(t, rec.Header().Get(echo.HeaderContentEncoding))
+	assert.Equal(t, `hi`, rec.Body.String())
+}
+
+func TestGzip(t *testing.T) {
+	// 默认与原来一样压缩所有长度和类型的响应
+	e := echo.New()
+	e.Use(Gzip())
+	e.Get(`/small`, func(c echo.Context) error {
+		return c.String(`hi`)
+	})
+	e.Get(`/png`, func(c echo.Context) error {
+		c.Response().Header().Set(echo.HeaderContentType, `image/png`)
+		return c.Blob([]byte(`png`))
+	})
+	e.RebuildRouter()
+	for _, path := range []string{`/small`, `/png`} {
+		rec := test.Request(echo.GET, path, e, acceptEncoding(`gzip, br`))
+		assert.Equal(t, `gzip`, rec.Header().Get(echo.HeaderContentEncoding), path)
+		r, err := gzip.NewReader(rec.Body)
+		assert.NoError(t, err, path)
+		b, _ := ioutil.ReadAll(r)
+		assert.NotEmpty(t, b, path)
+	}
+
+	e = echo.New()
+	e.Use(GzipWithConfig(&GzipConfig{MinLength: 10, ContentTypes: []string{`text/*`}}))
+	e.Get(`/small`, func(c echo.Context) error {
+		return c.String(`hi`)
+	})
+	e.Get(`/png`, func(c echo.Context) error {
+		c.Response().Header().Set(echo.HeaderContentType, `image/png`)
+		return c.Blob([]byte(strings.Repeat(`png`, 10)))
+	})
+	e.RebuildRouter()
+	for _, path := range []string{`/small`, `/png`} {
+		rec := test.Request(echo.GET, path, e, acceptEncoding(`gzip`))
+		assert.Empty(t, rec.Header().Get(echo.HeaderContentEncoding), path)
+	}
+}
@@ -4,6 +4,7 @@ import (
 	"net/http"
 	"strconv"
 	"strings"
+	"sync"
 
 	"github.com/webx-top/echo"
 )
@@ -15,9 +16,15 @@ type (
 		Skipper echo.Skipper
 
 		// AllowOrigin defines a list of origins that may access the resource.
+		// Wildcard subdomains such as "https://*.example.com" are supported.
 		// Optional with default value as []string{"*"}.
 		AllowOrigins []string
 
+		// AllowOriginFunc is a custom function to validate the origin (e.g. tenant lookup).
+		// It is called when the origin does not match AllowOrigins.
+		// Optional.
+		AllowOriginFunc func(c echo.Context, origin string) (bool, error)
+
 		// AllowMethods defines a list methods allowed when accessing the resource.
 		// This is used in response to a preflight request.
 		// Optional with default value as `DefaultCORSConfig.AllowMethods`.
@@ -35,15 +42,42 @@ type (
 		// Optional with default value as false.
 		AllowCredentials bool
 
+		// AllowPrivateNetwork indicates whether or not to respond to the Private Network Access
+		// preflight header `Access-Control-Request-Private-Network: true`.
+		// Optional with default value as false.
+		AllowPrivateNetwork bool
+
 		// ExposeHeaders defines a whitelist headers that clients are allowed to
 		// access.
 		// Optional with default value as []string{}.
 		ExposeHeaders []string
 
 		// MaxAge indicates how long (in seconds) the results of a preflight request
-		// can be cached.
+		// can be cached. A negative value sends "0" to disable caching.
 		// Optional with default value as 0.
 		MaxAge int
+
+		// MetaKey is the key of `Route.Meta` holding a per-route policy (*CORSConfig),
+		// or false to disable CORS for the route.
+		// Optional with default value as "cors".
+		MetaKey string
+
+		// Groups defines per-group policies keyed by the group prefix (`Route.Prefix`).
+		// Optional.
+		Groups map[string]*CORSConfig
+	}
+
+	corsPolicy struct {
+		allowAll         bool
+		allowOrigins     map[string]struct{}
+		allowPatterns    [][2]string // prefix, suffix
+		allowOriginFunc  func(c echo.Context, origin string) (bool, error)
+		allowMethods     string
+		allowHeaders     string
+		allowCredentials bool
+		allowPrivateNet  bool
+		exposeHeaders    string
+		maxAge           string
 	}
 )
 
@@ -53,6 +87,7 @@ var (
 		Skipper:      echo.DefaultSkipper,
 		AllowOrigins: []string{"*"},
 		AllowMethods: []string{echo.GET, echo.HEAD, echo.PUT, echo.POST, echo.DELETE},
+		MetaKey:      "cors",
 	}
 )
 
@@ -64,22 +99,47 @@ func CORS() echo.MiddlewareFunc {
 
 // CORSFromConfig returns a CORS middleware from config.
 // See `CORS()`.
+//
+// Registered with `Echo.Use()` or `Echo.Pre()`, the middleware runs before routing,
+// so preflight requests are answered even when no OPTIONS route is registered
+// and per-route policies are resolved from the route of `Access-Control-Request-Method`.
 func CORSWithConfig(config CORSConfig) echo.MiddlewareFunc {
 	// Defaults
 	if config.Skipper == nil {
 		config.Skipper = DefaultCORSConfig.Skipper
 	}
-	if len(config.AllowOrigins) == 0 {
-		config.AllowOrigins = DefaultCORSConfig.AllowOrigins
+	if config.MetaKey == "" {
+		config.MetaKey = DefaultCORSConfig.MetaKey
 	}
-	if len(config.AllowMethods) == 0 {
-		config.AllowMethods = DefaultCORSConfig.AllowMethods
+	policy := newCORSPolicy(&config)
+	var policies sync.Map // *CORSConfig => *corsPolicy
+	routePolicy := func(c echo.Context, method string) *corsPolicy {
+		req := c.Request()
+		route := c.Echo().FindRoute(req.Host(), method, req.URL().Path())
+		if route == nil {
+			return policy
+		}
+		var cfg *CORSConfig
+		switch v := route.Meta[config.MetaKey].(type) {
+		case *CORSConfig:
+			cfg = v
+		case bool:
+			if !v {
+				return nil
+			}
+		}
+		if cfg == nil {
+			cfg = config.Groups[route.Prefix]
+		}
+		if cfg == nil {
+			return policy
+		}
+		if p, ok := policies.Load(cfg); ok {
+			return p.(*corsPolicy)
+		}
+		p, _ := policies.LoadOrStore(cfg, newCORSPolicy(cfg))
+		return p.(*corsPolicy)
 	}
-	allowOrigins := strings.Join(config.AllowOrigins, ",")
-	allowMethods := strings.Join(config.AllowMethods, ",")
-	allowHeaders := strings.Join(config.AllowHeaders, ",")
-	exposeHeaders := strings.Join(config.ExposeHeaders, ",")
-	maxAge := strconv.Itoa(config.MaxAge)
 
 	return func(next echo.Handler) echo.Handler {
 		return echo.HandlerFunc(func(c echo.Context) error {
@@ -88,41 +148,134 @@ func CORSWithConfig(config CORSConfig) echo.MiddlewareFunc {
 			}
 			req := c.Request()
 			header := c.Response().Header()
+			origin := req.Header().Get(echo.HeaderOrigin)
+			requestMethod := req.Header().Get(echo.HeaderAccessControlRequestMethod)
+			preflight := req.Method() == echo.OPTIONS && requestMethod != ""
+
+			// The response varies by origin even if it is not a CORS request
+			header.Add(echo.HeaderVary, echo.HeaderOrigin)
+			if origin == "" {
+				return next.Handle(c)
+			}
+			method := req.Method()
+			if preflight {
+				method = requestMethod
+			}
+			p := routePolicy(c, method)
+			if p == nil {
+				return next.Handle(c)
+			}
+			allowOrigin, err := p.allowOrigin(c, origin)
+			if err != nil {
+				return err
+			}
 
 			// Simple request
-			if req.Method() != echo.OPTIONS {
-				header.Add(echo.HeaderVary, echo.HeaderOrigin)
-				header.Set(echo.HeaderAccessControlAllowOrigin, allowOrigins)
-				if config.AllowCredentials {
+			if !preflight {
+				if allowOrigin == "" {
+					return next.Handle(c)
+				}
+				header.Set(echo.HeaderAccessControlAllowOrigin, allowOrigin)
+				if p.allowCredentials {
 					header.Set(echo.HeaderAccessControlAllowCredentials, "true")
 				}
-				if exposeHeaders != "" {
-					header.Set(echo.HeaderAccessControlExposeHeaders, exposeHeaders)
+				if p.exposeHeaders != "" {
+					header.Set(echo.HeaderAccessControlExposeHeaders, p.exposeHeaders)
 				}
 				return next.Handle(c)
 			}
 
 			// Preflight request
-			header.Add(echo.HeaderVary, echo.HeaderOrigin)
 			header.Add(echo.HeaderVary, echo.HeaderAccessControlRequestMethod)
 			header.Add(echo.HeaderVary, echo.HeaderAccessControlRequestHeaders)
-			header.Set(echo.HeaderAccessControlAllowOrigin, allowOrigins)
-			header.Set(echo.HeaderAccessControlAllowMethods, allowMethods)
-			if config.AllowCredentials {
+			if allowOrigin == "" {
+				return c.NoContent(http.StatusNoContent)
+			}
+			header.Set(echo.HeaderAccessControlAllowOrigin, allowOrigin)
+			header.Set(echo.HeaderAccessControlAllowMethods, p.allowMethods)
+			if p.allowCredentials {
 				header.Set(echo.HeaderAccessControlAllowCredentials, "true")
 			}
-			if allowHeaders != "" {
-				header.Set(echo.HeaderAccessControlAllowHeaders, allowHeaders)
+			if p.allowHeaders != "" {
+				header.Set(echo.HeaderAccessControlAllowHeaders, p.allowHeaders)
 			} else {
 				h := req.Header().Get(echo.HeaderAccessControlRequestHeaders)
 				if h != "" {
 					header.Set(echo.HeaderAccessControlAllowHeaders, h)
 				}
 			}
-			if config.MaxAge > 0 {
-				header.Set(echo.HeaderAccessControlMaxAge, maxAge)
+			if p.allowPrivateNet && req.Header().Get(echo.HeaderAccessControlRequestPrivateNetwork) == "true" {
+				header.Set(echo.HeaderAccessControlAllowPrivateNetwork, "true")
+			}
+			if p.maxAge != "" {
+				header.Set(echo.HeaderAccessControlMaxAge, p.maxAge)
 			}
 			return c.NoContent(http.StatusNoContent)
 		})
 	}
 }
+
+func newCORSPolicy(config *CORSConfig) *corsPolicy {
+	allowOrigins := config.AllowOrigins
+	if len(allowOrigins) == 0 && config.AllowOriginFunc == nil {
+		allowOrigins = DefaultCORSConfig.AllowOrigins
+	}
+	allowMethods := config.AllowMethods
+	if len(allowMethods) == 0 {
+		allowMethods = DefaultCORSConfig.AllowMethods
+	}
+	p := &corsPolicy{
+		allowOrigins:     map[string]struct{}{},
+		allowOriginFunc:  config.AllowOriginFunc,
+		allowMethods:     strings.Join(allowMethods, ","),
+		allowHeaders:     strings.Join(config.AllowHeaders, ","),
+		allowCredentials: config.AllowCredentials,
+		allowPrivateNet:  config.AllowPrivateNetwork,
+		exposeHeaders:    strings.Join(config.ExposeHeaders, ","),
+	}
+	for _, origin := range allowOrigins {
+		origin = strings.ToLower(strings.TrimSuffix(origin, "/"))
+		if origin == "*" {
+			p.allowAll = true
+		} else if pos := strings.Index(origin, "*"); pos >= 0 {
+			p.allowPatterns = append(p.allowPatterns, [2]string{origin[:pos], origin[pos+1:]})
+		} else {
+			p.allowOrigins[origin] = struct{}{}
+		}
+	}
+	if config.MaxAge > 0 {
+		p.maxAge = strconv.Itoa(config.MaxAge)
+	} else if config.MaxAge < 0 {
+		p.maxAge = "0"
+	}
+	return p
+}
+
+// allowOrigin returns the value of `Access-Control-Allow-Origin`, or empty if the origin is not allowed.
+func (p *corsPolicy) allowOrigin(c echo.Context, origin string) (string, error) {
+	if p.allowAll {
+		if p.allowCredentials {
+			return origin, nil
+		}
+		return "*", nil
+	}
+	lower := strings.ToLower(origin)
+	if _, ok := p.allowOrigins[lower]; ok {
+		return origin, nil
+	}
+	for _, pattern := range p.allowPatterns {
+		if len(lower) > len(pattern[0])+len(pattern[1]) &&
+			strings.HasPrefix(lower, pattern[0]) && strings.HasSuffix(lower, pattern[1]) &&
+			!strings.ContainsAny(lower[len(pattern[0]):len(lower)-len(pattern[1])], "/:") {
+			return origin, nil
+		}
+	}
+	if p.allowOriginFunc != nil {
+		ok, err := p.allowOriginFunc(c, origin)
+		if err != nil || !ok {
+			return "", err
+		}
+		return origin, nil
+	}
+	return "", nil
+}
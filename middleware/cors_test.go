@@ -0,0 +1,218 @@
+package middleware
+
+import (
+	"errors"
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/webx-top/echo"
+	test "github.com/webx-top/echo/testing"
+)
+
+func corsRequest(origin string, headers ...string) func(*http.Request) {
+	return func(req *http.Request) {
+		if origin != "" {
+			req.Header.Set(echo.HeaderOrigin, origin)
+		}
+		for i := 0; i+1 < len(headers); i += 2 {
+			req.Header.Set(headers[i], headers[i+1])
+		}
+	}
+}
+
+func TestCORSAllowOrigin(t *testing.T) {
+	errLookup := errors.New(`tenant lookup failed`)
+	e := echo.New()
+	e.Use(CORSWithConfig(CORSConfig{
+		AllowOrigins: []string{`https://*.example.com`, `https://exact.org/`},
+		AllowOriginFunc: func(c echo.Context, origin string) (bool, error) {
+			switch origin {
+			case `https://tenant.org`:
+				return true, nil
+			case `https://error.org`:
+				return false, errLookup
+			}
+			return false, nil
+		},
+	}))
+	e.Get(`/`, func(c echo.Context) error {
+		return c.String(`ok`)
+	})
+	e.RebuildRouter()
+
+	for _, c := range []struct {
+		origin string
+		allow  string
+		status int
+	}{
+		{`https://a.example.com`, `https://a.example.com`, http.StatusOK},
+		{`https://a.b.example.com`, `https://a.b.example.com`, http.StatusOK},
+		{`HTTPS://A.Example.com`, `HTTPS://A.Example.com`, http.StatusOK},
+		{`https://example.com`, ``, http.StatusOK},
+		{`https://example.com.evil`, ``, http.StatusOK},
+		{`https://evilexample.com`, ``, http.StatusOK},
+		{`https://a.example.com:8443`, ``, http.StatusOK},
+		{`http://a.example.com`, ``, http.StatusOK},
+		{`https://exact.org`, `https://exact.org`, http.StatusOK},
+		{`https://tenant.org`, `https://tenant.org`, http.StatusOK},
+		{`https://other.org`, ``, http.StatusOK},
+		{`https://error.org`, ``, http.StatusInternalServerError},
+		{``, ``, http.StatusOK},
+	} {
+		rec := test.Request(echo.GET, `/`, e, corsRequest(c.origin))
+		assert.Equal(t, c.status, rec.Code, c.origin)
+		assert.Equal(t, c.allow, rec.Header().Get(echo.HeaderAccessControlAllowOrigin), c.origin)
+		// 响应始终随 Origin 变化
+		assert.Equal(t, echo.HeaderOrigin, rec.Header().Get(echo.HeaderVary), c.origin)
+	}
+}
+
+func TestCORSCredentials(t *testing.T) {
+	for _, c := range []struct {
+		credentials bool
+		allow       string
+	}{
+		{false, `*`},
+		// 允许凭证时不能使用 *，需要回显 Origin
+		{true, `https://a.org`},
+	} {
+		e := echo.New()
+		e.Use(CORSWithConfig(CORSConfig{AllowCredentials: c.credentials}))
+		e.Get(`/`, func(c echo.Context) error {
+			return c.String(`ok`)
+		})
+		e.RebuildRouter()
+
+		rec := test.Request(echo.GET, `/`, e, corsRequest(`https://a.org`))
+		assert.Equal(t, c.allow, rec.Header().Get(echo.HeaderAccessControlAllowOrigin))
+		if c.credentials {
+			assert.Equal(t, `true`, rec.Header().Get(echo.HeaderAccessControlAllowCredentials))
+		} else {
+			assert.Empty(t, rec.Header().Get(echo.HeaderAccessControlAllowCredentials))
+		}
+	}
+}
+
+func TestCORSPreflight(t *testing.T) {
+	for _, c := range []struct {
+		config  CORSConfig
+		headers []string
+		maxAge  string
+		private string
+	}{
+		{CORSConfig{}, nil, ``, ``},
+		{CORSConfig{MaxAge: 600}, nil, `600`, ``},
+		{CORSConfig{MaxAge: -1}, nil, `0`, ``},
+		{CORSConfig{}, []string{echo.HeaderAccessControlRequestPrivateNetwork, `true`}, ``, ``},
+		{CORSConfig{AllowPrivateNetwork: true}, nil, ``, ``},
+		{CORSConfig{AllowPrivateNetwork: true}, []string{echo.HeaderAccessControlRequestPrivateNetwork, `true`}, ``, `true`},
+	} {
+		e := echo.New()
+		e.Use(CORSWithConfig(c.config))
+		// 没有注册 OPTIONS 路由
+		e.Post(`/`, func(c echo.Context) error {
+			return c.String(`ok`)
+		})
+		e.RebuildRouter()
+
+		headers := append([]string{
+			echo.HeaderAccessControlRequestMethod, echo.POST,
+			echo.HeaderAccessControlRequestHeaders, `X-Token`,
+		}, c.headers...)
+		rec := test.Request(echo.OPTIONS, `/`, e, corsRequest(`https://a.org`, headers...))
+		assert.Equal(t, http.StatusNoContent, rec.Code)
+		assert.Equal(t, `*`, rec.Header().Get(echo.HeaderAccessControlAllowOrigin))
+		assert.Equal(t, `GET,HEAD,PUT,POST,DELETE`, rec.Header().Get(echo.HeaderAccessControlAllowMethods))
+		assert.Equal(t, `X-Token`, rec.Header().Get(echo.HeaderAccessControlAllowHeaders))
+		assert.Equal(t, []string{
+			echo.HeaderOrigin,
+			echo.HeaderAccessControlRequestMethod,
+			echo.HeaderAccessControlRequestHeaders,
+		}, rec.Header()[echo.HeaderVary])
+		assert.Equal(t, c.maxAge, rec.Header().Get(echo.HeaderAccessControlMaxAge))
+		assert.Equal(t, c.private, rec.Header().Get(echo.HeaderAccessControlAllowPrivateNetwork))
+	}
+}
+
+func TestCORSRoutePolicy(t *testing.T) {
+	e := echo.New()
+	e.Use(CORSWithConfig(CORSConfig{
+		AllowOrigins: []string{`https://default.org`},
+		Groups: map[string]*CORSConfig{
+			`/admin`: {AllowOrigins: []string{`https://admin.org`}},
+		},
+	}))
+	ok := func(c echo.Context) error {
+		return c.String(c.Route().Path)
+	}
+	e.Get(`/`, ok)
+	e.Get(`/route`, e.MetaHandler(echo.H{
+		`cors`: &CORSConfig{AllowOrigins: []string{`https://route.org`}},
+	}, ok))
+	e.Put(`/route`, ok)
+	e.Get(`/disabled`, e.MetaHandler(echo.H{`cors`: false}, ok))
+	g := e.Group(`/admin`)
+	g.Get(`/users`, ok)
+	g.Get(`/settings`, e.MetaHandler(echo.H{
+		`cors`: &CORSConfig{AllowOrigins: []string{`https://route.org`}},
+	}, ok))
+	e.Host(`api.example.com`).Get(`/host`, e.MetaHandler(echo.H{
+		`cors`: &CORSConfig{AllowOrigins: []string{`https://host.org`}},
+	}, ok))
+	e.RebuildRouter()
+
+	for _, c := range []struct {
+		method string
+		path   string
+		host   string
+		origin string
+		allow  bool
+	}{
+		{echo.GET, `/`, ``, `https://default.org`, true},
+		{echo.GET, `/`, ``, `https://route.org`, false},
+		{echo.GET, `/route`, ``, `https://route.org`, true},
+		{echo.GET, `/route`, ``, `https://default.org`, false},
+		// 同一路径的其它方法使用默认策略
+		{echo.PUT, `/route`, ``, `https://default.org`, true},
+		{echo.PUT, `/route`, ``, `https://route.org`, false},
+		{echo.GET, `/disabled`, ``, `https://default.org`, false},
+		{echo.GET, `/admin/users`, ``, `https://admin.org`, true},
+		{echo.GET, `/admin/users`, ``, `https://default.org`, false},
+		// 路由策略优先于分组策略
+		{echo.GET, `/admin/settings`, ``, `https://route.org`, true},
+		{echo.GET, `/admin/settings`, ``, `https://admin.org`, false},
+		// 使用 Host 对应的路由器
+		{echo.GET, `/host`, `api.example.com`, `https://host.org`, true},
+		{echo.GET, `/host`, `api.example.com`, `https://default.org`, false},
+		{echo.GET, `/missing`, ``, `https://default.org`, true},
+	} {
+		name := c.method + ` ` + c.host + c.path + ` ` + c.origin
+		expected := ``
+		if c.allow {
+			expected = c.origin
+		}
+		setHost := func(req *http.Request) {
+			corsRequest(c.origin)(req)
+			req.Host = c.host
+		}
+		rec := test.Request(c.method, c.path, e, setHost)
+		assert.Equal(t, expected, rec.Header().Get(echo.HeaderAccessControlAllowOrigin), name)
+		// 查找策略不影响后续处理器中的路由
+		if rec.Code == http.StatusOK {
+			assert.Equal(t, c.path, rec.Body.String(), name)
+		}
+
+		// 预检请求按 Access-Control-Request-Method 对应的路由选择策略
+		rec = test.Request(echo.OPTIONS, c.path, e, func(req *http.Request) {
+			setHost(req)
+			req.Header.Set(echo.HeaderAccessControlRequestMethod, c.method)
+		})
+		if c.path == `/disabled` {
+			assert.NotEqual(t, http.StatusNoContent, rec.Code, name)
+			continue
+		}
+		assert.Equal(t, http.StatusNoContent, rec.Code, name)
+		assert.Equal(t, expected, rec.Header().Get(echo.HeaderAccessControlAllowOrigin), name)
+	}
+}
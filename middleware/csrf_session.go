@@ -0,0 +1,314 @@
+package middleware
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/base64"
+	"html/template"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"github.com/webx-top/echo"
+)
+
+const csrfSecretLength = 32
+
+type (
+	// SessionCSRFConfig defines the config for session-backed CSRF middleware.
+	// 密钥保存在会话(Sessioner)中，每次请求生成经过掩码处理的令牌(防止 BREACH 攻击)
+	SessionCSRFConfig struct {
+		// Skipper defines a function to skip middleware.
+		Skipper echo.Skipper `json:"-"`
+
+		// SessionKey 会话中保存密钥的键名
+		// Optional. Default value "_csrf_secret".
+		SessionKey string `json:"session_key"`
+
+		// HeaderName 提交令牌的请求头
+		// Optional. Default value "X-CSRF-Token".
+		HeaderName string `json:"header_name"`
+
+		// FormField 提交令牌的表单字段
+		// Optional. Default value "_csrf".
+		FormField string `json:"form_field"`
+
+		// Context key to store generated CSRF token into context.
+		// Optional. Default value "csrf".
+		ContextKey string `json:"context_key"`
+
+		// PerForm 为 true 时可以为每个表单的 action 生成单独的令牌，只能提交到该路径。
+		// 为某个路径生成过限定路径的令牌之后，提交到该路径时必须使用限定路径的令牌，
+		// 其它路径仍然使用不限定路径的令牌
+		PerForm bool `json:"per_form"`
+
+		// MaxScopes 会话中最多记录的限定路径数量，超过时丢弃最久未生成令牌的路径，
+		// 被丢弃的路径恢复为接受不限定路径的令牌。每条记录为固定长度的摘要，避免会话(cookie)无限增长
+		// Optional. Default value 20.
+		MaxScopes int `json:"max_scopes"`
+
+		// TrustedOrigins 对于不安全的请求方法，Origin(或 Referer)必须与本站相同或在此列表中，
+		// 格式为 "https://example.com"
+		TrustedOrigins []string `json:"trusted_origins"`
+
+		// FuncPrefix 模板函数名前缀，会注册 <FuncPrefix>Token 和 <FuncPrefix>Field。
+		// Optional. Default value "CSRF".
+		FuncPrefix string `json:"func_prefix"`
+	}
+)
+
+var (
+	// DefaultSessionCSRFConfig is the default session-backed CSRF middleware config.
+	DefaultSessionCSRFConfig = SessionCSRFConfig{
+		Skipper:    echo.DefaultSkipper,
+		SessionKey: "_csrf_secret",
+		HeaderName: echo.HeaderXCSRFToken,
+		FormField:  "_csrf",
+		ContextKey: "csrf",
+		MaxScopes:  20,
+		FuncPrefix: "CSRF",
+	}
+)
+
+// SessionCSRF returns a session-backed synchronizer-token CSRF middleware.
+// 必须在 session 中间件之后使用
+func SessionCSRF() echo.MiddlewareFuncd {
+	return SessionCSRFWithConfig(DefaultSessionCSRFConfig)
+}
+
+// SessionCSRFWithConfig returns a session-backed CSRF middleware with config.
+// See `SessionCSRF()`.
+func SessionCSRFWithConfig(config SessionCSRFConfig) echo.MiddlewareFuncd {
+	if config.Skipper == nil {
+		config.Skipper = DefaultSessionCSRFConfig.Skipper
+	}
+	if config.SessionKey == "" {
+		config.SessionKey = DefaultSessionCSRFConfig.SessionKey
+	}
+	if config.HeaderName == "" {
+		config.HeaderName = DefaultSessionCSRFConfig.HeaderName
+	}
+	if config.FormField == "" {
+		config.FormField = DefaultSessionCSRFConfig.FormField
+	}
+	if config.ContextKey == "" {
+		config.ContextKey = DefaultSessionCSRFConfig.ContextKey
+	}
+	if config.FuncPrefix == "" {
+		config.FuncPrefix = DefaultSessionCSRFConfig.FuncPrefix
+	}
+	if config.MaxScopes <= 0 {
+		config.MaxScopes = DefaultSessionCSRFConfig.MaxScopes
+	}
+	trusted := make(map[string]struct{}, len(config.TrustedOrigins))
+	for _, origin := range config.TrustedOrigins {
+		trusted[strings.ToLower(strings.TrimSuffix(origin, "/"))] = struct{}{}
+	}
+
+	return func(next echo.Handler) echo.HandlerFunc {
+		return func(c echo.Context) error {
+			if config.Skipper(c) {
+				return next.Handle(c)
+			}
+			secret, err := csrfSecret(c, config.SessionKey)
+			if err != nil {
+				return err
+			}
+			scopesKey := config.SessionKey + `_scopes`
+			requestPath := c.Request().URL().Path()
+
+			switch c.Request().Method() {
+			case echo.GET, echo.HEAD, echo.OPTIONS, echo.TRACE:
+			default:
+				// Validate only for requests which are not defined as 'safe' by RFC7231
+				if !csrfOriginAllowed(c, trusted) {
+					return echo.NewHTTPError(http.StatusForbidden, "csrf origin is not trusted")
+				}
+				clientToken := c.Header(config.HeaderName)
+				if len(clientToken) == 0 {
+					clientToken = c.Form(config.FormField)
+				}
+				var scoped bool
+				if config.PerForm {
+					scoped = csrfScopeIssued(c, scopesKey, requestPath)
+				}
+				if !validateMaskedCSRFToken(secret, clientToken, requestPath, config.PerForm, scoped) {
+					return echo.NewHTTPError(http.StatusForbidden, "csrf token is invalid")
+				}
+			}
+
+			token := func(action ...string) (string, error) {
+				var scope string
+				if config.PerForm && len(action) > 0 {
+					scope = csrfScope(requestPath, action[0])
+					csrfIssueScope(c, scopesKey, scope, config.MaxScopes)
+				}
+				return maskCSRFToken(scopedCSRFSecret(secret, scope))
+			}
+			contextToken, err := token()
+			if err != nil {
+				return err
+			}
+			c.Set(config.ContextKey, contextToken)
+			c.SetFunc(config.FuncPrefix+`Token`, token)
+			c.SetFunc(config.FuncPrefix+`Field`, func(action ...string) (template.HTML, error) {
+				value, err := token(action...)
+				if err != nil {
+					return ``, err
+				}
+				return template.HTML(`<input type="hidden" name="` + template.HTMLEscapeString(config.FormField) + `" value="` + value + `" />`), nil
+			})
+
+			// Protect clients from caching the response
+			c.Response().Header().Add(echo.HeaderVary, echo.HeaderCookie)
+
+			return next.Handle(c)
+		}
+	}
+}
+
+// csrfSecret 返回会话中的密钥，不存在时生成
+func csrfSecret(c echo.Context, sessionKey string) ([]byte, error) {
+	if v, ok := c.Session().Get(sessionKey).(string); ok {
+		if secret, err := base64.RawURLEncoding.DecodeString(v); err == nil && len(secret) == csrfSecretLength {
+			return secret, nil
+		}
+	}
+	secret := make([]byte, csrfSecretLength)
+	if _, err := io.ReadFull(rand.Reader, secret); err != nil {
+		return nil, err
+	}
+	c.Session().Set(sessionKey, base64.RawURLEncoding.EncodeToString(secret))
+	return secret, nil
+}
+
+// csrfScope 返回表单 action 的路径，相对路径(包括空的 action)按当前请求的路径解析
+func csrfScope(requestPath string, action string) string {
+	base := &url.URL{Path: requestPath}
+	if u, err := url.Parse(action); err == nil {
+		action = base.ResolveReference(u).Path
+	}
+	if len(action) == 0 {
+		action = "/"
+	}
+	return action
+}
+
+// csrfScopeID 返回会话中记录路径所用的摘要
+func csrfScopeID(scope string) string {
+	sum := sha256.Sum256([]byte(scope))
+	return base64.RawURLEncoding.EncodeToString(sum[:9])
+}
+
+// csrfScopes 返回会话中记录的路径摘要，按生成令牌的时间从早到晚排列
+func csrfScopes(c echo.Context, scopesKey string) []string {
+	scopes, _ := c.Session().Get(scopesKey).(string)
+	if len(scopes) == 0 {
+		return nil
+	}
+	return strings.Split(scopes, "\n")
+}
+
+// csrfScopeIssued 是否为该路径生成过限定路径的令牌
+func csrfScopeIssued(c echo.Context, scopesKey string, scope string) bool {
+	id := csrfScopeID(scope)
+	for _, s := range csrfScopes(c, scopesKey) {
+		if s == id {
+			return true
+		}
+	}
+	return false
+}
+
+// csrfIssueScope 在会话中记录生成过限定路径的令牌的路径，最多保留 max 条最近的记录
+func csrfIssueScope(c echo.Context, scopesKey string, scope string, max int) {
+	id := csrfScopeID(scope)
+	scopes := csrfScopes(c, scopesKey)
+	if len(scopes) > 0 && scopes[len(scopes)-1] == id {
+		return
+	}
+	updated := make([]string, 0, len(scopes)+1)
+	for _, s := range scopes {
+		if s != id {
+			updated = append(updated, s)
+		}
+	}
+	updated = append(updated, id)
+	if len(updated) > max {
+		updated = updated[len(updated)-max:]
+	}
+	c.Session().Set(scopesKey, strings.Join(updated, "\n"))
+}
+
+func scopedCSRFSecret(secret []byte, scope string) []byte {
+	if len(scope) == 0 {
+		return secret
+	}
+	h := hmac.New(sha256.New, secret)
+	h.Write([]byte(scope))
+	return h.Sum(nil)
+}
+
+// maskCSRFToken 使用随机数对令牌进行异或，使每次响应中的令牌都不相同
+func maskCSRFToken(token []byte) (string, error) {
+	pad := make([]byte, len(token))
+	if _, err := io.ReadFull(rand.Reader, pad); err != nil {
+		return ``, err
+	}
+	masked := make([]byte, len(token)*2)
+	copy(masked, pad)
+	for i, b := range token {
+		masked[len(token)+i] = pad[i] ^ b
+	}
+	return base64.RawURLEncoding.EncodeToString(masked), nil
+}
+
+func unmaskCSRFToken(masked string) []byte {
+	b, err := base64.RawURLEncoding.DecodeString(masked)
+	if err != nil || len(b) != csrfSecretLength*2 {
+		return nil
+	}
+	token := make([]byte, csrfSecretLength)
+	for i := range token {
+		token[i] = b[i] ^ b[csrfSecretLength+i]
+	}
+	return token
+}
+
+// validateMaskedCSRFToken 验证令牌。scoped 为 true 时只接受限定该路径的令牌
+func validateMaskedCSRFToken(secret []byte, clientToken string, path string, perForm bool, scoped bool) bool {
+	token := unmaskCSRFToken(clientToken)
+	if token == nil {
+		return false
+	}
+	if !scoped && subtle.ConstantTimeCompare(token, secret) == 1 {
+		return true
+	}
+	return perForm && subtle.ConstantTimeCompare(token, scopedCSRFSecret(secret, path)) == 1
+}
+
+// csrfOriginAllowed 检查 Origin，没有 Origin 时检查 Referer。
+// 两者都没有时，只允许非 HTTPS 的请求
+func csrfOriginAllowed(c echo.Context, trusted map[string]struct{}) bool {
+	origin := c.Header(echo.HeaderOrigin)
+	if len(origin) == 0 {
+		referer := c.Referer()
+		if len(referer) == 0 {
+			return c.Scheme() != `https`
+		}
+		u, err := url.Parse(referer)
+		if err != nil || len(u.Host) == 0 {
+			return false
+		}
+		origin = u.Scheme + `://` + u.Host
+	}
+	origin = strings.ToLower(origin)
+	if origin == strings.ToLower(c.Scheme()+`://`+c.Request().Host()) {
+		return true
+	}
+	_, ok := trusted[origin]
+	return ok
+}
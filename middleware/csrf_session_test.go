@@ -0,0 +1,205 @@
+package middleware
+
+import (
+	"crypto/tls"
+	"html/template"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"regexp"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/webx-top/echo"
+	"github.com/webx-top/echo/middleware/session"
+	test "github.com/webx-top/echo/testing"
+)
+
+type csrfClient struct {
+	e      *echo.Echo
+	cookie []string
+}
+
+func (cl *csrfClient) do(method string, path string, rewrite func(*http.Request)) *httptest.ResponseRecorder {
+	rec := test.Request(method, path, cl.e, func(req *http.Request) {
+		req.Host = `example.com`
+		for _, h := range cl.cookie {
+			req.Header.Add(echo.HeaderCookie, strings.SplitN(h, `;`, 2)[0])
+		}
+		if rewrite != nil {
+			rewrite(req)
+		}
+	})
+	if cookie := rec.Header()[echo.HeaderSetCookie]; len(cookie) > 0 {
+		cl.cookie = cookie
+	}
+	return rec
+}
+
+func (cl *csrfClient) get(path string) string {
+	return cl.do(echo.GET, path, nil).Body.String()
+}
+
+// post 使用令牌提交，headers 为成对的请求头名称和值
+func (cl *csrfClient) post(path string, token string, headers ...string) int {
+	return cl.do(echo.POST, path, func(req *http.Request) {
+		req.Header.Set(echo.HeaderXCSRFToken, token)
+		for i := 0; i+1 < len(headers); i += 2 {
+			if headers[i] == `TLS` {
+				req.TLS = &tls.ConnectionState{}
+				continue
+			}
+			req.Header.Set(headers[i], headers[i+1])
+		}
+	}).Code
+}
+
+func newCSRFClient(config SessionCSRFConfig) *csrfClient {
+	e := echo.New()
+	e.Use(session.Middleware(nil))
+	e.Use(SessionCSRFWithConfig(config))
+	e.Get(`/token`, func(c echo.Context) error {
+		return c.String(c.Get(`csrf`).(string))
+	})
+	e.Get(`/form/edit`, func(c echo.Context) error {
+		token := c.GetFunc(`CSRFToken`).(func(...string) (string, error))
+		v, err := token(c.Query(`action`))
+		if err != nil {
+			return err
+		}
+		return c.String(v)
+	})
+	e.Get(`/field`, func(c echo.Context) error {
+		field := c.GetFunc(`CSRFField`).(func(...string) (template.HTML, error))
+		v, err := field()
+		if err != nil {
+			return err
+		}
+		return c.String(string(v))
+	})
+	ok := func(c echo.Context) error {
+		return c.String(`ok`)
+	}
+	e.Post(`/save`, ok)
+	e.Post(`/form/save`, ok)
+	e.Post(`/form/other`, ok)
+	e.RebuildRouter()
+	return &csrfClient{e: e}
+}
+
+func TestSessionCSRFToken(t *testing.T) {
+	cl := newCSRFClient(SessionCSRFConfig{})
+	first := cl.get(`/token`)
+	second := cl.get(`/token`)
+	assert.NotEmpty(t, first)
+	// 每次响应中的令牌都不相同，但都有效
+	assert.NotEqual(t, first, second)
+	assert.Equal(t, http.StatusOK, cl.post(`/save`, first))
+	assert.Equal(t, http.StatusOK, cl.post(`/save`, second))
+
+	// 篡改、长度不对和空的令牌
+	tampered := []byte(first)
+	if tampered[10] == 'A' {
+		tampered[10] = 'B'
+	} else {
+		tampered[10] = 'A'
+	}
+	for _, token := range []string{string(tampered), first[:len(first)-4], ``, `not base64!`} {
+		assert.Equal(t, http.StatusForbidden, cl.post(`/save`, token), token)
+	}
+
+	// 其它会话的令牌
+	other := &csrfClient{e: cl.e}
+	assert.NotEmpty(t, other.get(`/token`))
+	assert.Equal(t, http.StatusForbidden, other.post(`/save`, first))
+}
+
+func TestSessionCSRFOrigin(t *testing.T) {
+	cl := newCSRFClient(SessionCSRFConfig{TrustedOrigins: []string{`https://trusted.example.org/`}})
+	token := cl.get(`/token`)
+	for _, c := range []struct {
+		headers []string
+		status  int
+	}{
+		{[]string{echo.HeaderOrigin, `http://example.com`}, http.StatusOK},
+		{[]string{echo.HeaderOrigin, `HTTP://Example.com`}, http.StatusOK},
+		{[]string{echo.HeaderOrigin, `https://trusted.example.org`}, http.StatusOK},
+		{[]string{echo.HeaderOrigin, `https://evil.example.net`}, http.StatusForbidden},
+		{[]string{echo.HeaderOrigin, `null`}, http.StatusForbidden},
+		{[]string{`Referer`, `http://example.com/form`}, http.StatusOK},
+		{[]string{`Referer`, `https://trusted.example.org/page?q=1`}, http.StatusOK},
+		{[]string{`Referer`, `https://evil.example.net/form`}, http.StatusForbidden},
+		{[]string{`Referer`, `/relative`}, http.StatusForbidden},
+		// 没有 Origin 和 Referer 时只允许非 HTTPS 的请求
+		{nil, http.StatusOK},
+		{[]string{`TLS`, ``}, http.StatusForbidden},
+		{[]string{`TLS`, ``, echo.HeaderOrigin, `https://example.com`}, http.StatusOK},
+		{[]string{`TLS`, ``, echo.HeaderOrigin, `http://example.com`}, http.StatusForbidden},
+	} {
+		assert.Equal(t, c.status, cl.post(`/save`, token, c.headers...), strings.Join(c.headers, `: `))
+	}
+}
+
+func TestSessionCSRFPerForm(t *testing.T) {
+	cl := newCSRFClient(SessionCSRFConfig{PerForm: true})
+	unscoped := cl.get(`/token`)
+	assert.Equal(t, http.StatusOK, cl.post(`/form/save`, unscoped))
+
+	// 相对路径按当前页面(/form/edit)解析
+	for _, action := range []string{`save`, `./save`, `/form/save`, `http://example.com/form/save?id=1`, `../form/save`} {
+		scoped := cl.get(`/form/edit?action=` + url.QueryEscape(action))
+		assert.Equal(t, http.StatusOK, cl.post(`/form/save`, scoped), action)
+		assert.Equal(t, http.StatusForbidden, cl.post(`/form/other`, scoped), action)
+		assert.Equal(t, http.StatusForbidden, cl.post(`/save`, scoped), action)
+	}
+	// 生成过限定路径的令牌之后，该路径不再接受不限定路径的令牌
+	assert.Equal(t, http.StatusForbidden, cl.post(`/form/save`, unscoped))
+	assert.Equal(t, http.StatusOK, cl.post(`/form/other`, unscoped))
+
+	// 空的 action 提交到当前页面
+	scoped := cl.get(`/form/edit?action=`)
+	assert.Equal(t, http.StatusForbidden, cl.post(`/form/save`, scoped))
+
+	// 未启用 PerForm 时忽略 action
+	cl = newCSRFClient(SessionCSRFConfig{})
+	token := cl.get(`/form/edit?action=save`)
+	assert.Equal(t, http.StatusOK, cl.post(`/form/other`, token))
+}
+
+func TestSessionCSRFField(t *testing.T) {
+	cl := newCSRFClient(SessionCSRFConfig{FormField: `_token"`})
+	field := cl.get(`/field`)
+	m := regexp.MustCompile(`^<input type="hidden" name="_token&#34;" value="([A-Za-z0-9_-]+)" />$`).FindStringSubmatch(field)
+	if assert.Len(t, m, 2, field) {
+		assert.Equal(t, http.StatusOK, cl.post(`/save`, m[1]))
+	}
+}
+
+func TestSessionCSRFMaxScopes(t *testing.T) {
+	cl := newCSRFClient(SessionCSRFConfig{PerForm: true, MaxScopes: 2})
+	unscoped := cl.get(`/token`)
+	cl.get(`/form/edit?action=save`)
+	cl.get(`/form/edit?action=other`)
+	assert.Equal(t, http.StatusForbidden, cl.post(`/form/save`, unscoped))
+	assert.Equal(t, http.StatusForbidden, cl.post(`/form/other`, unscoped))
+
+	// 超过 MaxScopes 时丢弃最久未生成令牌的路径
+	cl.get(`/form/edit?action=save`)
+	cl.get(`/form/edit?action=/save`)
+	assert.Equal(t, http.StatusOK, cl.post(`/form/other`, unscoped))
+	assert.Equal(t, http.StatusForbidden, cl.post(`/form/save`, unscoped))
+	assert.Equal(t, http.StatusForbidden, cl.post(`/save`, unscoped))
+
+	// 会话中的记录不会随 action 的数量增长
+	cl = newCSRFClient(SessionCSRFConfig{PerForm: true})
+	cl.get(`/token`)
+	var size int
+	for i := 0; i < 100; i++ {
+		cl.get(`/form/edit?action=` + url.QueryEscape(`/items/`+strings.Repeat(`x`, i)+`/delete`))
+		if i == 30 {
+			size = len(strings.Join(cl.cookie, ``))
+		}
+	}
+	assert.Equal(t, size, len(strings.Join(cl.cookie, ``)))
+}
@@ -0,0 +1,140 @@
+package middleware
+
+import (
+	"compress/flate"
+	"compress/gzip"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"strings"
+
+	"github.com/andybalholm/brotli"
+	"github.com/klauspost/compress/zstd"
+	"github.com/webx-top/echo"
+	"github.com/webx-top/echo/middleware/bytes"
+)
+
+type (
+	// DecompressConfig defines the config for Decompress middleware.
+	DecompressConfig struct {
+		// Skipper defines a function to skip middleware.
+		Skipper echo.Skipper `json:"-"`
+
+		// Maximum allowed size for a decompressed request body, it can be specified
+		// as `4x` or `4xB`, where x is one of the multiple from K, M, G, T or P.
+		// The size of the compressed body can be limited by `BodyLimit`.
+		// Optional. Default value "10M".
+		Limit string `json:"limit"`
+		limit int64
+	}
+
+	// DecompressorFunc 创建解压缩器
+	DecompressorFunc func(r io.Reader) (io.ReadCloser, error)
+)
+
+var (
+	// DefaultDecompressConfig is the default Decompress middleware config.
+	DefaultDecompressConfig = &DecompressConfig{
+		Skipper: echo.DefaultSkipper,
+		Limit:   `10M`,
+	}
+
+	// Decompressors 已注册的解压缩器
+	Decompressors = map[string]DecompressorFunc{
+		`gzip`: func(r io.Reader) (io.ReadCloser, error) {
+			return gzip.NewReader(r)
+		},
+		`x-gzip`: func(r io.Reader) (io.ReadCloser, error) {
+			return gzip.NewReader(r)
+		},
+		`deflate`: func(r io.Reader) (io.ReadCloser, error) {
+			return flate.NewReader(r), nil
+		},
+		`br`: func(r io.Reader) (io.ReadCloser, error) {
+			return ioutil.NopCloser(brotli.NewReader(r)), nil
+		},
+		`zstd`: func(r io.Reader) (io.ReadCloser, error) {
+			d, err := zstd.NewReader(r, zstd.WithDecoderConcurrency(1))
+			if err != nil {
+				return nil, err
+			}
+			return d.IOReadCloser(), nil
+		},
+	}
+)
+
+// RegisterDecompressor 注册解压缩器
+func RegisterDecompressor(encoding string, fn DecompressorFunc) {
+	Decompressors[encoding] = fn
+}
+
+// Decompress returns a middleware which decompresses request body
+// according to the `Content-Encoding` header.
+//
+// Unsupported encodings are rejected with "415 - Unsupported Media Type",
+// decompressed bodies larger than the limit are rejected with
+// "413 - Request Entity Too Large".
+func Decompress(config ...*DecompressConfig) echo.MiddlewareFunc {
+	if len(config) < 1 || config[0] == nil {
+		return DecompressWithConfig(DefaultDecompressConfig)
+	}
+	return DecompressWithConfig(config[0])
+}
+
+// DecompressWithConfig returns a Decompress middleware with config.
+// See: `Decompress()`.
+func DecompressWithConfig(config *DecompressConfig) echo.MiddlewareFunc {
+	// Defaults
+	if config.Skipper == nil {
+		config.Skipper = DefaultDecompressConfig.Skipper
+	}
+	if len(config.Limit) == 0 {
+		config.Limit = DefaultDecompressConfig.Limit
+	}
+	limit, err := bytes.Parse(config.Limit)
+	if err != nil {
+		panic(fmt.Errorf("invalid decompress-limit=%s", config.Limit))
+	}
+	config.limit = limit
+
+	return func(next echo.Handler) echo.Handler {
+		return echo.HandlerFunc(func(c echo.Context) error {
+			if config.Skipper(c) {
+				return next.Handle(c)
+			}
+			req := c.Request()
+			header := req.Header().Get(echo.HeaderContentEncoding)
+			if len(header) == 0 {
+				return next.Handle(c)
+			}
+			encodings := strings.Split(header, `,`)
+			var body io.Reader = req.Body()
+			// 按与编码相反的顺序解码
+			for i := len(encodings) - 1; i >= 0; i-- {
+				encoding := strings.ToLower(strings.TrimSpace(encodings[i]))
+				if len(encoding) == 0 || encoding == `identity` {
+					continue
+				}
+				fn, ok := Decompressors[encoding]
+				if !ok {
+					return echo.ErrUnsupportedMediaType
+				}
+				r, err := fn(body)
+				if err != nil {
+					return echo.NewHTTPError(http.StatusBadRequest, err.Error())
+				}
+				defer r.Close()
+				body = r
+			}
+			req.Header().Del(echo.HeaderContentEncoding)
+			req.Header().Del(echo.HeaderContentLength)
+			req.SetBody(&limitedReader{
+				BodyLimitConfig: BodyLimitConfig{limit: config.limit},
+				reader:          body,
+				context:         c,
+			})
+			return next.Handle(c)
+		})
+	}
+}
@@ -0,0 +1,99 @@
+package middleware
+
+import (
+	"bytes"
+	"compress/flate"
+	"compress/gzip"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/andybalholm/brotli"
+	"github.com/klauspost/compress/zstd"
+	"github.com/stretchr/testify/assert"
+	"github.com/webx-top/echo"
+	test "github.com/webx-top/echo/testing"
+)
+
+func compressBody(t *testing.T, encoding string, data string) []byte {
+	var (
+		buf bytes.Buffer
+		w   io.WriteCloser
+		err error
+	)
+	switch encoding {
+	case `gzip`:
+		w = gzip.NewWriter(&buf)
+	case `deflate`:
+		w, err = flate.NewWriter(&buf, flate.DefaultCompression)
+	case `br`:
+		w = brotli.NewWriter(&buf)
+	case `zstd`:
+		w, err = zstd.NewWriter(&buf)
+	}
+	assert.NoError(t, err)
+	_, err = w.Write([]byte(data))
+	assert.NoError(t, err)
+	assert.NoError(t, w.Close())
+	return buf.Bytes()
+}
+
+func encodedBody(encoding string, body []byte) func(*http.Request) {
+	return func(req *http.Request) {
+		req.Header.Set(echo.HeaderContentEncoding, encoding)
+		req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+		req.Body = ioutil.NopCloser(bytes.NewReader(body))
+		req.ContentLength = int64(len(body))
+	}
+}
+
+func TestDecompress(t *testing.T) {
+	e := echo.New()
+	e.Use(Decompress(&DecompressConfig{Limit: `1K`}))
+	e.Post(`/`, func(c echo.Context) error {
+		m := echo.H{}
+		if err := c.MustBind(&m); err != nil {
+			return err
+		}
+		return c.JSON(m)
+	})
+	e.Post(`/raw`, func(c echo.Context) error {
+		b, err := ioutil.ReadAll(c.Request().Body())
+		if err != nil {
+			return err
+		}
+		return c.String(strings.Repeat(`.`, len(b)))
+	})
+	e.RebuildRouter()
+
+	for _, encoding := range []string{`gzip`, `deflate`, `br`, `zstd`} {
+		rec := test.Request(echo.POST, `/`, e, encodedBody(encoding, compressBody(t, encoding, `{"a":"b"}`)))
+		assert.Equal(t, http.StatusOK, rec.Code, encoding)
+		assert.Equal(t, `{"a":"b"}`, strings.TrimSpace(rec.Body.String()), encoding)
+	}
+
+	// 多重编码按相反的顺序解码
+	body := compressBody(t, `br`, string(compressBody(t, `gzip`, `{"a":"b"}`)))
+	rec := test.Request(echo.POST, `/`, e, encodedBody(`gzip, br`, body))
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.Equal(t, `{"a":"b"}`, strings.TrimSpace(rec.Body.String()))
+
+	rec = test.Request(echo.POST, `/`, e, encodedBody(`compress`, []byte(`x`)))
+	assert.Equal(t, http.StatusUnsupportedMediaType, rec.Code)
+
+	rec = test.Request(echo.POST, `/`, e, encodedBody(`gzip`, []byte(`not gzip`)))
+	assert.Equal(t, http.StatusBadRequest, rec.Code)
+
+	// 解压后超过 Limit (压缩炸弹)
+	for _, encoding := range []string{`gzip`, `zstd`} {
+		body := compressBody(t, encoding, strings.Repeat(`a`, 1<<18))
+		assert.True(t, len(body) < 1024, encoding)
+		rec = test.Request(echo.POST, `/raw`, e, encodedBody(encoding, body))
+		assert.Equal(t, http.StatusRequestEntityTooLarge, rec.Code, encoding)
+	}
+	rec = test.Request(echo.POST, `/raw`, e, encodedBody(`gzip`, compressBody(t, `gzip`, strings.Repeat(`a`, 1024))))
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.Equal(t, 1024, rec.Body.Len())
+}
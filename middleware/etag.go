@@ -0,0 +1,93 @@
+package middleware
+
+import (
+	"net/http"
+
+	"github.com/webx-top/echo"
+	"github.com/webx-top/echo/engine"
+)
+
+type (
+	// ETagConfig defines the config for ETag middleware.
+	ETagConfig struct {
+		// Skipper defines a function to skip middleware.
+		Skipper echo.Skipper `json:"-"`
+
+		// Weak generates weak ETags (W/"...").
+		// Optional. Default value false.
+		Weak bool `json:"weak"`
+
+		// Generator generates the ETag from the response body.
+		// Optional. Default value echo.GenerateETag.
+		Generator func(body []byte, weak bool) string `json:"-"`
+	}
+)
+
+var (
+	// DefaultETagConfig is the default ETag middleware config.
+	DefaultETagConfig = &ETagConfig{
+		Skipper:   echo.DefaultSkipper,
+		Generator: echo.GenerateETag,
+	}
+)
+
+// ETag returns a middleware which buffers the response of GET and HEAD requests,
+// sets the `ETag` header and answers `304 Not Modified` when it matches `If-None-Match`.
+// A handler may set its own `ETag` header, which is used instead of the generated one.
+// Responses which stop keeping the body (`Stream`, `ServeContent`, etc.) are passed through unchanged.
+func ETag(config ...*ETagConfig) echo.MiddlewareFunc {
+	if len(config) < 1 || config[0] == nil {
+		return ETagWithConfig(DefaultETagConfig)
+	}
+	return ETagWithConfig(config[0])
+}
+
+// ETagWithConfig returns an ETag middleware with config.
+// See: `ETag()`.
+func ETagWithConfig(config *ETagConfig) echo.MiddlewareFunc {
+	// Defaults
+	if config.Skipper == nil {
+		config.Skipper = DefaultETagConfig.Skipper
+	}
+	if config.Generator == nil {
+		config.Generator = DefaultETagConfig.Generator
+	}
+
+	return func(h echo.Handler) echo.Handler {
+		return echo.HandlerFunc(func(c echo.Context) error {
+			if config.Skipper(c) {
+				return h.Handle(c)
+			}
+			req := c.Request()
+			if req.Method() != echo.GET && req.Method() != echo.HEAD {
+				return h.Handle(c)
+			}
+			res := c.Response()
+			buf := engine.NewBufferedResponse(res)
+			c.SetResponse(buf)
+			err := h.Handle(c)
+			c.SetResponse(res)
+			if err != nil {
+				if buf.Committed() {
+					buf.Send()
+				}
+				return err
+			}
+			if buf.Passthrough() || !buf.Committed() || buf.Status() != http.StatusOK {
+				return buf.Send()
+			}
+			etag := res.Header().Get(echo.HeaderETag)
+			if len(etag) == 0 {
+				etag = config.Generator(buf.Body(), config.Weak)
+				res.Header().Set(echo.HeaderETag, etag)
+			}
+			if echo.ETagMatch(req.Header().Get(echo.HeaderIfNoneMatch), etag, true) {
+				res.Header().Del(echo.HeaderContentType)
+				res.Header().Del(echo.HeaderContentLength)
+				res.WriteHeader(http.StatusNotModified)
+				return nil
+			}
+			return buf.Send()
+		})
+	}
+}
@@ -0,0 +1,41 @@
+package middleware
+
+import (
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/webx-top/echo"
+	test "github.com/webx-top/echo/testing"
+)
+
+func TestETag(t *testing.T) {
+	e := echo.New()
+	e.Use(ETag())
+	e.Get(`/`, func(c echo.Context) error {
+		return c.String(`ok`)
+	})
+	e.Get(`/stream`, func(c echo.Context) error {
+		return c.ServeContent(strings.NewReader(`stream`), `a.txt`, time.Time{})
+	})
+	e.RebuildRouter()
+
+	rec := test.Request(echo.GET, `/`, e)
+	assert.Equal(t, http.StatusOK, rec.Code)
+	etag := rec.Header().Get(echo.HeaderETag)
+	assert.Equal(t, echo.GenerateETag([]byte(`ok`), false), etag)
+
+	rec = test.Request(echo.GET, `/`, e, func(req *http.Request) {
+		req.Header.Set(echo.HeaderIfNoneMatch, etag)
+	})
+	assert.Equal(t, http.StatusNotModified, rec.Code)
+	assert.Empty(t, rec.Body.String())
+
+	// 停止保留内容的响应直接输出，不生成 ETag
+	rec = test.Request(echo.GET, `/stream`, e)
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.Equal(t, `stream`, rec.Body.String())
+	assert.Empty(t, rec.Header().Get(echo.HeaderETag))
+}
@@ -0,0 +1,43 @@
+// +build go1.16
+
+/*
+
+   Copyright 2016 Wenhui Shen <www.webx.top>
+
+   Licensed under the Apache License, Version 2.0 (the "License");
+   you may not use this file except in compliance with the License.
+   You may obtain a copy of the License at
+
+       http://www.apache.org/licenses/LICENSE-2.0
+
+   Unless required by applicable law or agreed to in writing, software
+   distributed under the License is distributed on an "AS IS" BASIS,
+   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+   See the License for the specific language governing permissions and
+   limitations under the License.
+
+*/
+package language
+
+import (
+	"io/fs"
+	"net/http"
+	"path"
+	"path/filepath"
+	"strings"
+)
+
+// SetFS 从 fs.FS(如 embed.FS)中读取语言文件，RulesPath 和 MessagesPath 为 fsys 中的目录
+func (c *Config) SetFS(fsys fs.FS) *Config {
+	return c.SetFSFunc(func(dir string) http.FileSystem {
+		dir = strings.TrimPrefix(path.Clean(`/`+filepath.ToSlash(dir)), `/`)
+		if len(dir) == 0 {
+			return http.FS(fsys)
+		}
+		sub, err := fs.Sub(fsys, dir)
+		if err != nil {
+			return http.FS(fsys)
+		}
+		return http.FS(sub)
+	})
+}
@@ -18,7 +18,6 @@
 package language
 
 import (
-	"regexp"
 	"strings"
 
 	"github.com/webx-top/echo"
@@ -26,9 +25,8 @@ import (
 )
 
 var (
-	LangVarName        = `lang`
-	DefaultLang        = `zh-cn`
-	headerAcceptRemove = regexp.MustCompile(`;q=[0-9.]+`)
+	LangVarName = `lang`
+	DefaultLang = `zh-cn`
 )
 
 func New(c ...*Config) *Language {
@@ -112,14 +110,14 @@ func (a *Language) Valid(lang string) bool {
 	return false
 }
 
+// DetectHeader 按 q 值从高到低匹配 Accept-Language 中的语种
 func (a *Language) DetectHeader(r engine.Request) string {
-	al := r.Header().Get(`Accept-Language`)
-	al = headerAcceptRemove.ReplaceAllString(al, ``)
-	lg := strings.SplitN(al, `,`, 5)
-	for _, lang := range lg {
-		lang = strings.ToLower(lang)
-		if a.Valid(lang) {
-			return lang
+	for _, al := range echo.ParseAcceptQuality(r.Header().Get(echo.HeaderAcceptLanguage)) {
+		if al.Q <= 0 {
+			continue
+		}
+		if a.Valid(al.Value) {
+			return al.Value
 		}
 	}
 	return a.Default
@@ -145,6 +143,7 @@ func (a *Language) Middleware() echo.MiddlewareFunc {
 				c.SetCookie(LangVarName, lang)
 			}
 			c.SetTranslator(NewTranslate(lang, a.I18n))
+			echo.AddVary(c.Response().Header(), echo.HeaderAcceptLanguage)
 			return h.Handle(c)
 		})
 	})
@@ -0,0 +1,94 @@
+// +build go1.16
+
+/*
+
+   Copyright 2016 Wenhui Shen <www.webx.top>
+
+   Licensed under the Apache License, Version 2.0 (the "License");
+   you may not use this file except in compliance with the License.
+   You may obtain a copy of the License at
+
+       http://www.apache.org/licenses/LICENSE-2.0
+
+   Unless required by applicable law or agreed to in writing, software
+   distributed under the License is distributed on an "AS IS" BASIS,
+   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+   See the License for the specific language governing permissions and
+   limitations under the License.
+
+*/
+
+package manager
+
+import (
+	"io/fs"
+	"path"
+	"path/filepath"
+	"strings"
+	"sync"
+
+	"github.com/webx-top/echo/middleware/render/driver"
+)
+
+var _ driver.Manager = &FSManager{}
+
+// NewFS 创建从 fs.FS(如 embed.FS)读取模板的管理器。
+// fsys 的根目录对应模板目录 templateDir
+func NewFS(fsys fs.FS, templateDir string) *FSManager {
+	prefix, _ := filepath.Abs(templateDir)
+	return &FSManager{
+		BaseManager: &driver.BaseManager{},
+		FS:          fsys,
+		Prefix:      prefix,
+		caches:      map[string][]byte{},
+	}
+}
+
+// FSManager 从 fs.FS 读取模板并缓存内容
+type FSManager struct {
+	*driver.BaseManager
+	FS     fs.FS
+	Prefix string
+	caches map[string][]byte
+	mutex  sync.RWMutex
+}
+
+func (m *FSManager) name(tmpl string) string {
+	if abs, err := filepath.Abs(tmpl); err == nil {
+		tmpl = abs
+	}
+	if tmpl == m.Prefix {
+		tmpl = ``
+	} else if strings.HasPrefix(tmpl, m.Prefix+string(filepath.Separator)) {
+		tmpl = tmpl[len(m.Prefix):]
+	}
+	tmpl = path.Clean(`/` + filepath.ToSlash(tmpl))
+	if tmpl == `/` {
+		return `.`
+	}
+	return tmpl[1:]
+}
+
+func (m *FSManager) GetTemplate(tmpl string) ([]byte, error) {
+	name := m.name(tmpl)
+	m.mutex.RLock()
+	content, ok := m.caches[name]
+	m.mutex.RUnlock()
+	if ok {
+		return content, nil
+	}
+	content, err := fs.ReadFile(m.FS, name)
+	if err != nil {
+		return nil, err
+	}
+	m.mutex.Lock()
+	m.caches[name] = content
+	m.mutex.Unlock()
+	return content, nil
+}
+
+func (m *FSManager) ClearCache() {
+	m.mutex.Lock()
+	m.caches = map[string][]byte{}
+	m.mutex.Unlock()
+}
@@ -0,0 +1,40 @@
+// +build go1.16
+
+package manager
+
+import (
+	"path/filepath"
+	"testing"
+	"testing/fstest"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestFSManager(t *testing.T) {
+	fsys := fstest.MapFS{
+		`index.html`:       {Data: []byte(`index`)},
+		`user/detail.html`: {Data: []byte(`detail`)},
+	}
+	m := NewFS(fsys, `./template`)
+	b, err := m.GetTemplate(filepath.Join(`template`, `user`, `detail.html`))
+	assert.NoError(t, err)
+	assert.Equal(t, `detail`, string(b))
+
+	b, err = m.GetTemplate(`./template/index.html`)
+	assert.NoError(t, err)
+	assert.Equal(t, `index`, string(b))
+	fsys[`index.html`] = &fstest.MapFile{Data: []byte(`new`)}
+	b, _ = m.GetTemplate(`./template/index.html`)
+	assert.Equal(t, `index`, string(b))
+	m.ClearCache()
+	b, _ = m.GetTemplate(`./template/index.html`)
+	assert.Equal(t, `new`, string(b))
+
+	_, err = m.GetTemplate(`./template/missing.html`)
+	assert.Error(t, err)
+
+	// 只去掉完整的目录前缀
+	fsys[`2/x.html`] = &fstest.MapFile{Data: []byte(`x`)}
+	_, err = m.GetTemplate(`./template2/x.html`)
+	assert.Error(t, err)
+}
@@ -106,6 +106,14 @@ func HTTPErrorHandler(opt *Options) echo.HTTPErrorHandler {
 				code = e.Code
 			}
 			msg = e.Message
+		case *echo.ProblemError:
+			if e.Status > 0 {
+				code = e.Status
+			}
+			msg = e.Error()
+		case echo.FieldErrors, *echo.FieldError:
+			code = http.StatusUnprocessableEntity
+			msg = e.Error()
 		case *echo.PanicError:
 			panicErr = e
 
@@ -1,14 +1,20 @@
 package bolt
 
 import (
+	"bytes"
+	"encoding/base64"
+	"log"
 	"runtime"
+	"strconv"
+	"sync"
 	"time"
 
-	"github.com/admpub/boltstore/reaper"
+	"github.com/admpub/boltstore/shared"
 	"github.com/admpub/boltstore/store"
 	"github.com/admpub/sessions"
 	"github.com/boltdb/bolt"
 	"github.com/webx-top/echo"
+	"github.com/webx-top/echo/encoding/keyring"
 	ss "github.com/webx-top/echo/middleware/session/engine"
 )
 
@@ -39,10 +45,13 @@ func RegWithOptions(opts *BoltOptions, args ...string) sessions.Store {
 }
 
 type BoltOptions struct {
-	File          string        `json:"file"`
-	KeyPairs      [][]byte      `json:"keyPairs"`
-	BucketName    string        `json:"bucketName"`
-	CheckInterval time.Duration `json:"checkInterval"`
+	File          string                         `json:"file"`
+	KeyPairs      [][]byte                       `json:"keyPairs"`
+	Keyring       *keyring.Keyring               `json:"-"` // 设置后代替 KeyPairs，用于密钥轮换
+	BucketName    string                         `json:"bucketName"`
+	CheckInterval time.Duration                  `json:"checkInterval"` // 回收过期会话的间隔(<0 时不启动后台回收)
+	MaxAge        time.Duration                  `json:"maxAge"`        // 超过此时长未保存的会话即使尚未到期也会被回收(为 0 时只回收已到期的会话)
+	OnGC          func(reclaimed int, err error) `json:"-"`             // 每次回收之后调用，可用于上报监控指标
 }
 
 // NewBoltStore ./sessions.db
@@ -55,11 +64,14 @@ func NewBoltStore(opts *BoltOptions) (sessions.Store, error) {
 	b := &boltStore{
 		config:   &config,
 		keyPairs: opts.KeyPairs,
+		keyring:  opts.Keyring,
 		dbFile:   opts.File,
 		Storex: &Storex{
 			Store: &store.Store{},
 		},
 		checkInterval: opts.CheckInterval,
+		maxAge:        opts.MaxAge,
+		onGC:          opts.OnGC,
 	}
 	b.Storex.b = b
 	return b, nil
@@ -70,6 +82,13 @@ type Storex struct {
 	db          *bolt.DB
 	b           *boltStore
 	initialized bool
+	mutex       sync.RWMutex // 密钥变更时会替换 Store
+}
+
+func (s *Storex) current() *store.Store {
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
+	return s.Store
 }
 
 func (s *Storex) Get(ctx echo.Context, name string) (*sessions.Session, error) {
@@ -79,7 +98,11 @@ func (s *Storex) Get(ctx echo.Context, name string) (*sessions.Session, error) {
 			return nil, err
 		}
 	}
-	return s.Store.Get(ctx, name)
+	session, err := s.current().Get(ctx, name)
+	if err == nil && session != nil && !session.IsNew && s.b.keyring != nil {
+		ss.CheckStale(ctx, name, new(string), s.b.keyring.Codecs()...)
+	}
+	return session, err
 }
 
 func (s *Storex) New(ctx echo.Context, name string) (*sessions.Session, error) {
@@ -89,7 +112,7 @@ func (s *Storex) New(ctx echo.Context, name string) (*sessions.Session, error) {
 			return nil, err
 		}
 	}
-	return s.Store.New(ctx, name)
+	return s.current().New(ctx, name)
 }
 
 func (s *Storex) Save(ctx echo.Context, session *sessions.Session) error {
@@ -99,23 +122,24 @@ func (s *Storex) Save(ctx echo.Context, session *sessions.Session) error {
 			return err
 		}
 	}
-	return s.Store.Save(ctx, session)
+	return s.current().Save(ctx, session)
 }
 
 type boltStore struct {
 	*Storex
 	config        *store.Config
 	keyPairs      [][]byte
-	quiteC        chan<- struct{}
-	doneC         <-chan struct{}
+	keyring       *keyring.Keyring
+	gc            *ss.GC
 	dbFile        string
 	checkInterval time.Duration
+	maxAge        time.Duration
+	onGC          func(reclaimed int, err error)
 }
 
 func (c *boltStore) Close() error {
-	// Invoke a reaper which checks and removes expired sessions periodically.
-	if c.quiteC != nil && c.doneC != nil {
-		reaper.Quit(c.quiteC, c.doneC)
+	if c.gc != nil {
+		c.gc.Stop()
 	}
 
 	if c.Storex.db != nil {
@@ -125,6 +149,23 @@ func (c *boltStore) Close() error {
 	return nil
 }
 
+// Remove 删除会话数据
+func (b *boltStore) Remove(sessionID string) error {
+	if b.Storex.initialized == false {
+		err := b.Init()
+		if err != nil {
+			return err
+		}
+	}
+	return b.Storex.db.Update(func(tx *bolt.Tx) error {
+		bucket := tx.Bucket(b.bucketName())
+		if bucket == nil {
+			return nil
+		}
+		return bucket.Delete([]byte(sessionID))
+	})
+}
+
 func (b *boltStore) Init() error {
 	if b.Storex.db == nil {
 		var err error
@@ -132,17 +173,19 @@ func (b *boltStore) Init() error {
 		if err != nil {
 			return err
 		}
-		b.Storex.Store, err = store.New(b.Storex.db, *b.config, b.keyPairs...)
+		keyPairs := b.keyPairs
+		if b.keyring != nil {
+			keyPairs = b.keyring.KeyPairs()
+			b.keyring.OnChange(b.reloadKeys)
+		}
+		b.Storex.Store, err = store.New(b.Storex.db, *b.config, keyPairs...)
 		if err != nil {
 			return err
 		}
 		if b.checkInterval == 0 {
 			b.checkInterval = DefaultCheckInterval
 		}
-		b.quiteC, b.doneC = reaper.Run(b.Storex.db, reaper.Options{
-			BucketName:    b.config.DBOptions.BucketName,
-			CheckInterval: b.checkInterval,
-		})
+		b.gc = ss.NewGC(b.checkInterval, b.collect, b.onGC).Start()
 		runtime.SetFinalizer(b, func(b *boltStore) {
 			b.Close()
 		})
@@ -150,3 +193,131 @@ func (b *boltStore) Init() error {
 	b.Storex.initialized = true
 	return nil
 }
+
+// reloadKeys 密钥环变更后使用新的密钥重新创建存储
+func (b *boltStore) reloadKeys() {
+	st, err := store.New(b.Storex.db, *b.config, b.keyring.KeyPairs()...)
+	if err != nil {
+		log.Println(`sessions: failed to reload bolt keys:`, err)
+		return
+	}
+	b.Storex.mutex.Lock()
+	b.Storex.Store = st
+	b.Storex.mutex.Unlock()
+}
+
+// GC 返回回收器，可用于手动回收和获取统计信息
+func (b *boltStore) GC() (*ss.GC, error) {
+	if b.Storex.initialized == false {
+		if err := b.Init(); err != nil {
+			return nil, err
+		}
+	}
+	return b.gc, nil
+}
+
+func (b *boltStore) bucketName() []byte {
+	bucketName := b.config.DBOptions.BucketName
+	if len(bucketName) == 0 {
+		bucketName = []byte(`sessions`)
+	}
+	return bucketName
+}
+
+// gcBatchSize 每个事务中最多删除的会话数，避免长时间持有写锁
+var gcBatchSize = 1000
+
+// collect 删除过期的会话
+func (b *boltStore) collect() (int, error) {
+	bucketName := b.bucketName()
+	var n int
+	var seek []byte
+	for {
+		var keys [][]byte
+		err := b.Storex.db.View(func(tx *bolt.Tx) error {
+			bucket := tx.Bucket(bucketName)
+			if bucket == nil {
+				return nil
+			}
+			c := bucket.Cursor()
+			k, v := c.First()
+			if seek != nil {
+				k, v = c.Seek(seek)
+			}
+			for ; k != nil; k, v = c.Next() {
+				if len(keys) >= gcBatchSize {
+					seek = append([]byte{}, k...)
+					return nil
+				}
+				if b.expired(v) {
+					keys = append(keys, append([]byte{}, k...))
+				}
+			}
+			seek = nil
+			return nil
+		})
+		if err != nil {
+			return n, err
+		}
+		if len(keys) > 0 {
+			err = b.Storex.db.Update(func(tx *bolt.Tx) error {
+				bucket := tx.Bucket(bucketName)
+				if bucket == nil {
+					return nil
+				}
+				for _, k := range keys {
+					// 在写事务中重新检查，期间可能已被重新保存
+					if !b.expired(bucket.Get(k)) {
+						continue
+					}
+					if err := bucket.Delete(k); err != nil {
+						return err
+					}
+					n++
+				}
+				return nil
+			})
+			if err != nil {
+				return n, err
+			}
+		}
+		if seek == nil {
+			return n, nil
+		}
+	}
+}
+
+func (b *boltStore) expired(data []byte) bool {
+	if data == nil {
+		return false
+	}
+	session, err := shared.Session(data)
+	if err != nil {
+		return false
+	}
+	if shared.Expired(session) {
+		return true
+	}
+	if b.maxAge <= 0 {
+		return false
+	}
+	saved, ok := encodedTime(session.GetValues())
+	return ok && time.Since(saved) > b.maxAge
+}
+
+// encodedTime 从 securecookie 编码的数据("时间戳|数据|签名"的 base64)中读取保存时间
+func encodedTime(value []byte) (time.Time, bool) {
+	b, err := base64.URLEncoding.DecodeString(string(value))
+	if err != nil {
+		return time.Time{}, false
+	}
+	pos := bytes.IndexByte(b, '|')
+	if pos < 0 {
+		return time.Time{}, false
+	}
+	ts, err := strconv.ParseInt(string(b[:pos]), 10, 64)
+	if err != nil {
+		return time.Time{}, false
+	}
+	return time.Unix(ts, 0), true
+}
@@ -0,0 +1,86 @@
+package bolt
+
+import (
+	"encoding/base64"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/webx-top/echo"
+	"github.com/webx-top/echo/middleware/session"
+	test "github.com/webx-top/echo/testing"
+)
+
+func TestEncodedTime(t *testing.T) {
+	ts := time.Now().Add(-time.Hour).Unix()
+	value := base64.URLEncoding.EncodeToString([]byte(strconv.FormatInt(ts, 10) + `|data|sig`))
+	saved, ok := encodedTime([]byte(value))
+	assert.True(t, ok)
+	assert.Equal(t, ts, saved.Unix())
+
+	for _, v := range []string{
+		`!invalid`,
+		base64.URLEncoding.EncodeToString([]byte(`data`)),
+		base64.URLEncoding.EncodeToString([]byte(`ts|data|sig`)),
+	} {
+		_, ok = encodedTime([]byte(v))
+		assert.False(t, ok, v)
+	}
+}
+
+func TestBoltStoreGC(t *testing.T) {
+	dir, err := ioutil.TempDir(``, `echo-session-bolt`)
+	assert.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	st, err := NewBoltStore(&BoltOptions{
+		File:          filepath.Join(dir, `sessions.db`),
+		KeyPairs:      [][]byte{[]byte(`0123456789abcdef0123456789abcdef`)},
+		CheckInterval: -1,
+	})
+	assert.NoError(t, err)
+	store := st.(*boltStore)
+	defer store.Close()
+	save := func(maxAge int) {
+		e := echo.New()
+		e.Use(session.Sessions(echo.NewSessionOptions(`bolt`, `SID`, &echo.CookieOptions{MaxAge: maxAge, Path: `/`}), store))
+		e.Get(`/`, func(ctx echo.Context) error {
+			ctx.Session().Set(`v`, 1)
+			return ctx.String(`ok`)
+		})
+		e.RebuildRouter()
+		test.Request(echo.GET, `/`, e)
+	}
+	save(1)
+	save(1)
+	save(3600)
+	time.Sleep(2100 * time.Millisecond)
+
+	gc, err := store.GC()
+	assert.NoError(t, err)
+	// 只回收已到期的会话
+	n, err := gc.Run()
+	assert.NoError(t, err)
+	assert.Equal(t, 2, n)
+	n, _ = gc.Run()
+	assert.Equal(t, 0, n)
+	stats := gc.Stats()
+	assert.Equal(t, int64(2), stats.Runs)
+	assert.Equal(t, int64(2), stats.Reclaimed)
+	assert.Equal(t, 0, stats.LastReclaimed)
+
+	// 超过 maxAge 未保存的会话即使尚未到期也会被回收
+	store.maxAge = time.Second
+	save(3600)
+	n, err = gc.Run()
+	assert.NoError(t, err)
+	assert.Equal(t, 1, n)
+	stats = gc.Stats()
+	assert.Equal(t, int64(3), stats.Runs)
+	assert.Equal(t, int64(3), stats.Reclaimed)
+	assert.Equal(t, 1, stats.LastReclaimed)
+}
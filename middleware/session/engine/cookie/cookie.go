@@ -3,6 +3,8 @@ package cookie
 import (
 	codec "github.com/admpub/securecookie"
 	"github.com/admpub/sessions"
+	"github.com/webx-top/echo"
+	"github.com/webx-top/echo/encoding/keyring"
 	ss "github.com/webx-top/echo/middleware/session/engine"
 )
 
@@ -22,6 +24,9 @@ func New(opts *CookieOptions) sessions.Store {
 		opts = defaultOptions
 	}
 	store := NewCookieStore(opts.KeyPairs...)
+	if opts.Keyring != nil {
+		store.(*cookieStore).Codecs = opts.Keyring.Codecs()
+	}
 	return store
 }
 
@@ -47,7 +52,8 @@ func NewCookieOptions(keys ...string) *CookieOptions {
 }
 
 type CookieOptions struct {
-	KeyPairs [][]byte `json:"keyPairs"`
+	KeyPairs [][]byte         `json:"keyPairs"`
+	Keyring  *keyring.Keyring `json:"-"` // 设置后代替 KeyPairs，用于密钥轮换
 }
 
 // Keys are defined in pairs to allow key rotation, but the common case is to set a single
@@ -66,3 +72,15 @@ func NewCookieStore(keyPairs ...[]byte) sessions.Store {
 type cookieStore struct {
 	*sessions.CookieStore
 }
+
+func (c *cookieStore) Get(ctx echo.Context, name string) (*sessions.Session, error) {
+	return sessions.GetRegistry(ctx).Get(c, name)
+}
+
+func (c *cookieStore) New(ctx echo.Context, name string) (*sessions.Session, error) {
+	session, err := c.CookieStore.New(ctx, name)
+	if err == nil && session != nil && !session.IsNew {
+		ss.CheckStale(ctx, name, &map[interface{}]interface{}{}, c.Codecs...)
+	}
+	return session, err
+}
@@ -1,14 +1,39 @@
 package file
 
 import (
+	"io"
 	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
 
 	"github.com/admpub/sessions"
+	"github.com/webx-top/echo"
+	"github.com/webx-top/echo/encoding/keyring"
 	ss "github.com/webx-top/echo/middleware/session/engine"
 )
 
+var (
+	DefaultGCInterval = time.Minute * 30
+	DefaultMaxAge     = time.Hour * 24 * 30
+)
+
 func New(opts *FileOptions) sessions.Store {
 	store := NewFilesystemStore(opts.SavePath, opts.KeyPairs...)
+	if opts.Keyring != nil {
+		store.Codecs = opts.Keyring.Codecs()
+	}
+	interval := opts.GCInterval
+	if interval == 0 {
+		// 使用系统临时目录时其中可能有其它程序的 session_* 文件，默认不启动后台回收
+		if len(opts.SavePath) > 0 {
+			interval = DefaultGCInterval
+		} else {
+			interval = -1
+		}
+	}
+	store.SetGC(interval, opts.MaxAge, opts.OnGC)
 	return store
 }
 
@@ -27,8 +52,12 @@ func RegWithOptions(opts *FileOptions, args ...string) sessions.Store {
 }
 
 type FileOptions struct {
-	SavePath string   `json:"savePath"`
-	KeyPairs [][]byte `json:"keyPairs"`
+	SavePath   string                         `json:"savePath"`
+	KeyPairs   [][]byte                       `json:"keyPairs"`
+	Keyring    *keyring.Keyring               `json:"-"`          // 设置后代替 KeyPairs，用于密钥轮换
+	GCInterval time.Duration                  `json:"gcInterval"` // 回收过期会话文件的间隔(<0 时不启动后台回收；为 0 时只有设置了 SavePath 才按默认间隔回收)
+	MaxAge     time.Duration                  `json:"maxAge"`     // 超过此时长未保存的会话文件视为过期，cookie 的有效期更长时以 cookie 的有效期为准
+	OnGC       func(reclaimed int, err error) `json:"-"`          // 每次回收之后调用，可用于上报监控指标
 }
 
 // NewFilesystemStore returns a new FilesystemStore.
@@ -37,8 +66,10 @@ type FileOptions struct {
 // it will use os.TempDir().
 //
 // See NewCookieStore() for a description of the other parameters.
-func NewFilesystemStore(path string, keyPairs ...[]byte) sessions.Store {
-	if len(path) > 0 {
+func NewFilesystemStore(path string, keyPairs ...[]byte) *FilesystemStore {
+	if len(path) == 0 {
+		path = os.TempDir()
+	} else {
 		fi, err := os.Stat(path)
 		if os.IsNotExist(err) || !fi.IsDir() {
 			err = os.MkdirAll(path, os.ModePerm)
@@ -47,9 +78,166 @@ func NewFilesystemStore(path string, keyPairs ...[]byte) sessions.Store {
 			}
 		}
 	}
-	return &filesystemStore{sessions.NewFilesystemStore(path, keyPairs...)}
+	return &FilesystemStore{
+		FilesystemStore: sessions.NewFilesystemStore(path, keyPairs...),
+		path:            path,
+	}
 }
 
-type filesystemStore struct {
+// FilesystemStore 文件会话存储，支持定期回收过期的会话文件
+type FilesystemStore struct {
 	*sessions.FilesystemStore
+	path   string
+	maxAge time.Duration
+	gc     *ss.GC
+	// 保存会话时持有读锁，回收时持有写锁，避免删除正在写入的会话文件
+	mutex sync.RWMutex
+}
+
+func (f *FilesystemStore) Get(ctx echo.Context, name string) (*sessions.Session, error) {
+	return sessions.GetRegistry(ctx).Get(f, name)
+}
+
+func (f *FilesystemStore) New(ctx echo.Context, name string) (*sessions.Session, error) {
+	s, err := f.FilesystemStore.New(ctx, name)
+	// 使会话通过 f.Save 保存
+	session := sessions.NewSession(f, name)
+	if s != nil {
+		session.ID = s.ID
+		session.Values = s.Values
+		session.IsNew = s.IsNew
+		if !s.IsNew {
+			ss.CheckStale(ctx, name, new(string), f.Codecs...)
+		}
+	}
+	return session, err
+}
+
+func (f *FilesystemStore) Save(ctx echo.Context, session *sessions.Session) error {
+	f.mutex.RLock()
+	defer f.mutex.RUnlock()
+	if err := f.FilesystemStore.Save(ctx, session); err != nil {
+		return err
+	}
+	return f.extend(session.ID, time.Duration(ctx.CookieOptions().MaxAge)*time.Second)
+}
+
+// extend 在 cookie 的有效期长于 maxAge 时推迟会话文件的修改时间，
+// 使回收按 cookie 的有效期进行，避免提前删除“记住我”等长期会话
+func (f *FilesystemStore) extend(sessionID string, cookieMaxAge time.Duration) error {
+	maxAge := f.maxAge
+	if maxAge <= 0 {
+		maxAge = DefaultMaxAge
+	}
+	if cookieMaxAge <= maxAge || len(sessionID) == 0 || strings.ContainsAny(sessionID, `/\`) {
+		return nil
+	}
+	file := filepath.Join(f.path, `session_`+sessionID)
+	t := time.Now().Add(cookieMaxAge - maxAge)
+	err := os.Chtimes(file, t, t)
+	if err != nil && os.IsNotExist(err) {
+		return nil
+	}
+	return err
+}
+
+// Remove 删除会话文件
+func (f *FilesystemStore) Remove(sessionID string) error {
+	if len(sessionID) == 0 || strings.ContainsAny(sessionID, `/\`) {
+		return nil
+	}
+	err := os.Remove(filepath.Join(f.path, `session_`+sessionID))
+	if err != nil && os.IsNotExist(err) {
+		return nil
+	}
+	return err
+}
+
+// SetGC 设置并启动过期会话文件的回收。interval <= 0 时只能通过 GC().Run() 手动回收，
+// maxAge 为 0 时使用 DefaultMaxAge
+func (f *FilesystemStore) SetGC(interval time.Duration, maxAge time.Duration, onCollect func(reclaimed int, err error)) *FilesystemStore {
+	if f.gc != nil {
+		f.gc.Stop()
+	}
+	if maxAge <= 0 {
+		maxAge = DefaultMaxAge
+	}
+	f.maxAge = maxAge
+	f.gc = ss.NewGC(interval, f.collect, onCollect).Start()
+	return f
+}
+
+// GC 返回回收器，可用于手动回收和获取统计信息
+func (f *FilesystemStore) GC() *ss.GC {
+	if f.gc == nil {
+		f.SetGC(0, 0, nil)
+	}
+	return f.gc
+}
+
+// Close 停止后台回收
+func (f *FilesystemStore) Close() error {
+	if f.gc != nil {
+		f.gc.Stop()
+	}
+	return nil
+}
+
+// collect 删除超过 maxAge 未修改的会话文件
+func (f *FilesystemStore) collect() (int, error) {
+	dir, err := os.Open(f.path)
+	if err != nil {
+		return 0, err
+	}
+	defer dir.Close()
+	var n int
+	for {
+		names, err := dir.Readdirnames(1000)
+		for _, name := range names {
+			if !strings.HasPrefix(name, `session_`) {
+				continue
+			}
+			removed, rmErr := f.removeExpired(filepath.Join(f.path, name))
+			if rmErr != nil {
+				return n, rmErr
+			}
+			if removed {
+				n++
+			}
+		}
+		if err == io.EOF {
+			return n, nil
+		}
+		if err != nil {
+			return n, err
+		}
+	}
+}
+
+func (f *FilesystemStore) removeExpired(file string) (bool, error) {
+	if !f.expired(file) {
+		return false, nil
+	}
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+	// 在持有锁之后重新检查，期间可能已被重新保存
+	if !f.expired(file) {
+		return false, nil
+	}
+	err := os.Remove(file)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return false, nil
+		}
+		return false, err
+	}
+	return true, nil
+}
+
+func (f *FilesystemStore) expired(file string) bool {
+	fi, err := os.Lstat(file)
+	if err != nil || !fi.Mode().IsRegular() {
+		return false
+	}
+	return time.Since(fi.ModTime()) > f.maxAge
 }
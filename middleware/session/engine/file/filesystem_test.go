@@ -0,0 +1,125 @@
+package file
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/webx-top/echo"
+	"github.com/webx-top/echo/middleware/session"
+	test "github.com/webx-top/echo/testing"
+)
+
+func TestFilesystemStoreGC(t *testing.T) {
+	dir, err := ioutil.TempDir(``, `echo-session-file`)
+	assert.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	old := time.Now().Add(-2 * time.Hour)
+	for _, name := range []string{`session_a`, `session_b`, `session_c`, `other`} {
+		file := filepath.Join(dir, name)
+		assert.NoError(t, ioutil.WriteFile(file, []byte(name), 0600))
+		if name != `session_c` {
+			assert.NoError(t, os.Chtimes(file, old, old))
+		}
+	}
+
+	var reclaimed int
+	store := NewFilesystemStore(dir).SetGC(-1, time.Hour, func(n int, err error) {
+		assert.NoError(t, err)
+		reclaimed += n
+	})
+	defer store.Close()
+	n, err := store.GC().Run()
+	assert.NoError(t, err)
+	assert.Equal(t, 2, n)
+	assert.Equal(t, 2, reclaimed)
+
+	names, _ := filepath.Glob(filepath.Join(dir, `*`))
+	for i, name := range names {
+		names[i] = filepath.Base(name)
+	}
+	assert.Equal(t, []string{`other`, `session_c`}, names)
+
+	n, _ = store.GC().Run()
+	assert.Equal(t, 0, n)
+	stats := store.GC().Stats()
+	assert.Equal(t, int64(2), stats.Runs)
+	assert.Equal(t, int64(2), stats.Reclaimed)
+	assert.Equal(t, 0, stats.LastReclaimed)
+
+	// 后台回收可以启动和停止
+	store.SetGC(time.Millisecond, time.Hour, nil)
+	time.Sleep(10 * time.Millisecond)
+	store.Close()
+	assert.True(t, store.GC().Stats().Runs > 0)
+}
+
+func TestFilesystemStoreDefaultGC(t *testing.T) {
+	dir, err := ioutil.TempDir(``, `echo-session-file`)
+	assert.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	interval := DefaultGCInterval
+	DefaultGCInterval = time.Millisecond
+	defer func() {
+		DefaultGCInterval = interval
+	}()
+
+	// 默认的系统临时目录不启动后台回收
+	tmp := New(&FileOptions{}).(*FilesystemStore)
+	dedicated := New(&FileOptions{SavePath: dir}).(*FilesystemStore)
+	time.Sleep(10 * time.Millisecond)
+	tmp.Close()
+	dedicated.Close()
+	assert.Equal(t, int64(0), tmp.GC().Stats().Runs)
+	assert.True(t, dedicated.GC().Stats().Runs > 0)
+}
+
+func TestFilesystemStoreCookieMaxAge(t *testing.T) {
+	dir, err := ioutil.TempDir(``, `echo-session-file`)
+	assert.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	store := NewFilesystemStore(dir, []byte(`0123456789abcdef0123456789abcdef`)).SetGC(-1, time.Hour, nil)
+	defer store.Close()
+	for _, c := range []struct {
+		maxAge   int
+		extended bool
+	}{
+		{0, false},
+		{1800, false},
+		// cookie 的有效期长于 maxAge 时按 cookie 的有效期回收
+		{3 * 3600, true},
+	} {
+		e := echo.New()
+		e.Use(session.Sessions(echo.NewSessionOptions(`file`, `SID`, &echo.CookieOptions{MaxAge: c.maxAge, Path: `/`}), store))
+		var id string
+		e.Get(`/`, func(ctx echo.Context) error {
+			ctx.Session().Set(`v`, 1)
+			err := ctx.Session().Save()
+			id = ctx.Session().ID()
+			return err
+		})
+		e.RebuildRouter()
+		test.Request(echo.GET, `/`, e)
+		if !assert.NotEmpty(t, id, c.maxAge) {
+			continue
+		}
+		fi, err := os.Stat(filepath.Join(dir, `session_`+id))
+		if !assert.NoError(t, err, c.maxAge) {
+			continue
+		}
+		if c.extended {
+			assert.WithinDuration(t, time.Now().Add(2*time.Hour), fi.ModTime(), time.Minute, c.maxAge)
+		} else {
+			assert.WithinDuration(t, time.Now(), fi.ModTime(), time.Minute, c.maxAge)
+		}
+	}
+	n, err := store.GC().Run()
+	assert.NoError(t, err)
+	assert.Equal(t, 0, n)
+}
@@ -0,0 +1,116 @@
+package engine
+
+import (
+	"log"
+	"sync"
+	"time"
+)
+
+// GCStats 过期会话回收统计
+type GCStats struct {
+	Runs          int64         `json:"runs"`          // 回收次数
+	Reclaimed     int64         `json:"reclaimed"`     // 累计回收的会话数
+	Errors        int64         `json:"errors"`        // 累计出错次数
+	LastRun       time.Time     `json:"lastRun"`       // 最后一次回收的开始时间
+	LastReclaimed int           `json:"lastReclaimed"` // 最后一次回收的会话数
+	LastDuration  time.Duration `json:"lastDuration"`  // 最后一次回收的耗时
+	LastError     string        `json:"lastError"`     // 最后一次回收的错误信息
+}
+
+// NewGC 创建过期会话回收器。collect 执行一次回收并返回回收的会话数，
+// onCollect 在每次回收之后调用(可以为 nil)，可用于上报监控指标
+func NewGC(interval time.Duration, collect func() (int, error), onCollect func(reclaimed int, err error)) *GC {
+	return &GC{
+		interval:  interval,
+		collect:   collect,
+		onCollect: onCollect,
+	}
+}
+
+// GC 定期回收过期会话，可安全地并发使用
+type GC struct {
+	interval  time.Duration
+	collect   func() (int, error)
+	onCollect func(reclaimed int, err error)
+
+	runMutex sync.Mutex // 保证同一时间只有一次回收
+	mutex    sync.Mutex
+	stats    GCStats
+	quit     chan struct{}
+	done     chan struct{}
+}
+
+// Start 启动后台回收，重复调用无效
+func (g *GC) Start() *GC {
+	g.mutex.Lock()
+	defer g.mutex.Unlock()
+	if g.quit != nil || g.interval <= 0 {
+		return g
+	}
+	g.quit, g.done = make(chan struct{}), make(chan struct{})
+	go g.loop(g.interval, g.quit, g.done)
+	return g
+}
+
+// Stop 停止后台回收并等待正在进行的回收结束，重复调用无效
+func (g *GC) Stop() {
+	g.mutex.Lock()
+	quit, done := g.quit, g.done
+	g.quit, g.done = nil, nil
+	g.mutex.Unlock()
+	if quit == nil {
+		return
+	}
+	close(quit)
+	<-done
+}
+
+// Run 立即执行一次回收
+func (g *GC) Run() (int, error) {
+	g.runMutex.Lock()
+	defer g.runMutex.Unlock()
+	start := time.Now()
+	n, err := g.collect()
+	g.mutex.Lock()
+	g.stats.Runs++
+	g.stats.Reclaimed += int64(n)
+	g.stats.LastRun = start
+	g.stats.LastReclaimed = n
+	g.stats.LastDuration = time.Since(start)
+	if err != nil {
+		g.stats.Errors++
+		g.stats.LastError = err.Error()
+	} else {
+		g.stats.LastError = ``
+	}
+	g.mutex.Unlock()
+	if g.onCollect != nil {
+		g.onCollect(n, err)
+	}
+	return n, err
+}
+
+// Stats 返回回收统计
+func (g *GC) Stats() GCStats {
+	g.mutex.Lock()
+	defer g.mutex.Unlock()
+	return g.stats
+}
+
+func (g *GC) loop(interval time.Duration, quit <-chan struct{}, done chan<- struct{}) {
+	ticker := time.NewTicker(interval)
+	defer func() {
+		ticker.Stop()
+		close(done)
+	}()
+	for {
+		select {
+		case <-quit:
+			return
+		case <-ticker.C:
+			if _, err := g.Run(); err != nil {
+				log.Printf(errorFormat, err)
+			}
+		}
+	}
+}
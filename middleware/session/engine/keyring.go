@@ -0,0 +1,66 @@
+package engine
+
+import (
+	"github.com/admpub/securecookie"
+	"github.com/webx-top/echo"
+)
+
+// reencoder 由支持密钥轮换的编解码器(如 encoding/keyring.Keyring)实现，
+// 返回的序号大于 0 时表示使用了旧密钥
+type reencoder interface {
+	DecodeIndex(name string, value string, dst interface{}) (int, error)
+}
+
+func staleKey(name string) string {
+	return `__session_stale_` + name
+}
+
+// MarkStale 标记会话使用了旧密钥解码，本次请求结束时会以最新的密钥重新保存
+func MarkStale(ctx echo.Context, name string) {
+	ctx.Internal().Set(staleKey(name), true)
+}
+
+// IsStale 会话是否被标记为使用了旧密钥解码
+func IsStale(ctx echo.Context, name string) bool {
+	return ctx.Internal().Bool(staleKey(name))
+}
+
+// DecodeMulti 与 securecookie.DecodeMulti 相同，
+// 使用支持密钥轮换的编解码器中的旧密钥解码时，标记会话需要重新保存
+func DecodeMulti(ctx echo.Context, name string, value string, dst interface{}, codecs ...securecookie.Codec) error {
+	if len(codecs) == 0 {
+		return securecookie.DecodeMulti(name, value, dst)
+	}
+	var errs securecookie.MultiError
+	for _, codec := range codecs {
+		var err error
+		if r, ok := codec.(reencoder); ok {
+			var index int
+			index, err = r.DecodeIndex(name, value, dst)
+			if err == nil && index > 0 {
+				MarkStale(ctx, name)
+			}
+		} else {
+			err = codec.Decode(name, value, dst)
+		}
+		if err == nil {
+			return nil
+		}
+		errs = append(errs, err)
+	}
+	return errs
+}
+
+// CheckStale 用于由其它库解码 cookie 的存储引擎：codecs 中有支持密钥轮换的编解码器时，
+// 重新解码会话 cookie 以检查是否使用了旧密钥。dst 的类型应与 cookie 中编码的值相同
+func CheckStale(ctx echo.Context, name string, dst interface{}, codecs ...securecookie.Codec) {
+	for _, codec := range codecs {
+		if _, ok := codec.(reencoder); !ok {
+			continue
+		}
+		if value := ctx.GetCookie(name); len(value) > 0 {
+			DecodeMulti(ctx, name, value, dst, codecs...)
+		}
+		return
+	}
+}
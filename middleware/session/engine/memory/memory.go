@@ -0,0 +1,249 @@
+package memory
+
+import (
+	"container/list"
+	"encoding/base32"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/admpub/securecookie"
+	"github.com/admpub/sessions"
+	"github.com/webx-top/echo"
+	"github.com/webx-top/echo/encoding/keyring"
+	ss "github.com/webx-top/echo/middleware/session/engine"
+)
+
+var (
+	DefaultMaxAge          = 86400
+	DefaultCleanupInterval = time.Minute * 5
+)
+
+func New(opts *MemoryOptions) sessions.Store {
+	return NewMemoryStore(opts)
+}
+
+func Reg(store sessions.Store, args ...string) {
+	name := `memory`
+	if len(args) > 0 {
+		name = args[0]
+	}
+	ss.Reg(name, store)
+}
+
+func RegWithOptions(opts *MemoryOptions, args ...string) sessions.Store {
+	store := New(opts)
+	Reg(store, args...)
+	return store
+}
+
+type MemoryOptions struct {
+	KeyPairs        [][]byte         `json:"keyPairs"`
+	Keyring         *keyring.Keyring `json:"-"`               // 设置后代替 KeyPairs，用于密钥轮换
+	MaxEntries      int              `json:"maxEntries"`      // 最多保存的会话数，超过后淘汰最近最少使用的会话(<=0 时不限制)
+	CleanupInterval time.Duration    `json:"cleanupInterval"` // 清理过期会话的间隔(<0 时不启动后台清理)
+}
+
+type item struct {
+	id      string
+	values  map[interface{}]interface{}
+	expires time.Time
+}
+
+// NewMemoryStore 创建内存会话存储。
+// 会话 ID 保存在 cookie 中，会话数据保存在内存中，因此只适用于单个进程
+func NewMemoryStore(opts *MemoryOptions) *MemoryStore {
+	if opts == nil {
+		opts = &MemoryOptions{}
+	}
+	keyPairs := opts.KeyPairs
+	if len(keyPairs) == 0 {
+		keyPairs = [][]byte{
+			securecookie.GenerateRandomKey(32),
+			securecookie.GenerateRandomKey(32),
+		}
+	}
+	codecs := securecookie.CodecsFromPairs(keyPairs...)
+	if opts.Keyring != nil {
+		codecs = opts.Keyring.Codecs()
+	}
+	m := &MemoryStore{
+		Codecs:     codecs,
+		maxEntries: opts.MaxEntries,
+		ll:         list.New(),
+		items:      map[string]*list.Element{},
+	}
+	interval := opts.CleanupInterval
+	if interval == 0 {
+		interval = DefaultCleanupInterval
+	}
+	if interval > 0 {
+		m.quit = make(chan struct{})
+		go m.cleanup(interval)
+	}
+	return m
+}
+
+// MemoryStore 内存会话存储，可安全地并发使用
+type MemoryStore struct {
+	Codecs     []securecookie.Codec
+	mutex      sync.Mutex
+	maxEntries int
+	ll         *list.List
+	items      map[string]*list.Element
+	quit       chan struct{}
+	closeOnce  sync.Once
+}
+
+func (m *MemoryStore) Get(ctx echo.Context, name string) (*sessions.Session, error) {
+	return sessions.GetRegistry(ctx).Get(m, name)
+}
+
+func (m *MemoryStore) New(ctx echo.Context, name string) (*sessions.Session, error) {
+	session := sessions.NewSession(m, name)
+	session.IsNew = true
+	value := ctx.GetCookie(name)
+	if len(value) == 0 {
+		return session, nil
+	}
+	err := ss.DecodeMulti(ctx, name, value, &session.ID, m.Codecs...)
+	if err != nil {
+		return session, err
+	}
+	if values, ok := m.Values(session.ID); ok {
+		session.Values = values
+		session.IsNew = false
+	}
+	return session, nil
+}
+
+func (m *MemoryStore) Save(ctx echo.Context, session *sessions.Session) error {
+	// Delete if max-age is < 0
+	if ctx.CookieOptions().MaxAge < 0 {
+		m.Remove(session.ID)
+		sessions.SetCookie(ctx, session.Name(), ``, -1)
+		return nil
+	}
+	if len(session.ID) == 0 {
+		session.ID = strings.TrimRight(
+			base32.StdEncoding.EncodeToString(
+				securecookie.GenerateRandomKey(32)), "=")
+	}
+	maxAge := ctx.CookieOptions().MaxAge
+	if maxAge == 0 {
+		maxAge = DefaultMaxAge
+	}
+	m.set(session.ID, session.Values, time.Duration(maxAge)*time.Second)
+	encoded, err := securecookie.EncodeMulti(session.Name(), session.ID, m.Codecs...)
+	if err != nil {
+		return err
+	}
+	sessions.SetCookie(ctx, session.Name(), encoded)
+	return nil
+}
+
+// Remove 删除会话数据
+func (m *MemoryStore) Remove(sessionID string) error {
+	m.mutex.Lock()
+	if el, ok := m.items[sessionID]; ok {
+		m.removeElement(el)
+	}
+	m.mutex.Unlock()
+	return nil
+}
+
+// Values 返回会话数据的副本，会话不存在或已过期时第二个返回值为 false
+func (m *MemoryStore) Values(sessionID string) (map[interface{}]interface{}, bool) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+	el, ok := m.items[sessionID]
+	if !ok {
+		return nil, false
+	}
+	it := el.Value.(*item)
+	if time.Now().After(it.expires) {
+		m.removeElement(el)
+		return nil, false
+	}
+	m.ll.MoveToFront(el)
+	return copyValues(it.values), true
+}
+
+// Len 当前保存的会话数(包含尚未清理的过期会话)
+func (m *MemoryStore) Len() int {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+	return m.ll.Len()
+}
+
+// Cleanup 删除所有过期的会话，返回删除的数量
+func (m *MemoryStore) Cleanup() int {
+	now := time.Now()
+	var n int
+	m.mutex.Lock()
+	for el := m.ll.Back(); el != nil; {
+		prev := el.Prev()
+		if now.After(el.Value.(*item).expires) {
+			m.removeElement(el)
+			n++
+		}
+		el = prev
+	}
+	m.mutex.Unlock()
+	return n
+}
+
+// Close 停止后台清理
+func (m *MemoryStore) Close() error {
+	m.closeOnce.Do(func() {
+		if m.quit != nil {
+			close(m.quit)
+		}
+	})
+	return nil
+}
+
+func (m *MemoryStore) cleanup(interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+			m.Cleanup()
+		case <-m.quit:
+			return
+		}
+	}
+}
+
+func (m *MemoryStore) set(sessionID string, values map[interface{}]interface{}, ttl time.Duration) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+	it := &item{
+		id:      sessionID,
+		values:  copyValues(values),
+		expires: time.Now().Add(ttl),
+	}
+	if el, ok := m.items[sessionID]; ok {
+		el.Value = it
+		m.ll.MoveToFront(el)
+		return
+	}
+	m.items[sessionID] = m.ll.PushFront(it)
+	for m.maxEntries > 0 && m.ll.Len() > m.maxEntries {
+		m.removeElement(m.ll.Back())
+	}
+}
+
+func (m *MemoryStore) removeElement(el *list.Element) {
+	it := m.ll.Remove(el).(*item)
+	delete(m.items, it.id)
+}
+
+func copyValues(values map[interface{}]interface{}) map[interface{}]interface{} {
+	r := make(map[interface{}]interface{}, len(values))
+	for k, v := range values {
+		r[k] = v
+	}
+	return r
+}
@@ -0,0 +1,119 @@
+package memory_test
+
+import (
+	"fmt"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/webx-top/echo"
+	"github.com/webx-top/echo/encoding/keyring"
+	"github.com/webx-top/echo/middleware/session"
+	"github.com/webx-top/echo/middleware/session/engine/memory"
+	test "github.com/webx-top/echo/testing"
+)
+
+func TestMemoryStore(t *testing.T) {
+	store := memory.NewMemoryStore(&memory.MemoryOptions{MaxEntries: 2, CleanupInterval: -1})
+	defer store.Close()
+	e := echo.New()
+	e.Use(session.Sessions(echo.NewSessionOptions(`memory`, `SID`), store))
+	e.Get(`/set/:v`, func(ctx echo.Context) error {
+		ctx.Session().Set(`v`, ctx.Param(`v`))
+		return ctx.String(`ok`)
+	})
+	e.Get(`/id`, func(ctx echo.Context) error {
+		return ctx.String(ctx.Session().ID())
+	})
+	e.Get(`/get`, func(ctx echo.Context) error {
+		return ctx.String(fmt.Sprintf(`%v`, ctx.Session().Get(`v`)))
+	})
+	e.Get(`/logout`, func(ctx echo.Context) error {
+		return ctx.Session().Destroy()
+	})
+	e.RebuildRouter()
+	withCookie := func(rec http.Header) func(*http.Request) {
+		return func(req *http.Request) {
+			for _, h := range rec["Set-Cookie"] {
+				req.Header.Add(`Cookie`, h)
+			}
+		}
+	}
+
+	a := test.Request(echo.GET, `/set/a`, e)
+	id := test.Request(echo.GET, `/id`, e, withCookie(a.Header())).Body.String()
+	values, ok := store.Values(id)
+	assert.True(t, ok)
+	assert.Equal(t, `a`, values[`v`])
+	rec := test.Request(echo.GET, `/get`, e, withCookie(a.Header()))
+	assert.Equal(t, `a`, rec.Body.String())
+
+	// LRU
+	test.Request(echo.GET, `/set/b`, e)
+	test.Request(echo.GET, `/get`, e, withCookie(a.Header()))
+	test.Request(echo.GET, `/set/c`, e)
+	assert.Equal(t, 2, store.Len())
+	rec = test.Request(echo.GET, `/get`, e, withCookie(a.Header()))
+	assert.Equal(t, `a`, rec.Body.String())
+
+	test.Request(echo.GET, `/logout`, e, withCookie(a.Header()))
+	_, ok = store.Values(id)
+	assert.False(t, ok)
+	assert.Equal(t, 1, store.Len())
+}
+
+func TestMemoryStoreCleanup(t *testing.T) {
+	store := memory.NewMemoryStore(&memory.MemoryOptions{CleanupInterval: -1})
+	e := echo.New()
+	opts := echo.NewSessionOptions(`memory`, `SID`, &echo.CookieOptions{MaxAge: 1, Path: `/`})
+	e.Use(session.Sessions(opts, store))
+	e.Get(`/`, func(ctx echo.Context) error {
+		ctx.Session().Set(`v`, 1)
+		return ctx.String(`ok`)
+	})
+	e.RebuildRouter()
+	test.Request(echo.GET, `/`, e)
+	assert.Equal(t, 0, store.Cleanup())
+	time.Sleep(1100 * time.Millisecond)
+	assert.Equal(t, 1, store.Cleanup())
+	assert.Equal(t, 0, store.Len())
+}
+
+func TestMemoryStoreKeyRotation(t *testing.T) {
+	ring := keyring.New([]byte(`hash-key-1`))
+	store := memory.NewMemoryStore(&memory.MemoryOptions{Keyring: ring, CleanupInterval: -1})
+	defer store.Close()
+	e := echo.New()
+	e.Use(session.Sessions(echo.NewSessionOptions(`memory`, `SID`), store))
+	e.Get(`/set`, func(ctx echo.Context) error {
+		ctx.Session().Set(`v`, `a`)
+		return ctx.String(`ok`)
+	})
+	e.Get(`/get`, func(ctx echo.Context) error {
+		return ctx.String(fmt.Sprintf(`%v`, ctx.Session().Get(`v`)))
+	})
+	e.RebuildRouter()
+	withCookie := func(rec http.Header) func(*http.Request) {
+		return func(req *http.Request) {
+			for _, h := range rec["Set-Cookie"] {
+				req.Header.Add(`Cookie`, h)
+			}
+		}
+	}
+
+	old := test.Request(echo.GET, `/set`, e)
+	rec := test.Request(echo.GET, `/get`, e, withCookie(old.Header()))
+	assert.Equal(t, `a`, rec.Body.String())
+	assert.Empty(t, rec.Header().Get(echo.HeaderSetCookie))
+
+	// 使用旧密钥解码的会话以新密钥重新保存
+	ring.Rotate([]byte(`hash-key-2`), nil)
+	rec = test.Request(echo.GET, `/get`, e, withCookie(old.Header()))
+	assert.Equal(t, `a`, rec.Body.String())
+	renewed := rec.Header()
+	assert.NotEmpty(t, renewed.Get(echo.HeaderSetCookie))
+	rec = test.Request(echo.GET, `/get`, e, withCookie(renewed))
+	assert.Equal(t, `a`, rec.Body.String())
+	assert.Empty(t, rec.Header().Get(echo.HeaderSetCookie))
+}
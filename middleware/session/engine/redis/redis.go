@@ -3,6 +3,8 @@ package redis
 import (
 	"github.com/admpub/redistore"
 	"github.com/admpub/sessions"
+	"github.com/webx-top/echo"
+	"github.com/webx-top/echo/encoding/keyring"
 	ss "github.com/webx-top/echo/middleware/session/engine"
 )
 
@@ -29,11 +31,12 @@ func RegWithOptions(opts *RedisOptions, args ...string) sessions.Store {
 }
 
 type RedisOptions struct {
-	Size     int      `json:"size"`
-	Network  string   `json:"network"`
-	Address  string   `json:"address"`
-	Password string   `json:"password"`
-	KeyPairs [][]byte `json:"keyPairs"`
+	Size     int              `json:"size"`
+	Network  string           `json:"network"`
+	Address  string           `json:"address"`
+	Password string           `json:"password"`
+	KeyPairs [][]byte         `json:"keyPairs"`
+	Keyring  *keyring.Keyring `json:"-"` // 设置后代替 KeyPairs，用于密钥轮换
 }
 
 // size: maximum number of idle connections.
@@ -54,9 +57,39 @@ func NewRedisStore(opts *RedisOptions) (sessions.Store, error) {
 	if err != nil {
 		return nil, err
 	}
-	return &redisStore{store}, nil
+	if opts.Keyring != nil {
+		store.Codecs = opts.Keyring.Codecs()
+	}
+	return &redisStore{RediStore: store, keyPrefix: `session_`}, nil
 }
 
 type redisStore struct {
 	*redistore.RediStore
+	keyPrefix string
+}
+
+func (s *redisStore) Get(ctx echo.Context, name string) (*sessions.Session, error) {
+	return sessions.GetRegistry(ctx).Get(s, name)
+}
+
+func (s *redisStore) New(ctx echo.Context, name string) (*sessions.Session, error) {
+	session, err := s.RediStore.New(ctx, name)
+	if err == nil && session != nil && !session.IsNew {
+		ss.CheckStale(ctx, name, new(string), s.Codecs...)
+	}
+	return session, err
+}
+
+// SetKeyPrefix set the prefix
+func (s *redisStore) SetKeyPrefix(p string) {
+	s.RediStore.SetKeyPrefix(p)
+	s.keyPrefix = p
+}
+
+// Remove 删除会话数据
+func (s *redisStore) Remove(sessionID string) error {
+	conn := s.RediStore.Pool.Get()
+	defer conn.Close()
+	_, err := conn.Do("DEL", s.keyPrefix+sessionID)
+	return err
 }
@@ -5,7 +5,10 @@
 package engine
 
 import (
+	"encoding/json"
 	"log"
+	"strconv"
+	"time"
 
 	"github.com/admpub/sessions"
 	"github.com/webx-top/echo"
@@ -15,12 +18,23 @@ const (
 	errorFormat = "[sessions] ERROR! %s\n"
 )
 
+// RefreshedKey 滑动过期时记录上次保存时间(Unix 纳秒时间戳)的会话键名。
+// 由框架内部维护，使用带前缀的名称以免与应用的键冲突
+var RefreshedKey = `_echo.session.refreshedAt`
+
+// Remover 可以按会话 ID 删除会话数据的存储引擎
+type Remover interface {
+	Remove(sessionID string) error
+}
+
 type Session struct {
-	name    string
-	context echo.Context
-	store   sessions.Store
-	session *sessions.Session
-	written bool
+	name      string
+	context   echo.Context
+	store     sessions.Store
+	session   *sessions.Session
+	written   bool
+	refreshed bool   // 本次请求中已经更新过 RefreshedKey
+	staleID   string // Regenerate 之前的会话 ID，新 ID 保存成功之后删除
 }
 
 func (s *Session) Get(key string) interface{} {
@@ -34,8 +48,11 @@ func (s *Session) Set(key string, val interface{}) echo.Sessioner {
 }
 
 func (s *Session) Delete(key string) echo.Sessioner {
-	delete(s.Session().Values, key)
-	s.written = true
+	values := s.Session().Values
+	if _, ok := values[key]; ok {
+		delete(values, key)
+		s.written = true
+	}
 	return s
 }
 
@@ -55,8 +72,11 @@ func (s *Session) AddFlash(value interface{}, vars ...string) echo.Sessioner {
 }
 
 func (s *Session) Flashes(vars ...string) []interface{} {
-	s.written = true
-	return s.Session().Flashes(vars...)
+	flashes := s.Session().Flashes(vars...)
+	if len(flashes) > 0 {
+		s.written = true
+	}
+	return flashes
 }
 
 func (s *Session) SetID(id string) echo.Sessioner {
@@ -69,18 +89,116 @@ func (s *Session) ID() string {
 }
 
 func (s *Session) Save() error {
+	s.slide()
 	if s.Written() {
 		e := s.Session().Save(s.context)
-		if e == nil {
-			s.written = false
-		} else {
+		if e != nil {
 			log.Printf(errorFormat, e)
+			return e
+		}
+		s.written = false
+		if len(s.staleID) > 0 {
+			e = s.remove(s.staleID)
+			s.staleID = ``
 		}
 		return e
 	}
 	return nil
 }
 
+// slide 启用滑动过期时记录保存时间，并在距离上次保存超过刷新间隔时标记为需要保存。
+// 只处理本次请求中已经加载的会话，每个请求最多因刷新而保存一次
+func (s *Session) slide() {
+	if s.session == nil {
+		return
+	}
+	interval := s.context.SessionOptions().RefreshInterval
+	if interval <= 0 {
+		return
+	}
+	now := time.Now()
+	if !s.written {
+		if s.session.IsNew || s.refreshed {
+			return
+		}
+		if now.Sub(time.Unix(0, refreshedAt(s.session.Values[RefreshedKey]))) < interval {
+			return
+		}
+		s.written = true
+	}
+	s.refreshed = true
+	s.session.Values[RefreshedKey] = now.UnixNano()
+}
+
+// refreshedAt 读取上次保存的时间。JSON 等编解码器会将整数解码为 float64 等其它数值类型
+func refreshedAt(v interface{}) int64 {
+	switch t := v.(type) {
+	case int64:
+		return t
+	case int:
+		return int64(t)
+	case int32:
+		return int64(t)
+	case uint64:
+		return int64(t)
+	case uint32:
+		return int64(t)
+	case float64:
+		return int64(t)
+	case float32:
+		return int64(t)
+	case json.Number:
+		n, _ := t.Int64()
+		return n
+	case string:
+		n, _ := strconv.ParseInt(t, 10, 64)
+		return n
+	}
+	return 0
+}
+
+// Regenerate 更换会话 ID，会话数据在 Save 时以新 ID 保存，保存成功之后删除旧 ID 的数据
+func (s *Session) Regenerate() error {
+	session := s.Session()
+	if len(s.staleID) == 0 {
+		s.staleID = session.ID
+	}
+	session.ID = ``
+	session.IsNew = true
+	s.written = true
+	return nil
+}
+
+// Destroy 删除会话数据并使 cookie 过期。之后再写入数据时会创建新的会话
+func (s *Session) Destroy() error {
+	session := s.Session()
+	err := s.remove(session.ID)
+	if len(s.staleID) > 0 {
+		if e := s.remove(s.staleID); e != nil && err == nil {
+			err = e
+		}
+		s.staleID = ``
+	}
+	for key := range session.Values {
+		delete(session.Values, key)
+	}
+	session.ID = ``
+	session.IsNew = true
+	s.written = false
+	sessions.SetCookie(s.context, s.name, ``, -1)
+	return err
+}
+
+func (s *Session) remove(sessionID string) error {
+	if len(sessionID) == 0 {
+		return nil
+	}
+	if remover, ok := s.store.(Remover); ok {
+		return remover.Remove(sessionID)
+	}
+	return nil
+}
+
 func (s *Session) Session() *sessions.Session {
 	if s.session == nil {
 		var err error
@@ -88,6 +206,10 @@ func (s *Session) Session() *sessions.Session {
 		if err != nil {
 			log.Printf(errorFormat, err)
 		}
+		// 使用旧密钥解码的会话需要以最新的密钥重新保存
+		if s.session != nil && !s.session.IsNew && IsStale(s.context, s.name) {
+			s.written = true
+		}
 	}
 	return s.session
 }
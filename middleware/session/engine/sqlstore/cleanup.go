@@ -1,19 +1,19 @@
-package mysql
+package sqlstore
 
 import (
 	"log"
 	"time"
 )
 
-var defaultInterval = time.Minute * 5
+var DefaultCleanupInterval = time.Minute * 5
 
 // Cleanup runs a background goroutine every interval that deletes expired
 // sessions from the database.
 //
 // The design is based on https://github.com/yosssi/boltstore
-func (m *MySQLStore) Cleanup(interval time.Duration) (chan<- struct{}, <-chan struct{}) {
+func (m *SQLStore) Cleanup(interval time.Duration) (chan<- struct{}, <-chan struct{}) {
 	if interval <= 0 {
-		interval = defaultInterval
+		interval = DefaultCleanupInterval
 	}
 
 	quit, done := make(chan struct{}), make(chan struct{})
@@ -22,13 +22,13 @@ func (m *MySQLStore) Cleanup(interval time.Duration) (chan<- struct{}, <-chan st
 }
 
 // StopCleanup stops the background cleanup from running.
-func (m *MySQLStore) StopCleanup(quit chan<- struct{}, done <-chan struct{}) {
+func (m *SQLStore) StopCleanup(quit chan<- struct{}, done <-chan struct{}) {
 	quit <- struct{}{}
 	<-done
 }
 
 // cleanup deletes expired sessions at set intervals.
-func (m *MySQLStore) cleanup(interval time.Duration, quit <-chan struct{}, done chan<- struct{}) {
+func (m *SQLStore) cleanup(interval time.Duration, quit <-chan struct{}, done chan<- struct{}) {
 	ticker := time.NewTicker(interval)
 
 	defer func() {
@@ -43,17 +43,10 @@ func (m *MySQLStore) cleanup(interval time.Duration, quit <-chan struct{}, done
 			return
 		case <-ticker.C:
 			// Delete expired sessions on each tick.
-			err := m.deleteExpired()
+			_, err := m.DeleteExpired()
 			if err != nil {
-				log.Printf("sessions: mysqlstore: unable to delete expired sessions: %v", err)
+				log.Printf("sessions: sqlstore: unable to delete expired sessions: %v", err)
 			}
 		}
 	}
 }
-
-// deleteExpired deletes expired sessions from the database.
-func (m *MySQLStore) deleteExpired() error {
-	var deleteStmt = "DELETE FROM " + m.table + " WHERE expires_on < NOW()"
-	_, err := m.db.Exec(deleteStmt)
-	return err
-}
@@ -0,0 +1,113 @@
+package sqlstore
+
+import (
+	"bytes"
+	"strconv"
+	"strings"
+)
+
+// Dialect 描述不同数据库之间的 SQL 差异。
+// DDL 中的 {table} 和 {index} 分别会被替换为表名和过期时间索引名
+type Dialect struct {
+	DDL          []string
+	UpsertSuffix string              // 紧跟在 INSERT INTO ... VALUES (...) 之后，用于在 id 冲突时更新数据
+	Quote        func(string) string // 引用表名或索引名
+	Rebind       func(string) string // 将 ? 占位符转换为数据库所需的格式(为 nil 时不转换)
+}
+
+var (
+	// MySQL 适用于 MySQL 和 MariaDB
+	MySQL = &Dialect{
+		DDL: []string{"CREATE TABLE IF NOT EXISTS {table} (" +
+			"	`id` char(64) NOT NULL," +
+			"	`data` longblob NOT NULL," +
+			"	`created` int(11) unsigned NOT NULL DEFAULT '0'," +
+			"	`modified` int(11) unsigned NOT NULL DEFAULT '0'," +
+			"	`expires` int(11) unsigned NOT NULL DEFAULT '0'," +
+			"	PRIMARY KEY (`id`)," +
+			"	KEY {index} (`expires`)" +
+			"  ) ENGINE=InnoDB;"},
+		UpsertSuffix: " ON DUPLICATE KEY UPDATE data = VALUES(data), modified = VALUES(modified), expires = VALUES(expires)",
+		Quote: func(name string) string {
+			return "`" + strings.Trim(name, "`") + "`"
+		},
+	}
+
+	// SQLite 需要 SQLite 3.24.0 及以上版本
+	SQLite = &Dialect{
+		DDL: []string{
+			`CREATE TABLE IF NOT EXISTS {table} (` +
+				`id VARCHAR(64) NOT NULL PRIMARY KEY,` +
+				`data TEXT NOT NULL,` +
+				`created INTEGER NOT NULL DEFAULT 0,` +
+				`modified INTEGER NOT NULL DEFAULT 0,` +
+				`expires INTEGER NOT NULL DEFAULT 0)`,
+			`CREATE INDEX IF NOT EXISTS {index} ON {table} (expires)`,
+		},
+		UpsertSuffix: ` ON CONFLICT (id) DO UPDATE SET data = excluded.data, modified = excluded.modified, expires = excluded.expires`,
+		Quote:        quoteIdent,
+	}
+
+	// PostgreSQL 需要 PostgreSQL 9.5 及以上版本
+	PostgreSQL = &Dialect{
+		DDL: []string{
+			`CREATE TABLE IF NOT EXISTS {table} (` +
+				`id VARCHAR(64) NOT NULL PRIMARY KEY,` +
+				`data TEXT NOT NULL,` +
+				`created BIGINT NOT NULL DEFAULT 0,` +
+				`modified BIGINT NOT NULL DEFAULT 0,` +
+				`expires BIGINT NOT NULL DEFAULT 0)`,
+			`CREATE INDEX IF NOT EXISTS {index} ON {table} (expires)`,
+		},
+		UpsertSuffix: SQLite.UpsertSuffix,
+		Quote:        quoteIdent,
+		Rebind:       rebindDollar,
+	}
+
+	// Dialects 数据库引擎名(同时也是 database/sql 驱动名)与方言的对应关系
+	Dialects = map[string]*Dialect{
+		`mysql`:    MySQL,
+		`sqlite3`:  SQLite,
+		`sqlite`:   SQLite,
+		`postgres`: PostgreSQL,
+		`pgx`:      PostgreSQL,
+	}
+)
+
+// RegisterDialect 注册方言
+func RegisterDialect(engine string, dialect *Dialect) {
+	Dialects[engine] = dialect
+}
+
+// GetDialect 获取方言
+func GetDialect(engine string) *Dialect {
+	return Dialects[engine]
+}
+
+// Bind 将查询语句中的 ? 占位符转换为数据库所需的格式
+func (d *Dialect) Bind(query string) string {
+	if d.Rebind == nil {
+		return query
+	}
+	return d.Rebind(query)
+}
+
+func quoteIdent(name string) string {
+	return `"` + strings.Replace(strings.Trim(name, `"`), `"`, `""`, -1) + `"`
+}
+
+// rebindDollar 将 ? 转换为 $1、$2 ...
+func rebindDollar(query string) string {
+	var b bytes.Buffer
+	var n int
+	for _, r := range query {
+		if r != '?' {
+			b.WriteRune(r)
+			continue
+		}
+		n++
+		b.WriteByte('$')
+		b.WriteString(strconv.Itoa(n))
+	}
+	return b.String()
+}
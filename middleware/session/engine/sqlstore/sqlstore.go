@@ -0,0 +1,272 @@
+package sqlstore
+
+import (
+	"database/sql"
+	"encoding/base32"
+	"strings"
+	"time"
+
+	"github.com/admpub/errors"
+	"github.com/admpub/securecookie"
+	"github.com/admpub/sessions"
+	"github.com/webx-top/echo"
+	"github.com/webx-top/echo/encoding/dbconfig"
+	"github.com/webx-top/echo/encoding/keyring"
+	ss "github.com/webx-top/echo/middleware/session/engine"
+)
+
+var (
+	DefaultMaxAge = 86400
+	DefaultTable  = `sessions`
+)
+
+func New(opts *Options) sessions.Store {
+	store, err := NewSQLStore(opts)
+	if err != nil {
+		panic(err.Error())
+	}
+	return store
+}
+
+func Reg(store sessions.Store, args ...string) {
+	name := `sql`
+	if len(args) > 0 {
+		name = args[0]
+	}
+	ss.Reg(name, store)
+}
+
+func RegWithOptions(opts *Options, args ...string) sessions.Store {
+	store := New(opts)
+	Reg(store, args...)
+	return store
+}
+
+// Options 的 Config.Engine 既用于选择方言，也作为 database/sql 的驱动名，
+// 驱动需要自行导入(例如 _ "github.com/mattn/go-sqlite3")
+type Options struct {
+	Config          dbconfig.Config  `json:"config"`
+	Table           string           `json:"table"`
+	KeyPairs        [][]byte         `json:"keyPairs"`
+	Keyring         *keyring.Keyring `json:"-"`               // 设置后代替 KeyPairs，用于密钥轮换
+	CleanupInterval time.Duration    `json:"cleanupInterval"` // 清理过期会话的间隔(<0 时不启动后台清理)
+}
+
+// SQLStore 基于 database/sql 的会话存储
+type SQLStore struct {
+	db         *sql.DB
+	dialect    *Dialect
+	stmtUpsert *sql.Stmt
+	stmtDelete *sql.Stmt
+	stmtSelect *sql.Stmt
+	stmtExpire *sql.Stmt
+
+	Codecs []securecookie.Codec
+	table  string
+
+	quit chan<- struct{}
+	done <-chan struct{}
+}
+
+// NewSQLStore 根据 opts.Config 连接数据库并创建会话存储
+func NewSQLStore(opts *Options) (*SQLStore, error) {
+	dialect := GetDialect(opts.Config.Engine)
+	if dialect == nil {
+		return nil, errors.New(`sessions: unsupported sql engine: ` + opts.Config.Engine)
+	}
+	db, err := sql.Open(opts.Config.Engine, opts.Config.String())
+	if err != nil {
+		return nil, err
+	}
+	codecs := securecookie.CodecsFromPairs(opts.KeyPairs...)
+	if opts.Keyring != nil {
+		codecs = opts.Keyring.Codecs()
+	}
+	store, err := newSQLStore(db, dialect, opts.Table, codecs)
+	if err != nil {
+		db.Close()
+		return nil, err
+	}
+	if opts.CleanupInterval >= 0 {
+		store.quit, store.done = store.Cleanup(opts.CleanupInterval)
+	}
+	return store, nil
+}
+
+// NewSQLStoreFromConnection 使用已有的数据库连接创建会话存储，数据表不存在时会自动创建
+func NewSQLStoreFromConnection(db *sql.DB, dialect *Dialect, tableName string, keyPairs ...[]byte) (*SQLStore, error) {
+	return newSQLStore(db, dialect, tableName, securecookie.CodecsFromPairs(keyPairs...))
+}
+
+func newSQLStore(db *sql.DB, dialect *Dialect, tableName string, codecs []securecookie.Codec) (*SQLStore, error) {
+	if len(codecs) == 0 {
+		return nil, errors.New(`sessions: key pairs are required`)
+	}
+	if len(tableName) == 0 {
+		tableName = DefaultTable
+	}
+	table := dialect.Quote(tableName)
+	replacer := strings.NewReplacer(`{table}`, table, `{index}`, dialect.Quote(tableName+`_expires`))
+	for _, ddl := range dialect.DDL {
+		ddl = replacer.Replace(ddl)
+		if _, err := db.Exec(ddl); err != nil {
+			// 没有建表权限时，只要数据表已经存在就可以继续使用
+			if _, probeErr := db.Exec(`SELECT id FROM ` + table + ` WHERE 1 = 0`); probeErr != nil {
+				return nil, errors.Wrap(err, ddl)
+			}
+			break
+		}
+	}
+	m := &SQLStore{
+		db:      db,
+		dialect: dialect,
+		Codecs:  codecs,
+		table:   table,
+	}
+	queries := []struct {
+		stmt  **sql.Stmt
+		query string
+	}{
+		{&m.stmtUpsert, "INSERT INTO " + table + " (id, data, created, modified, expires) VALUES (?, ?, ?, ?, ?)" + dialect.UpsertSuffix},
+		{&m.stmtDelete, "DELETE FROM " + table + " WHERE id = ?"},
+		{&m.stmtSelect, "SELECT data, expires FROM " + table + " WHERE id = ?"},
+		{&m.stmtExpire, "DELETE FROM " + table + " WHERE expires < ?"},
+	}
+	for _, q := range queries {
+		query := dialect.Bind(q.query)
+		stmt, err := db.Prepare(query)
+		if err != nil {
+			m.closeStmts()
+			return nil, errors.Wrap(err, query)
+		}
+		*q.stmt = stmt
+	}
+	return m, nil
+}
+
+// DB 返回数据库连接
+func (m *SQLStore) DB() *sql.DB {
+	return m.db
+}
+
+// Close 停止后台清理并关闭数据库连接
+func (m *SQLStore) Close() error {
+	if m.quit != nil {
+		m.StopCleanup(m.quit, m.done)
+		m.quit = nil
+	}
+	m.closeStmts()
+	return m.db.Close()
+}
+
+func (m *SQLStore) closeStmts() {
+	for _, stmt := range []*sql.Stmt{m.stmtUpsert, m.stmtDelete, m.stmtSelect, m.stmtExpire} {
+		if stmt != nil {
+			stmt.Close()
+		}
+	}
+}
+
+func (m *SQLStore) Get(ctx echo.Context, name string) (*sessions.Session, error) {
+	return sessions.GetRegistry(ctx).Get(m, name)
+}
+
+func (m *SQLStore) New(ctx echo.Context, name string) (*sessions.Session, error) {
+	session := sessions.NewSession(m, name)
+	session.IsNew = true
+	value := ctx.GetCookie(name)
+	if len(value) == 0 {
+		return session, nil
+	}
+	err := ss.DecodeMulti(ctx, name, value, &session.ID, m.Codecs...)
+	if err != nil {
+		return session, err
+	}
+	if m.load(session) == nil {
+		session.IsNew = false
+	}
+	return session, nil
+}
+
+func (m *SQLStore) Save(ctx echo.Context, session *sessions.Session) error {
+	// Delete if max-age is < 0
+	if ctx.CookieOptions().MaxAge < 0 {
+		return m.Delete(ctx, session)
+	}
+	if len(session.ID) == 0 {
+		// generate random session ID key suitable for storage in the db
+		session.ID = strings.TrimRight(
+			base32.StdEncoding.EncodeToString(
+				securecookie.GenerateRandomKey(32)), "=")
+	}
+	if err := m.save(ctx, session); err != nil {
+		return err
+	}
+	encoded, err := securecookie.EncodeMulti(session.Name(), session.ID, m.Codecs...)
+	if err != nil {
+		return err
+	}
+	sessions.SetCookie(ctx, session.Name(), encoded)
+	return nil
+}
+
+func (m *SQLStore) Delete(ctx echo.Context, session *sessions.Session) error {
+	sessions.SetCookie(ctx, session.Name(), ``, -1)
+	// Clear session values.
+	for k := range session.Values {
+		delete(session.Values, k)
+	}
+	if len(session.ID) == 0 {
+		return nil
+	}
+	return m.Remove(session.ID)
+}
+
+// Remove 删除会话数据
+func (m *SQLStore) Remove(sessionID string) error {
+	_, err := m.stmtDelete.Exec(sessionID)
+	return err
+}
+
+// DeleteExpired 删除所有过期的会话，返回删除的数量
+func (m *SQLStore) DeleteExpired() (int64, error) {
+	result, err := m.stmtExpire.Exec(time.Now().Unix())
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
+func (m *SQLStore) maxAge(ctx echo.Context) int {
+	maxAge := ctx.CookieOptions().MaxAge
+	if maxAge == 0 {
+		maxAge = DefaultMaxAge
+	}
+	return maxAge
+}
+
+// save 插入或更新会话数据，更新时保留原有的创建时间
+func (m *SQLStore) save(ctx echo.Context, session *sessions.Session) error {
+	encoded, err := securecookie.EncodeMulti(session.Name(), session.Values, m.Codecs...)
+	if err != nil {
+		return err
+	}
+	now := time.Now().Unix()
+	expires := now + int64(m.maxAge(ctx))
+	_, err = m.stmtUpsert.Exec(session.ID, encoded, now, now, expires)
+	return err
+}
+
+func (m *SQLStore) load(session *sessions.Session) error {
+	var (
+		data    string
+		expires int64
+	)
+	if err := m.stmtSelect.QueryRow(session.ID).Scan(&data, &expires); err != nil {
+		return err
+	}
+	if expires < time.Now().Unix() {
+		return errors.New(`Session expired`)
+	}
+	return securecookie.DecodeMulti(session.Name(), data, &session.Values, m.Codecs...)
+}
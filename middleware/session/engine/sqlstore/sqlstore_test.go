@@ -0,0 +1,95 @@
+package sqlstore_test
+
+import (
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	_ "github.com/mattn/go-sqlite3"
+	"github.com/stretchr/testify/assert"
+	"github.com/webx-top/echo"
+	"github.com/webx-top/echo/encoding/dbconfig"
+	"github.com/webx-top/echo/middleware/session"
+	"github.com/webx-top/echo/middleware/session/engine/sqlstore"
+	test "github.com/webx-top/echo/testing"
+)
+
+func TestSQLiteStore(t *testing.T) {
+	dir, err := ioutil.TempDir(``, `echo-session-sql`)
+	assert.NoError(t, err)
+	defer os.RemoveAll(dir)
+	store, err := sqlstore.NewSQLStore(&sqlstore.Options{
+		Config: dbconfig.Config{
+			Engine:  `sqlite3`,
+			Name:    filepath.Join(dir, `session.db`),
+			Options: map[string]string{`_busy_timeout`: `5000`},
+		},
+		KeyPairs:        [][]byte{[]byte(`12345678901234567890123456789012`)},
+		CleanupInterval: -1,
+	})
+	if !assert.NoError(t, err) {
+		return
+	}
+	defer store.Close()
+
+	// 数据表已存在时可以重复创建
+	_, err = sqlstore.NewSQLStoreFromConnection(store.DB(), sqlstore.SQLite, sqlstore.DefaultTable, []byte(`key`))
+	assert.NoError(t, err)
+
+	e := echo.New()
+	e.Use(session.Sessions(echo.NewSessionOptions(`sql`, `SID`), store))
+	e.Get(`/set/:v`, func(ctx echo.Context) error {
+		ctx.Session().Set(`v`, ctx.Param(`v`))
+		return ctx.String(`ok`)
+	})
+	e.Get(`/get`, func(ctx echo.Context) error {
+		return ctx.String(fmt.Sprintf(`%v`, ctx.Session().Get(`v`)))
+	})
+	e.Get(`/id`, func(ctx echo.Context) error {
+		return ctx.String(ctx.Session().ID())
+	})
+	e.Get(`/logout`, func(ctx echo.Context) error {
+		return ctx.Session().Destroy()
+	})
+	e.RebuildRouter()
+	withCookie := func(rec http.Header) func(*http.Request) {
+		return func(req *http.Request) {
+			for _, h := range rec["Set-Cookie"] {
+				req.Header.Add(`Cookie`, h)
+			}
+		}
+	}
+	count := func() (n int) {
+		store.DB().QueryRow(`SELECT COUNT(*) FROM ` + sqlstore.DefaultTable).Scan(&n)
+		return
+	}
+
+	a := test.Request(echo.GET, `/set/a`, e)
+	assert.Equal(t, 1, count())
+	rec := test.Request(echo.GET, `/get`, e, withCookie(a.Header()))
+	assert.Equal(t, `a`, rec.Body.String())
+	test.Request(echo.GET, `/set/b`, e, withCookie(a.Header()))
+	rec = test.Request(echo.GET, `/get`, e, withCookie(a.Header()))
+	assert.Equal(t, `b`, rec.Body.String())
+	assert.Equal(t, 1, count())
+
+	// 过期清理
+	id := test.Request(echo.GET, `/id`, e, withCookie(a.Header())).Body.String()
+	_, err = store.DB().Exec(`UPDATE `+sqlstore.DefaultTable+` SET expires = ? WHERE id = ?`, time.Now().Unix()-1, id)
+	assert.NoError(t, err)
+	rec = test.Request(echo.GET, `/get`, e, withCookie(a.Header()))
+	assert.Equal(t, `<nil>`, rec.Body.String())
+	n, err := store.DeleteExpired()
+	assert.NoError(t, err)
+	assert.Equal(t, int64(1), n)
+	assert.Equal(t, 0, count())
+
+	b := test.Request(echo.GET, `/set/c`, e)
+	assert.Equal(t, 1, count())
+	test.Request(echo.GET, `/logout`, e, withCookie(b.Header()))
+	assert.Equal(t, 0, count())
+}
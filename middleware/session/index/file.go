@@ -0,0 +1,125 @@
+package index
+
+import (
+	"crypto/sha1"
+	"encoding/hex"
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sync"
+)
+
+// NewFileBackend 创建文件索引，每个用户的会话信息保存在 dir 下的一个 JSON 文件中。
+// 适合与 file、bolt 等单机存储引擎配合使用
+func NewFileBackend(dir string) (*FileBackend, error) {
+	if len(dir) == 0 {
+		dir = filepath.Join(os.TempDir(), `session_index`)
+	}
+	if err := os.MkdirAll(dir, 0700); err != nil {
+		return nil, err
+	}
+	return &FileBackend{dir: dir}, nil
+}
+
+// FileBackend 文件索引，在同一进程内可安全地并发使用
+type FileBackend struct {
+	mutex sync.RWMutex
+	dir   string
+}
+
+func (f *FileBackend) Save(info *Info) error {
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+	sessions, err := f.read(info.UserID)
+	if err != nil {
+		return err
+	}
+	sessions[info.SessionID] = info
+	return f.write(info.UserID, sessions)
+}
+
+func (f *FileBackend) Get(userID string, sessionID string) (*Info, error) {
+	f.mutex.RLock()
+	defer f.mutex.RUnlock()
+	sessions, err := f.read(userID)
+	if err != nil {
+		return nil, err
+	}
+	return sessions[sessionID], nil
+}
+
+func (f *FileBackend) List(userID string) ([]*Info, error) {
+	f.mutex.RLock()
+	defer f.mutex.RUnlock()
+	sessions, err := f.read(userID)
+	if err != nil {
+		return nil, err
+	}
+	list := make([]*Info, 0, len(sessions))
+	for _, info := range sessions {
+		list = append(list, info)
+	}
+	return list, nil
+}
+
+func (f *FileBackend) Delete(userID string, sessionIDs ...string) error {
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+	sessions, err := f.read(userID)
+	if err != nil {
+		return err
+	}
+	for _, sessionID := range sessionIDs {
+		delete(sessions, sessionID)
+	}
+	return f.write(userID, sessions)
+}
+
+func (f *FileBackend) filename(userID string) string {
+	sum := sha1.Sum([]byte(userID))
+	return filepath.Join(f.dir, hex.EncodeToString(sum[:])+`.json`)
+}
+
+func (f *FileBackend) read(userID string) (map[string]*Info, error) {
+	sessions := map[string]*Info{}
+	b, err := ioutil.ReadFile(f.filename(userID))
+	if err != nil {
+		if os.IsNotExist(err) {
+			return sessions, nil
+		}
+		return nil, err
+	}
+	err = json.Unmarshal(b, &sessions)
+	return sessions, err
+}
+
+func (f *FileBackend) write(userID string, sessions map[string]*Info) error {
+	filename := f.filename(userID)
+	if len(sessions) == 0 {
+		err := os.Remove(filename)
+		if err != nil && !os.IsNotExist(err) {
+			return err
+		}
+		return nil
+	}
+	b, err := json.Marshal(sessions)
+	if err != nil {
+		return err
+	}
+	// 先写入临时文件再重命名，避免读到不完整的内容
+	tmp, err := ioutil.TempFile(f.dir, `.tmp_`)
+	if err != nil {
+		return err
+	}
+	if _, err = tmp.Write(b); err != nil {
+		tmp.Close()
+		os.Remove(tmp.Name())
+		return err
+	}
+	if err = tmp.Close(); err != nil {
+		os.Remove(tmp.Name())
+		return err
+	}
+	return os.Rename(tmp.Name(), filename)
+}
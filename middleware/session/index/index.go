@@ -0,0 +1,263 @@
+// Package index 为会话建立按用户的索引，用于列出用户的所有会话(设备)，
+// 以及注销单个会话或用户的所有会话。可以与任意会话存储引擎配合使用
+package index
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/admpub/sessions"
+	"github.com/webx-top/echo"
+	"github.com/webx-top/echo/middleware/session/engine"
+)
+
+var (
+	DefaultUserKey       = `__uid`
+	DefaultTouchInterval = time.Minute
+	DefaultMaxAge        = 24 * time.Hour
+
+	ErrNoBackend = errors.New(`session index: backend is not set`)
+)
+
+// trackedKey 本次请求中调用过 Track 时，在 Context.Internal() 中记录的键名
+const trackedKey = `__sessionIndexTracked`
+
+// Info 会话信息
+type Info struct {
+	SessionID string    `json:"sessionId"`
+	UserID    string    `json:"userId"`
+	Created   time.Time `json:"created"`
+	LastSeen  time.Time `json:"lastSeen"`
+	IP        string    `json:"ip"`
+	UserAgent string    `json:"userAgent"`
+}
+
+// Backend 保存会话索引
+type Backend interface {
+	// Save 新增或更新会话信息
+	Save(info *Info) error
+	// Get 获取会话信息，不存在时返回 nil, nil
+	Get(userID string, sessionID string) (*Info, error)
+	// List 列出用户的所有会话
+	List(userID string) ([]*Info, error)
+	// Delete 删除用户的指定会话
+	Delete(userID string, sessionIDs ...string) error
+}
+
+type Options struct {
+	// Backend 必须与会话存储一样持久并在所有进程间共享：已登录的会话在索引中不存在时会被销毁，
+	// 使用内存索引时重启或多进程部署会使用户被注销
+	Backend Backend
+	// Store 用于注销时删除会话数据(需实现 engine.Remover)，为 nil 时只删除索引。
+	// 即使存储引擎不支持删除(例如 cookie)，中间件也会销毁索引中不存在的已登录会话
+	Store         sessions.Store
+	UserKey       string        // 会话中保存用户标识的键名
+	TouchInterval time.Duration // 更新最后访问时间的最小间隔
+	MaxAge        time.Duration // 超过此时长未访问的会话视为已过期，一般与会话的有效期一致
+}
+
+// Index 会话索引
+type Index struct {
+	backend       Backend
+	store         sessions.Store
+	userKey       string
+	touchInterval time.Duration
+	maxAge        time.Duration
+}
+
+// New 创建会话索引，必须指定 Backend
+func New(opts *Options) (*Index, error) {
+	if opts == nil || opts.Backend == nil {
+		return nil, ErrNoBackend
+	}
+	idx := &Index{
+		backend:       opts.Backend,
+		store:         opts.Store,
+		userKey:       opts.UserKey,
+		touchInterval: opts.TouchInterval,
+		maxAge:        opts.MaxAge,
+	}
+	if len(idx.userKey) == 0 {
+		idx.userKey = DefaultUserKey
+	}
+	if idx.touchInterval <= 0 {
+		idx.touchInterval = DefaultTouchInterval
+	}
+	if idx.maxAge <= 0 {
+		idx.maxAge = DefaultMaxAge
+	}
+	return idx, nil
+}
+
+// Backend 返回索引的存储
+func (idx *Index) Backend() Backend {
+	return idx.backend
+}
+
+// Track 将当前会话关联到用户(通常在登录成功并调用 Session().Regenerate() 之后调用)。
+// 会话 ID 在保存时才会生成，所以索引在请求结束时由中间件写入
+func (idx *Index) Track(c echo.Context, userID string) {
+	c.Session().Set(idx.userKey, userID)
+	c.Internal().Set(trackedKey, true)
+}
+
+// UserID 返回当前会话关联的用户
+func (idx *Index) UserID(c echo.Context) string {
+	userID, _ := c.Session().Get(idx.userKey).(string)
+	return userID
+}
+
+// List 列出用户的所有未过期会话，按最后访问时间倒序排列
+func (idx *Index) List(userID string) ([]*Info, error) {
+	list, err := idx.backend.List(userID)
+	if err != nil {
+		return nil, err
+	}
+	deadline := time.Now().Add(-idx.maxAge - idx.touchInterval)
+	var expired []string
+	active := list[:0]
+	for _, info := range list {
+		if info.LastSeen.Before(deadline) {
+			expired = append(expired, info.SessionID)
+			continue
+		}
+		active = append(active, info)
+	}
+	if len(expired) > 0 {
+		if err = idx.backend.Delete(userID, expired...); err != nil {
+			return nil, err
+		}
+	}
+	sort.Slice(active, func(i, j int) bool {
+		return active[i].LastSeen.After(active[j].LastSeen)
+	})
+	return active, nil
+}
+
+// Revoke 注销用户的指定会话
+func (idx *Index) Revoke(userID string, sessionIDs ...string) error {
+	if len(sessionIDs) == 0 {
+		return nil
+	}
+	if err := idx.backend.Delete(userID, sessionIDs...); err != nil {
+		return err
+	}
+	remover, ok := idx.store.(engine.Remover)
+	if !ok {
+		return nil
+	}
+	for _, sessionID := range sessionIDs {
+		if err := remover.Remove(sessionID); err != nil {
+			return fmt.Errorf(`session index: failed to remove session %s: %v`, sessionID, err)
+		}
+	}
+	return nil
+}
+
+// RevokeAll 注销用户的所有会话，except 中的会话除外(例如"退出其它设备"时传入当前会话 ID)
+func (idx *Index) RevokeAll(userID string, except ...string) error {
+	list, err := idx.backend.List(userID)
+	if err != nil {
+		return err
+	}
+	sessionIDs := make([]string, 0, len(list))
+	for _, info := range list {
+		if !inSlice(info.SessionID, except) {
+			sessionIDs = append(sessionIDs, info.SessionID)
+		}
+	}
+	return idx.Revoke(userID, sessionIDs...)
+}
+
+// Middleware 维护会话索引，需要放在 session 中间件之后。
+// 已登录的会话在索引中不存在时(已被注销)会被销毁
+func (idx *Index) Middleware() echo.MiddlewareFuncd {
+	return func(h echo.Handler) echo.HandlerFunc {
+		return func(c echo.Context) error {
+			sess := c.Session()
+			sessionID := sess.ID()
+			userID := idx.UserID(c)
+			var info *Info
+			if len(userID) > 0 && len(sessionID) > 0 {
+				var err error
+				info, err = idx.backend.Get(userID, sessionID)
+				if err != nil {
+					return err
+				}
+				if info == nil {
+					if err = sess.Destroy(); err != nil {
+						c.Logger().Error(err)
+					}
+					userID = ``
+				}
+			}
+			err := h.Handle(c)
+			if e := idx.update(c, userID, sessionID, info); e != nil {
+				c.Logger().Error(e)
+			}
+			return err
+		}
+	}
+}
+
+// update 在请求结束时同步索引：新登录、会话 ID 变更、退出登录以及更新最后访问时间
+func (idx *Index) update(c echo.Context, oldUserID string, oldSessionID string, info *Info) error {
+	tracked, _ := c.Internal().Get(trackedKey).(bool)
+	userID := idx.UserID(c)
+	if len(userID) == 0 && len(oldUserID) == 0 {
+		return nil
+	}
+	sess := c.Session()
+	if len(oldUserID) > 0 && oldUserID == userID && !tracked {
+		// 会话可能在请求处理期间被注销，此时不再保存，以免重新写入会话数据和索引
+		current, err := idx.backend.Get(oldUserID, oldSessionID)
+		if err != nil {
+			return err
+		}
+		if current == nil {
+			return sess.Destroy()
+		}
+		info = current
+	}
+	// 确保新会话已经生成 ID
+	if err := sess.Save(); err != nil {
+		return err
+	}
+	sessionID := sess.ID()
+	if len(oldUserID) > 0 && (oldUserID != userID || oldSessionID != sessionID) {
+		if err := idx.backend.Delete(oldUserID, oldSessionID); err != nil {
+			return err
+		}
+		if oldUserID != userID {
+			info = nil
+		}
+	}
+	if len(userID) == 0 || len(sessionID) == 0 {
+		return nil
+	}
+	now := time.Now()
+	if info == nil || tracked || info.SessionID != sessionID {
+		created := now
+		if info != nil && !tracked {
+			created = info.Created
+		}
+		info = &Info{SessionID: sessionID, UserID: userID, Created: created}
+	} else if now.Sub(info.LastSeen) < idx.touchInterval {
+		return nil
+	}
+	info.LastSeen = now
+	info.IP = c.RealIP()
+	info.UserAgent = c.Request().UserAgent()
+	return idx.backend.Save(info)
+}
+
+func inSlice(v string, items []string) bool {
+	for _, item := range items {
+		if item == v {
+			return true
+		}
+	}
+	return false
+}
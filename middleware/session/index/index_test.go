@@ -0,0 +1,167 @@
+package index_test
+
+import (
+	"database/sql"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	_ "github.com/mattn/go-sqlite3"
+	"github.com/stretchr/testify/assert"
+	"github.com/webx-top/echo"
+	"github.com/webx-top/echo/middleware/session"
+	"github.com/webx-top/echo/middleware/session/engine/memory"
+	"github.com/webx-top/echo/middleware/session/engine/sqlstore"
+	"github.com/webx-top/echo/middleware/session/index"
+	test "github.com/webx-top/echo/testing"
+)
+
+func TestIndex(t *testing.T) {
+	store := memory.NewMemoryStore(&memory.MemoryOptions{CleanupInterval: -1})
+	defer store.Close()
+	idx, err := index.New(&index.Options{Backend: index.NewMemoryBackend(), Store: store})
+	assert.NoError(t, err)
+	e := echo.New()
+	e.Use(session.Sessions(echo.NewSessionOptions(`memory`, `SID`), store))
+	e.Use(idx.Middleware())
+	e.Get(`/login`, func(c echo.Context) error {
+		if err := c.Session().Regenerate(); err != nil {
+			return err
+		}
+		idx.Track(c, `u1`)
+		return c.String(`ok`)
+	})
+	e.Get(`/whoami`, func(c echo.Context) error {
+		return c.String(idx.UserID(c))
+	})
+	e.Get(`/id`, func(c echo.Context) error {
+		return c.String(c.Session().ID())
+	})
+	e.Get(`/logout-others`, func(c echo.Context) error {
+		return idx.RevokeAll(idx.UserID(c), c.Session().ID())
+	})
+	e.Get(`/logout`, func(c echo.Context) error {
+		return c.Session().Destroy()
+	})
+	e.Get(`/revoke-self`, func(c echo.Context) error {
+		if err := idx.Revoke(idx.UserID(c), c.Session().ID()); err != nil {
+			return err
+		}
+		c.Session().Set(`after`, `revoked`)
+		return c.String(`ok`)
+	})
+	e.RebuildRouter()
+	device := func(ua string, rec http.Header) func(*http.Request) {
+		return func(req *http.Request) {
+			req.Header.Set(`User-Agent`, ua)
+			if rec == nil {
+				return
+			}
+			for _, h := range rec["Set-Cookie"] {
+				req.Header.Add(`Cookie`, h)
+			}
+		}
+	}
+
+	a := test.Request(echo.GET, `/login`, e, device(`A`, nil)).Header()
+	b := test.Request(echo.GET, `/login`, e, device(`B`, nil)).Header()
+	idA := test.Request(echo.GET, `/id`, e, device(`A`, a)).Body.String()
+	idB := test.Request(echo.GET, `/id`, e, device(`B`, b)).Body.String()
+	assert.NotEqual(t, idA, idB)
+
+	list, err := idx.List(`u1`)
+	assert.NoError(t, err)
+	if assert.Len(t, list, 2) {
+		agents := map[string]string{list[0].SessionID: list[0].UserAgent, list[1].SessionID: list[1].UserAgent}
+		assert.Equal(t, map[string]string{idA: `A`, idB: `B`}, agents)
+	}
+
+	// 退出其它设备
+	test.Request(echo.GET, `/logout-others`, e, device(`A`, a))
+	assert.Equal(t, `u1`, test.Request(echo.GET, `/whoami`, e, device(`A`, a)).Body.String())
+	assert.Equal(t, ``, test.Request(echo.GET, `/whoami`, e, device(`B`, b)).Body.String())
+	_, ok := store.Values(idB)
+	assert.False(t, ok)
+	list, _ = idx.List(`u1`)
+	assert.Len(t, list, 1)
+
+	// 重新登录后旧 ID 的索引被替换
+	a = test.Request(echo.GET, `/login`, e, device(`A`, a)).Header()
+	list, _ = idx.List(`u1`)
+	if assert.Len(t, list, 1) {
+		assert.NotEqual(t, idA, list[0].SessionID)
+	}
+
+	test.Request(echo.GET, `/logout`, e, device(`A`, a))
+	list, _ = idx.List(`u1`)
+	assert.Len(t, list, 0)
+
+	// 请求处理期间被注销的会话不会在请求结束时被重新保存
+	a = test.Request(echo.GET, `/login`, e, device(`A`, nil)).Header()
+	idA = test.Request(echo.GET, `/id`, e, device(`A`, a)).Body.String()
+	test.Request(echo.GET, `/revoke-self`, e, device(`A`, a))
+	_, ok = store.Values(idA)
+	assert.False(t, ok)
+	list, _ = idx.List(`u1`)
+	assert.Len(t, list, 0)
+	assert.Equal(t, ``, test.Request(echo.GET, `/whoami`, e, device(`A`, a)).Body.String())
+
+	_, err = index.New(&index.Options{Store: store})
+	assert.Equal(t, index.ErrNoBackend, err)
+}
+
+func testBackend(t *testing.T, backend index.Backend) {
+	now := time.Unix(time.Now().Unix(), 0)
+	assert.NoError(t, backend.Save(&index.Info{SessionID: `s1`, UserID: `u1`, Created: now, LastSeen: now, IP: `127.0.0.1`, UserAgent: `A`}))
+	assert.NoError(t, backend.Save(&index.Info{SessionID: `s2`, UserID: `u1`, Created: now, LastSeen: now}))
+	assert.NoError(t, backend.Save(&index.Info{SessionID: `s3`, UserID: `u2`, Created: now, LastSeen: now}))
+	info, err := backend.Get(`u1`, `s1`)
+	assert.NoError(t, err)
+	if assert.NotNil(t, info) {
+		assert.Equal(t, `A`, info.UserAgent)
+		assert.True(t, now.Equal(info.LastSeen))
+	}
+	info.UserAgent = `B`
+	assert.NoError(t, backend.Save(info))
+	info, _ = backend.Get(`u1`, `s1`)
+	assert.Equal(t, `B`, info.UserAgent)
+	info, err = backend.Get(`u2`, `s1`)
+	assert.NoError(t, err)
+	assert.Nil(t, info)
+	list, err := backend.List(`u1`)
+	assert.NoError(t, err)
+	assert.Len(t, list, 2)
+	assert.NoError(t, backend.Delete(`u1`, `s1`, `s2`))
+	list, _ = backend.List(`u1`)
+	assert.Len(t, list, 0)
+	list, _ = backend.List(`u2`)
+	assert.Len(t, list, 1)
+}
+
+func TestMemoryBackend(t *testing.T) {
+	testBackend(t, index.NewMemoryBackend())
+}
+
+func TestFileBackend(t *testing.T) {
+	dir, err := ioutil.TempDir(``, `echo-session-index`)
+	assert.NoError(t, err)
+	defer os.RemoveAll(dir)
+	backend, err := index.NewFileBackend(dir)
+	assert.NoError(t, err)
+	testBackend(t, backend)
+}
+
+func TestSQLBackend(t *testing.T) {
+	dir, err := ioutil.TempDir(``, `echo-session-index`)
+	assert.NoError(t, err)
+	defer os.RemoveAll(dir)
+	db, err := sql.Open(`sqlite3`, filepath.Join(dir, `index.db`))
+	assert.NoError(t, err)
+	defer db.Close()
+	backend, err := index.NewSQLBackend(db, sqlstore.SQLite, ``)
+	assert.NoError(t, err)
+	testBackend(t, backend)
+}
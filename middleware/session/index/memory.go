@@ -0,0 +1,64 @@
+package index
+
+import "sync"
+
+// NewMemoryBackend 创建内存索引，只适用于单个进程
+func NewMemoryBackend() *MemoryBackend {
+	return &MemoryBackend{users: map[string]map[string]Info{}}
+}
+
+// MemoryBackend 内存索引，可安全地并发使用
+type MemoryBackend struct {
+	mutex sync.RWMutex
+	users map[string]map[string]Info
+}
+
+func (m *MemoryBackend) Save(info *Info) error {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+	sessions, ok := m.users[info.UserID]
+	if !ok {
+		sessions = map[string]Info{}
+		m.users[info.UserID] = sessions
+	}
+	sessions[info.SessionID] = *info
+	return nil
+}
+
+func (m *MemoryBackend) Get(userID string, sessionID string) (*Info, error) {
+	m.mutex.RLock()
+	defer m.mutex.RUnlock()
+	info, ok := m.users[userID][sessionID]
+	if !ok {
+		return nil, nil
+	}
+	return &info, nil
+}
+
+func (m *MemoryBackend) List(userID string) ([]*Info, error) {
+	m.mutex.RLock()
+	defer m.mutex.RUnlock()
+	sessions := m.users[userID]
+	list := make([]*Info, 0, len(sessions))
+	for _, info := range sessions {
+		info := info
+		list = append(list, &info)
+	}
+	return list, nil
+}
+
+func (m *MemoryBackend) Delete(userID string, sessionIDs ...string) error {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+	sessions, ok := m.users[userID]
+	if !ok {
+		return nil
+	}
+	for _, sessionID := range sessionIDs {
+		delete(sessions, sessionID)
+	}
+	if len(sessions) == 0 {
+		delete(m.users, userID)
+	}
+	return nil
+}
@@ -0,0 +1,114 @@
+package redis
+
+import (
+	"encoding/json"
+	"time"
+
+	"github.com/gomodule/redigo/redis"
+	"github.com/webx-top/echo/middleware/session/index"
+)
+
+type RedisOptions struct {
+	Size      int    `json:"size"`
+	Network   string `json:"network"`
+	Address   string `json:"address"`
+	Password  string `json:"password"`
+	DB        int    `json:"db"`
+	KeyPrefix string `json:"keyPrefix"`
+}
+
+// NewRedisBackend 创建 redis 索引。
+// size: maximum number of idle connections.
+// network: tcp or udp
+// address: host:port
+// password: redis-password
+func NewRedisBackend(opts *RedisOptions) *RedisBackend {
+	network := opts.Network
+	if len(network) == 0 {
+		network = `tcp`
+	}
+	pool := &redis.Pool{
+		MaxIdle:     opts.Size,
+		IdleTimeout: 240 * time.Second,
+		Dial: func() (redis.Conn, error) {
+			return redis.Dial(network, opts.Address, redis.DialPassword(opts.Password), redis.DialDatabase(opts.DB))
+		},
+		TestOnBorrow: func(c redis.Conn, t time.Time) error {
+			_, err := c.Do("PING")
+			return err
+		},
+	}
+	return NewRedisBackendWithPool(pool, opts.KeyPrefix)
+}
+
+func NewRedisBackendWithPool(pool *redis.Pool, prefix string) *RedisBackend {
+	if len(prefix) == 0 {
+		prefix = `session_index_`
+	}
+	return &RedisBackend{Pool: pool, prefix: prefix}
+}
+
+// RedisBackend 每个用户的会话信息保存在一个 hash 中(field 为会话 ID)
+type RedisBackend struct {
+	Pool   *redis.Pool
+	prefix string
+}
+
+func (s *RedisBackend) Save(info *index.Info) error {
+	b, err := json.Marshal(info)
+	if err != nil {
+		return err
+	}
+	conn := s.Pool.Get()
+	defer conn.Close()
+	_, err = conn.Do("HSET", s.prefix+info.UserID, info.SessionID, b)
+	return err
+}
+
+func (s *RedisBackend) Get(userID string, sessionID string) (*index.Info, error) {
+	conn := s.Pool.Get()
+	defer conn.Close()
+	b, err := redis.Bytes(conn.Do("HGET", s.prefix+userID, sessionID))
+	if err != nil {
+		if err == redis.ErrNil {
+			return nil, nil
+		}
+		return nil, err
+	}
+	info := &index.Info{}
+	err = json.Unmarshal(b, info)
+	return info, err
+}
+
+func (s *RedisBackend) List(userID string) ([]*index.Info, error) {
+	conn := s.Pool.Get()
+	defer conn.Close()
+	values, err := redis.StringMap(conn.Do("HGETALL", s.prefix+userID))
+	if err != nil {
+		return nil, err
+	}
+	list := make([]*index.Info, 0, len(values))
+	for _, v := range values {
+		info := &index.Info{}
+		if err = json.Unmarshal([]byte(v), info); err != nil {
+			return nil, err
+		}
+		list = append(list, info)
+	}
+	return list, nil
+}
+
+func (s *RedisBackend) Delete(userID string, sessionIDs ...string) error {
+	if len(sessionIDs) == 0 {
+		return nil
+	}
+	conn := s.Pool.Get()
+	defer conn.Close()
+	args := make([]interface{}, 0, len(sessionIDs)+1)
+	args = append(args, s.prefix+userID)
+	for _, sessionID := range sessionIDs {
+		args = append(args, sessionID)
+	}
+	_, err := conn.Do("HDEL", args...)
+	return err
+}
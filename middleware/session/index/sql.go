@@ -0,0 +1,117 @@
+package index
+
+import (
+	"database/sql"
+	"time"
+
+	"github.com/webx-top/echo/middleware/session/engine/sqlstore"
+)
+
+var DefaultSQLTable = `session_index`
+
+// NewSQLBackend 创建数据库索引，数据表不存在时会自动创建。
+// dialect 用于引用表名以及转换占位符，例如 sqlstore.GetDialect(`sqlite3`)
+func NewSQLBackend(db *sql.DB, dialect *sqlstore.Dialect, tableName string) (*SQLBackend, error) {
+	if len(tableName) == 0 {
+		tableName = DefaultSQLTable
+	}
+	table := dialect.Quote(tableName)
+	ddl := `CREATE TABLE IF NOT EXISTS ` + table + ` (` +
+		`user_id VARCHAR(128) NOT NULL,` +
+		`session_id VARCHAR(64) NOT NULL,` +
+		`created BIGINT NOT NULL DEFAULT 0,` +
+		`last_seen BIGINT NOT NULL DEFAULT 0,` +
+		`ip VARCHAR(64) NOT NULL DEFAULT '',` +
+		`user_agent VARCHAR(255) NOT NULL DEFAULT '',` +
+		`PRIMARY KEY (user_id, session_id))`
+	if _, err := db.Exec(ddl); err != nil {
+		if _, probeErr := db.Exec(`SELECT user_id FROM ` + table + ` WHERE 1 = 0`); probeErr != nil {
+			return nil, err
+		}
+	}
+	return &SQLBackend{db: db, dialect: dialect, table: table}, nil
+}
+
+// SQLBackend 数据库索引，适合与 sqlstore、redis 等多进程共享的存储引擎配合使用
+type SQLBackend struct {
+	db      *sql.DB
+	dialect *sqlstore.Dialect
+	table   string
+}
+
+func (s *SQLBackend) Save(info *Info) error {
+	tx, err := s.db.Begin()
+	if err != nil {
+		return err
+	}
+	_, err = tx.Exec(s.dialect.Bind(`DELETE FROM `+s.table+` WHERE user_id = ? AND session_id = ?`), info.UserID, info.SessionID)
+	if err == nil {
+		_, err = tx.Exec(s.dialect.Bind(`INSERT INTO `+s.table+` (user_id, session_id, created, last_seen, ip, user_agent) VALUES (?, ?, ?, ?, ?, ?)`),
+			info.UserID, info.SessionID, info.Created.Unix(), info.LastSeen.Unix(), truncate(info.IP, 64), truncate(info.UserAgent, 255))
+	}
+	if err != nil {
+		tx.Rollback()
+		return err
+	}
+	return tx.Commit()
+}
+
+func (s *SQLBackend) Get(userID string, sessionID string) (*Info, error) {
+	rows, err := s.db.Query(s.dialect.Bind(`SELECT session_id, created, last_seen, ip, user_agent FROM `+s.table+` WHERE user_id = ? AND session_id = ?`), userID, sessionID)
+	if err != nil {
+		return nil, err
+	}
+	list, err := s.scan(userID, rows)
+	if err != nil || len(list) == 0 {
+		return nil, err
+	}
+	return list[0], nil
+}
+
+func (s *SQLBackend) List(userID string) ([]*Info, error) {
+	rows, err := s.db.Query(s.dialect.Bind(`SELECT session_id, created, last_seen, ip, user_agent FROM `+s.table+` WHERE user_id = ?`), userID)
+	if err != nil {
+		return nil, err
+	}
+	return s.scan(userID, rows)
+}
+
+func (s *SQLBackend) Delete(userID string, sessionIDs ...string) error {
+	if len(sessionIDs) == 0 {
+		return nil
+	}
+	query := `DELETE FROM ` + s.table + ` WHERE user_id = ? AND session_id IN (?`
+	args := make([]interface{}, 0, len(sessionIDs)+1)
+	args = append(args, userID, sessionIDs[0])
+	for _, sessionID := range sessionIDs[1:] {
+		query += `, ?`
+		args = append(args, sessionID)
+	}
+	_, err := s.db.Exec(s.dialect.Bind(query+`)`), args...)
+	return err
+}
+
+func (s *SQLBackend) scan(userID string, rows *sql.Rows) ([]*Info, error) {
+	defer rows.Close()
+	var list []*Info
+	for rows.Next() {
+		var created, lastSeen int64
+		info := &Info{UserID: userID}
+		if err := rows.Scan(&info.SessionID, &created, &lastSeen, &info.IP, &info.UserAgent); err != nil {
+			return nil, err
+		}
+		info.Created = time.Unix(created, 0)
+		info.LastSeen = time.Unix(lastSeen, 0)
+		list = append(list, info)
+	}
+	return list, rows.Err()
+}
+
+// truncate 按字符截断，避免超出字段长度
+func truncate(s string, n int) string {
+	r := []rune(s)
+	if len(r) <= n {
+		return s
+	}
+	return string(r[:n])
+}
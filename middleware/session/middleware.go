@@ -41,6 +41,8 @@ func Sessions(options *echo.SessionOptions, store sessions.Store) echo.Middlewar
 		return func(c echo.Context) error {
 			s := newSession(c)
 			c.SetSessioner(s)
+			// 会话只在首次访问时加载，并且只在修改过(或需要滑动刷新)时保存：
+			// 通常在输出响应之前保存一次，以便写入 cookie；处理器没有输出内容时在最后保存
 			c.AddPreResponseHook(s.Save)
 			err := h.Handle(c)
 			if e := s.Save(); e != nil {
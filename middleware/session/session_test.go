@@ -1,14 +1,23 @@
 package session_test
 
 import (
+	"errors"
 	"fmt"
+	"io/ioutil"
 	"net/http"
+	"os"
+	"path/filepath"
 	"strconv"
 	"testing"
+	"time"
 
+	"github.com/admpub/sessions"
 	"github.com/stretchr/testify/assert"
 	"github.com/webx-top/echo"
 	"github.com/webx-top/echo/middleware/session"
+	"github.com/webx-top/echo/middleware/session/engine"
+	"github.com/webx-top/echo/middleware/session/engine/file"
+	"github.com/webx-top/echo/middleware/session/engine/memory"
 	test "github.com/webx-top/echo/testing"
 )
 
@@ -52,3 +61,240 @@ func TestSession(t *testing.T) {
 		assert.Equal(t, strconv.Itoa(i)+`:test-`+strconv.Itoa(i), resp)
 	}
 }
+
+func TestSessionRegenerateAndDestroy(t *testing.T) {
+	e := echo.New()
+	e.Use(session.Middleware(nil))
+	e.Get(`/login`, func(ctx echo.Context) error {
+		ctx.Session().Set(`user`, `test`)
+		return ctx.String(`ok`)
+	})
+	e.Get(`/regenerate`, func(ctx echo.Context) error {
+		if err := ctx.Session().Regenerate(); err != nil {
+			return err
+		}
+		return ctx.String(`ok`)
+	})
+	e.Get(`/logout`, func(ctx echo.Context) error {
+		if err := ctx.Session().Destroy(); err != nil {
+			return err
+		}
+		return ctx.String(`ok`)
+	})
+	e.Get(`/result`, func(ctx echo.Context) error {
+		return ctx.String(fmt.Sprintf(`%v`, ctx.Session().Get(`user`)))
+	})
+	e.RebuildRouter()
+	rew := func(headers http.Header) func(req *http.Request) {
+		return func(req *http.Request) {
+			for _, h := range headers["Set-Cookie"] {
+				req.Header.Add(`Cookie`, h)
+			}
+		}
+	}
+	_, _, header := request(`GET`, `/login`, e)
+	_, _, regenerated := request(`GET`, `/regenerate`, e, rew(header))
+	assert.Equal(t, `SID=`, regenerated["Set-Cookie"][0][0:4])
+	_, resp, _ := request(`GET`, `/result`, e, rew(regenerated))
+	assert.Equal(t, `test`, resp)
+
+	_, _, destroyed := request(`GET`, `/logout`, e, rew(regenerated))
+	assert.Equal(t, `SID=;`, destroyed["Set-Cookie"][0][0:5])
+	_, resp, _ = request(`GET`, `/result`, e)
+	assert.Equal(t, `<nil>`, resp)
+}
+
+func TestSessionRegenerateStores(t *testing.T) {
+	dir, err := ioutil.TempDir(``, `echo-session-test`)
+	assert.NoError(t, err)
+	defer os.RemoveAll(dir)
+	memoryStore := memory.NewMemoryStore(&memory.MemoryOptions{CleanupInterval: -1})
+	defer memoryStore.Close()
+	fileStore := file.NewFilesystemStore(dir, []byte(`0123456789abcdef0123456789abcdef`))
+	for name, c := range map[string]struct {
+		store  sessions.Store
+		exists func(id string) bool
+	}{
+		`memory`: {memoryStore, func(id string) bool {
+			_, ok := memoryStore.Values(id)
+			return ok
+		}},
+		`file`: {fileStore, func(id string) bool {
+			_, err := os.Stat(filepath.Join(dir, `session_`+id))
+			return err == nil
+		}},
+	} {
+		e := echo.New()
+		e.Use(session.Sessions(echo.NewSessionOptions(name, `SID`), c.store))
+		e.Get(`/login`, func(ctx echo.Context) error {
+			ctx.Session().Set(`user`, `test`)
+			return ctx.String(`ok`)
+		})
+		e.Get(`/regenerate`, func(ctx echo.Context) error {
+			if err := ctx.Session().Regenerate(); err != nil {
+				return err
+			}
+			return ctx.String(ctx.Session().ID())
+		})
+		e.Get(`/id`, func(ctx echo.Context) error {
+			return ctx.String(ctx.Session().ID())
+		})
+		e.Get(`/result`, func(ctx echo.Context) error {
+			return ctx.String(fmt.Sprintf(`%v`, ctx.Session().Get(`user`)))
+		})
+		e.RebuildRouter()
+		rew := func(headers http.Header) func(req *http.Request) {
+			return func(req *http.Request) {
+				for _, h := range headers["Set-Cookie"] {
+					req.Header.Add(`Cookie`, h)
+				}
+			}
+		}
+
+		_, _, login := request(`GET`, `/login`, e)
+		_, oldID, _ := request(`GET`, `/id`, e, rew(login))
+		assert.NotEmpty(t, oldID, name)
+		assert.True(t, c.exists(oldID), name)
+
+		_, _, regenerated := request(`GET`, `/regenerate`, e, rew(login))
+		_, newID, _ := request(`GET`, `/id`, e, rew(regenerated))
+		assert.NotEmpty(t, newID, name)
+		assert.NotEqual(t, oldID, newID, name)
+		assert.True(t, c.exists(newID), name)
+		_, resp, _ := request(`GET`, `/result`, e, rew(regenerated))
+		assert.Equal(t, `test`, resp, name)
+
+		// 旧 ID 的会话数据已被删除
+		assert.False(t, c.exists(oldID), name)
+		_, resp, _ = request(`GET`, `/result`, e, rew(login))
+		assert.Equal(t, `<nil>`, resp, name)
+	}
+}
+
+// failingStore 保存总是失败，记录被删除的会话 ID
+type failingStore struct {
+	removed []string
+}
+
+func (s *failingStore) Get(ctx echo.Context, name string) (*sessions.Session, error) {
+	return s.New(ctx, name)
+}
+
+func (s *failingStore) New(ctx echo.Context, name string) (*sessions.Session, error) {
+	session := sessions.NewSession(s, name)
+	session.ID = `old`
+	session.IsNew = false
+	return session, nil
+}
+
+func (s *failingStore) Save(ctx echo.Context, session *sessions.Session) error {
+	return errors.New(`save failed`)
+}
+
+func (s *failingStore) Remove(sessionID string) error {
+	s.removed = append(s.removed, sessionID)
+	return nil
+}
+
+func TestSessionRegenerateSaveFailure(t *testing.T) {
+	store := &failingStore{}
+	e := echo.New()
+	e.Use(session.Sessions(echo.NewSessionOptions(`test`, `SID`), store))
+	e.Get(`/regenerate`, func(ctx echo.Context) error {
+		if err := ctx.Session().Regenerate(); err != nil {
+			return err
+		}
+		return ctx.String(`ok`)
+	})
+	e.RebuildRouter()
+
+	// 新 ID 保存失败时保留旧 ID 的会话数据
+	request(`GET`, `/regenerate`, e)
+	assert.Empty(t, store.removed)
+}
+
+func TestSessionDirtyTracking(t *testing.T) {
+	e := echo.New()
+	e.Use(session.Middleware(nil))
+	e.Get(`/set`, func(ctx echo.Context) error {
+		ctx.Session().Set(`user`, `test`)
+		return ctx.String(`ok`)
+	})
+	e.Get(`/read`, func(ctx echo.Context) error {
+		ctx.Session().Delete(`missing`)
+		ctx.Flash()
+		return ctx.String(fmt.Sprintf(`%v`, ctx.Session().Get(`user`)))
+	})
+	e.RebuildRouter()
+	_, _, header := request(`GET`, `/set`, e)
+	code, resp, header := request(`GET`, `/read`, e, func(req *http.Request) {
+		for _, h := range header["Set-Cookie"] {
+			req.Header.Add(`Cookie`, h)
+		}
+	})
+	assert.Equal(t, 200, code)
+	assert.Equal(t, `test`, resp)
+	assert.Empty(t, header["Set-Cookie"])
+}
+
+// refreshStore 返回已有的会话，上次保存时间按 JSON 编解码器的方式存为 float64
+type refreshStore struct {
+	refreshed time.Time
+	saves     int
+}
+
+func (s *refreshStore) Get(ctx echo.Context, name string) (*sessions.Session, error) {
+	return s.New(ctx, name)
+}
+
+func (s *refreshStore) New(ctx echo.Context, name string) (*sessions.Session, error) {
+	session := sessions.NewSession(s, name)
+	session.ID = `test`
+	session.IsNew = false
+	session.Values[engine.RefreshedKey] = float64(s.refreshed.UnixNano())
+	return session, nil
+}
+
+func (s *refreshStore) Save(ctx echo.Context, session *sessions.Session) error {
+	s.saves++
+	return nil
+}
+
+func TestSessionRefresh(t *testing.T) {
+	store := &refreshStore{}
+	options := echo.NewSessionOptions(`test`, `SID`)
+	options.RefreshInterval = time.Hour
+	e := echo.New()
+	e.Use(session.Sessions(options, store))
+	e.Get(`/read`, func(ctx echo.Context) error {
+		ctx.Session().Get(`user`)
+		return ctx.String(`ok`)
+	})
+	e.Get(`/set`, func(ctx echo.Context) error {
+		ctx.Session().Set(`user`, `test`)
+		return ctx.String(`ok`)
+	})
+	e.RebuildRouter()
+
+	store.refreshed = time.Now()
+	request(`GET`, `/read`, e)
+	assert.Equal(t, 0, store.saves)
+
+	// 超过刷新间隔时只保存一次(输出响应前保存后，中间件结束时不再保存)
+	store.refreshed = time.Now().Add(-2 * time.Hour)
+	request(`GET`, `/read`, e)
+	assert.Equal(t, 1, store.saves)
+
+	store.refreshed = time.Now()
+	request(`GET`, `/set`, e)
+	assert.Equal(t, 2, store.saves)
+
+	// 小于一秒的刷新间隔不会被截断为 0
+	options.RefreshInterval = 500 * time.Millisecond
+	store.refreshed = time.Now().Add(-100 * time.Millisecond)
+	request(`GET`, `/read`, e)
+	assert.Equal(t, 2, store.saves)
+	store.refreshed = time.Now().Add(-600 * time.Millisecond)
+	request(`GET`, `/read`, e)
+	assert.Equal(t, 3, store.saves)
+}
@@ -0,0 +1,75 @@
+package middleware
+
+import (
+	"io/ioutil"
+	"net/http"
+	"os"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/webx-top/echo"
+	test "github.com/webx-top/echo/testing"
+)
+
+func TestStaticSPA(t *testing.T) {
+	dir, err := ioutil.TempDir(``, `echo-spa-test`)
+	assert.NoError(t, err)
+	defer os.RemoveAll(dir)
+	writeStaticFiles(t, dir, map[string]string{
+		`index.html`: `<html><head></head><body></body></html>`,
+		`app.js`:     `js`,
+	})
+
+	e := echo.New()
+	e.Use(Static(&StaticOptions{
+		Root:       dir,
+		SPA:        true,
+		SPAExclude: []string{`/api`, `/admin/`},
+		SPAInject: func(c echo.Context, html []byte) ([]byte, error) {
+			return InjectHTML(html, `<script>window.ENV="test"</script>`), nil
+		},
+	}))
+	e.Get(`/api/ok`, func(c echo.Context) error {
+		return c.String(`api`)
+	})
+	e.RebuildRouter()
+
+	index := `<html><head><script>window.ENV="test"</script></head><body></body></html>`
+	rec := test.Request(echo.GET, `/users/1`, e)
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.Equal(t, index, rec.Body.String())
+	assert.Equal(t, echo.MIMETextHTMLCharsetUTF8, rec.Header().Get(echo.HeaderContentType))
+	etag := rec.Header().Get(echo.HeaderETag)
+	assert.NotEmpty(t, etag)
+	rec = test.Request(echo.GET, `/users/1`, e, func(req *http.Request) {
+		req.Header.Set(echo.HeaderIfNoneMatch, etag)
+	})
+	assert.Equal(t, http.StatusNotModified, rec.Code)
+
+	rec = test.Request(echo.GET, `/app.js`, e)
+	assert.Equal(t, `js`, rec.Body.String())
+	// 带扩展名的路径仍然返回 404
+	rec = test.Request(echo.GET, `/missing.js`, e)
+	assert.Equal(t, http.StatusNotFound, rec.Code)
+	rec = test.Request(echo.POST, `/users/1`, e)
+	assert.Equal(t, http.StatusNotFound, rec.Code)
+
+	// 排除的路径交给后续处理器
+	rec = test.Request(echo.GET, `/api/ok`, e)
+	assert.Equal(t, `api`, rec.Body.String())
+	for _, p := range []string{`/api`, `/api/missing`, `/admin`, `/admin/users`} {
+		rec = test.Request(echo.GET, p, e)
+		assert.Equal(t, http.StatusNotFound, rec.Code, p)
+	}
+	// 按路径段匹配
+	for _, p := range []string{`/apiary`, `/api-docs/intro`, `/administrator`} {
+		rec = test.Request(echo.GET, p, e)
+		assert.Equal(t, http.StatusOK, rec.Code, p)
+		assert.Equal(t, index, rec.Body.String(), p)
+	}
+}
+
+func TestInjectHTML(t *testing.T) {
+	assert.Equal(t, `<html><head><meta></head></html>`, string(InjectHTML([]byte(`<html><head></head></html>`), `<meta>`)))
+	assert.Equal(t, `<html><HEAD><meta></HEAD></html>`, string(InjectHTML([]byte(`<html><HEAD></HEAD></html>`), `<meta>`)))
+}
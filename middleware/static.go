@@ -1,15 +1,23 @@
 package middleware
 
 import (
+	"bytes"
 	"html/template"
+	"io"
+	"io/ioutil"
 	"net/http"
 	"os"
 	"path"
 	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
 
 	"github.com/admpub/log"
 
 	"github.com/webx-top/echo"
+	"github.com/webx-top/echo/engine"
 )
 
 var ListDirTemplate = `<!doctype html>
@@ -44,11 +52,95 @@ type (
 		Debug    bool            `json:"debug"`
 		FS       http.FileSystem `json:"-"`
 
+		// Precompressed 查找预压缩文件的编码(如 br、gzip、zstd)，按顺序优先。
+		// 客户端支持时输出同目录下的 `file.br`、`file.gz` 或 `file.zst`
+		Precompressed []string `json:"precompressed"`
+
+		// CacheRules 按路径设置 Cache-Control 和 Expires，使用第一条匹配的规则
+		CacheRules []*StaticCacheRule `json:"cacheRules"`
+
+		// Immutable 为带指纹的文件名(如 app.3f2a9c1e.js)设置一年有效期和 immutable
+		Immutable bool `json:"immutable"`
+
+		// Fingerprint 匹配带指纹的文件名。为 nil 时使用 DefaultFingerprintRegexp
+		Fingerprint *regexp.Regexp `json:"-"`
+
+		// SPA 单页应用模式。找不到文件时对不带扩展名的路径输出 SPAIndex，
+		// 带扩展名的路径(如 .js、.png)仍然返回 404
+		SPA bool `json:"spa"`
+
+		// SPAIndex 单页应用的入口文件，相对于 Root 或 Fallback 目录
+		// Optional. Default value Index or "index.html".
+		SPAIndex string `json:"spaIndex"`
+
+		// SPAExclude 直接交给后续处理器的 URL 路径前缀(如 /api)
+		SPAExclude []string `json:"spaExclude"`
+
+		// SPAInject 修改入口文件的 HTML 内容，用于注入运行时配置或 CSP nonce 等。
+		// 设置后每次请求都会重新生成内容，ETag 根据生成的内容计算
+		SPAInject func(c echo.Context, html []byte) ([]byte, error) `json:"-"`
+
 		open   func(string) (http.File, error)
 		render func(echo.Context, interface{}) error
+		// fsRooted 为 true 时 Root 和 Fallback 为 FS 内以 `/` 开头的路径(由 SetFS 设置)
+		fsRooted bool
+	}
+
+	// StaticCacheRule 静态文件的缓存规则
+	StaticCacheRule struct {
+		// Pattern 使用 path.Match 匹配相对于 Path 的文件路径(如 `/js/*.js`)，
+		// 不含 `/` 时只匹配文件名(如 `*.css`)
+		Pattern   string        `json:"pattern"`
+		MaxAge    time.Duration `json:"maxAge"`
+		Private   bool          `json:"private"`
+		NoCache   bool          `json:"noCache"`
+		Immutable bool          `json:"immutable"`
+	}
+)
+
+var (
+	// DefaultFingerprintRegexp 默认的文件名指纹规则
+	DefaultFingerprintRegexp = regexp.MustCompile(`[.-][0-9a-fA-F]{8,}\.[a-zA-Z0-9]+$`)
+
+	// PrecompressedExtensions 预压缩文件的扩展名
+	PrecompressedExtensions = map[string]string{
+		`br`:   `.br`,
+		`gzip`: `.gz`,
+		`zstd`: `.zst`,
 	}
 )
 
+const immutableMaxAge = 365 * 24 * time.Hour
+
+// Match 检查规则是否匹配文件路径
+func (r *StaticCacheRule) Match(file string) bool {
+	name := file
+	if !strings.Contains(r.Pattern, `/`) {
+		name = path.Base(file)
+	}
+	ok, _ := path.Match(r.Pattern, name)
+	return ok
+}
+
+// CacheControl 生成 Cache-Control 头部的值
+func (r *StaticCacheRule) CacheControl() string {
+	if r.NoCache {
+		if r.Private {
+			return `private, no-cache`
+		}
+		return `no-cache`
+	}
+	v := `public`
+	if r.Private {
+		v = `private`
+	}
+	v += `, max-age=` + strconv.FormatInt(int64(r.MaxAge/time.Second), 10)
+	if r.Immutable {
+		v += `, immutable`
+	}
+	return v
+}
+
 func Static(options ...*StaticOptions) echo.MiddlewareFunc {
 	// Default options
 	opts := new(StaticOptions)
@@ -63,12 +155,12 @@ func (s *StaticOptions) Init() *StaticOptions {
 		s.Skipper = echo.DefaultSkipper
 	}
 	var err error
-	s.Root, err = filepath.Abs(s.Root)
+	s.Root, err = s.absPath(s.Root)
 	if err != nil {
 		panic(err)
 	}
 	for index, fallback := range s.Fallback {
-		s.Fallback[index], err = filepath.Abs(fallback)
+		s.Fallback[index], err = s.absPath(fallback)
 		if err != nil {
 			panic(err)
 		}
@@ -76,6 +168,16 @@ func (s *StaticOptions) Init() *StaticOptions {
 			log.GetLogger("echo").Debug(`[middleware][static] `, `Register assets directory: `, fallback)
 		}
 	}
+	if s.SPA && len(s.SPAIndex) == 0 {
+		if len(s.Index) > 0 {
+			s.SPAIndex = s.Index
+		} else {
+			s.SPAIndex = `index.html`
+		}
+	}
+	if s.Immutable && s.Fingerprint == nil {
+		s.Fingerprint = DefaultFingerprintRegexp
+	}
 	if len(s.Path) > 0 && s.Path[0] != '/' {
 		s.Path = `/` + s.Path
 	}
@@ -87,7 +189,7 @@ func (s *StaticOptions) Init() *StaticOptions {
 
 func (s *StaticOptions) AddFallback(fallback string) *StaticOptions {
 	var err error
-	fallback, err = filepath.Abs(fallback)
+	fallback, err = s.absPath(fallback)
 	if err != nil {
 		panic(err)
 	}
@@ -98,6 +200,21 @@ func (s *StaticOptions) AddFallback(fallback string) *StaticOptions {
 	return s
 }
 
+// absPath 返回目录的绝对路径。通过 SetFS 使用 fs.FS 时返回以 `/` 开头的 FS 内路径
+func (s *StaticOptions) absPath(p string) (string, error) {
+	if s.fsRooted {
+		return path.Join(`/`, filepath.ToSlash(p)), nil
+	}
+	return filepath.Abs(p)
+}
+
+func (s *StaticOptions) join(elem ...string) string {
+	if s.fsRooted {
+		return path.Join(elem...)
+	}
+	return filepath.Join(elem...)
+}
+
 func (s *StaticOptions) getOpener() func(file string) (http.File, error) {
 	if s.open != nil {
 		return s.open
@@ -140,7 +257,7 @@ func (s *StaticOptions) getRender() func(c echo.Context, data interface{}) error
 }
 
 func (s *StaticOptions) findFile(c echo.Context, root string, hasIndex bool, file string, render func(echo.Context, interface{}) error, opener func(string) (http.File, error)) error {
-	absFile := filepath.Join(root, file)
+	absFile := s.join(root, file)
 	fp, err := opener(absFile)
 	if err != nil {
 		return echo.ErrNotFound
@@ -152,21 +269,23 @@ func (s *StaticOptions) findFile(c echo.Context, root string, hasIndex bool, fil
 	}
 	if fi.IsDir() {
 		if hasIndex {
-			fp.Close()
 			// Index file
-			indexFile := filepath.Join(absFile, s.Index)
-			fp, err = opener(indexFile)
+			indexFile := s.join(absFile, s.Index)
+			ifp, err := opener(indexFile)
 			if err != nil {
 				return echo.ErrNotFound
 			}
-			fi, err = fp.Stat()
+			defer ifp.Close()
+			fi, err = ifp.Stat()
 			if err != nil || fi.IsDir() {
 				if s.Browse {
 					return listDirByCustomFS(absFile, file, c, render, opener)
 				}
 				return echo.ErrNotFound
 			}
+			fp = ifp
 			absFile = indexFile
+			file = path.Join(file, s.Index)
 		} else {
 			if s.Browse {
 				return listDirByCustomFS(absFile, file, c, render, opener)
@@ -174,7 +293,88 @@ func (s *StaticOptions) findFile(c echo.Context, root string, hasIndex bool, fil
 			return echo.ErrNotFound
 		}
 	}
-	return c.ServeContent(fp, fi.Name(), fi.ModTime())
+	return s.serveFile(c, fp, fi, absFile, file, opener)
+}
+
+func (s *StaticOptions) serveFile(c echo.Context, fp http.File, fi os.FileInfo, absFile string, file string, opener func(string) (http.File, error)) error {
+	header := c.Response().Header()
+	s.setCacheHeaders(header, file)
+	var (
+		content  io.Reader = fp
+		size               = fi.Size()
+		encoding string
+	)
+	if len(s.Precompressed) > 0 {
+		cfp, cfi, enc, exists := s.findPrecompressed(c, absFile, opener)
+		if exists {
+			echo.AddVary(header, echo.HeaderAcceptEncoding)
+		}
+		if cfp != nil {
+			defer cfp.Close()
+			content, size, encoding = cfp, cfi.Size(), enc
+		}
+	}
+	etag := `"` + strconv.FormatInt(fi.ModTime().Unix(), 16) + `-` + strconv.FormatInt(size, 16)
+	if len(encoding) > 0 {
+		etag += `-` + encoding
+	}
+	etag += `"`
+	if err := c.CheckPrecondition(etag, fi.ModTime()); err != nil {
+		return err
+	}
+	if len(encoding) > 0 {
+		header.Set(echo.HeaderContentEncoding, encoding)
+	}
+	// 使用原文件名，以便按原文件的扩展名设置 Content-Type
+	return c.ServeContent(content, fi.Name(), fi.ModTime())
+}
+
+func (s *StaticOptions) setCacheHeaders(header engine.Header, file string) {
+	if s.Fingerprint != nil && s.Fingerprint.MatchString(path.Base(file)) {
+		header.Set(echo.HeaderCacheControl, `public, max-age=`+strconv.FormatInt(int64(immutableMaxAge/time.Second), 10)+`, immutable`)
+		header.Set(echo.HeaderExpires, time.Now().Add(immutableMaxAge).UTC().Format(http.TimeFormat))
+		return
+	}
+	for _, rule := range s.CacheRules {
+		if !rule.Match(file) {
+			continue
+		}
+		header.Set(echo.HeaderCacheControl, rule.CacheControl())
+		if !rule.NoCache {
+			header.Set(echo.HeaderExpires, time.Now().Add(rule.MaxAge).UTC().Format(http.TimeFormat))
+		}
+		return
+	}
+}
+
+// findPrecompressed 查找客户端支持的预压缩文件。exists 表示是否存在任意预压缩文件(用于设置 Vary)
+func (s *StaticOptions) findPrecompressed(c echo.Context, absFile string, opener func(string) (http.File, error)) (fp http.File, fi os.FileInfo, encoding string, exists bool) {
+	accepts := echo.ParseAcceptQuality(c.Request().Header().Get(echo.HeaderAcceptEncoding))
+	for _, enc := range s.Precompressed {
+		ext, ok := PrecompressedExtensions[enc]
+		if !ok {
+			ext = `.` + enc
+		}
+		cfp, err := opener(absFile + ext)
+		if err != nil {
+			continue
+		}
+		cfi, err := cfp.Stat()
+		if err != nil || cfi.IsDir() {
+			cfp.Close()
+			continue
+		}
+		exists = true
+		if fp == nil && encodingQuality(accepts, enc) > 0 {
+			fp, fi, encoding = cfp, cfi, enc
+			continue
+		}
+		cfp.Close()
+		if fp != nil {
+			break
+		}
+	}
+	return
 }
 
 func (s *StaticOptions) Middleware() echo.MiddlewareFunc {
@@ -191,6 +391,9 @@ func (s *StaticOptions) Middleware() echo.MiddlewareFunc {
 			if len(file) < length || file[0:length] != s.Path {
 				return next.Handle(c)
 			}
+			if s.SPA && s.isSPAExcluded(file) {
+				return next.Handle(c)
+			}
 			file = file[length:]
 			file = path.Clean(file)
 			err := s.findFile(c, s.Root, hasIndex, file, render, opener)
@@ -198,12 +401,9 @@ func (s *StaticOptions) Middleware() echo.MiddlewareFunc {
 				return err
 			}
 			if err == echo.ErrNotFound {
-				if len(s.Fallback) == 0 {
-					return err
-				}
 				for _, fallback := range s.Fallback {
 					if s.Debug {
-						log.GetLogger("echo").Debug(`[middleware][static] `, `fallback ->  `, filepath.Join(fallback, file))
+						log.GetLogger("echo").Debug(`[middleware][static] `, `fallback ->  `, s.join(fallback, file))
 					}
 					err = s.findFile(c, fallback, hasIndex, file, render, opener)
 					if err == nil {
@@ -211,13 +411,86 @@ func (s *StaticOptions) Middleware() echo.MiddlewareFunc {
 					}
 				}
 			}
+			if err == echo.ErrNotFound && s.isSPARoute(c, file) {
+				return s.serveSPAIndex(c, opener)
+			}
 			return err
 		})
 	}
 }
 
+// isSPARoute 是否应该输出单页应用的入口文件
+func (s *StaticOptions) isSPARoute(c echo.Context, file string) bool {
+	if !s.SPA {
+		return false
+	}
+	if method := c.Request().Method(); method != echo.GET && method != echo.HEAD {
+		return false
+	}
+	return len(path.Ext(file)) == 0
+}
+
+// isSPAExcluded 是否为单页应用模式下排除的路径(交给后续处理器处理)。
+// 按路径段匹配，如 /api 匹配 /api 和 /api/users，但不匹配 /apiary
+func (s *StaticOptions) isSPAExcluded(urlPath string) bool {
+	for _, prefix := range s.SPAExclude {
+		prefix = strings.TrimSuffix(prefix, `/`)
+		if urlPath == prefix || strings.HasPrefix(urlPath, prefix+`/`) {
+			return true
+		}
+	}
+	return false
+}
+
+func (s *StaticOptions) serveSPAIndex(c echo.Context, opener func(string) (http.File, error)) error {
+	roots := append([]string{s.Root}, s.Fallback...)
+	file := `/` + strings.TrimPrefix(s.SPAIndex, `/`)
+	for _, root := range roots {
+		absFile := s.join(root, file)
+		fp, err := opener(absFile)
+		if err != nil {
+			continue
+		}
+		defer fp.Close()
+		fi, err := fp.Stat()
+		if err != nil || fi.IsDir() {
+			continue
+		}
+		if s.SPAInject == nil {
+			return s.serveFile(c, fp, fi, absFile, file, opener)
+		}
+		b, err := ioutil.ReadAll(fp)
+		if err != nil {
+			return err
+		}
+		b, err = s.SPAInject(c, b)
+		if err != nil {
+			return err
+		}
+		s.setCacheHeaders(c.Response().Header(), file)
+		if err = c.CheckPrecondition(echo.GenerateETag(b, false), time.Time{}); err != nil {
+			return err
+		}
+		c.Response().Header().Set(echo.HeaderContentType, echo.MIMETextHTMLCharsetUTF8)
+		return c.Blob(b)
+	}
+	return echo.ErrNotFound
+}
+
+// InjectHTML 将 snippet 插入到 HTML 的 </head> 之前(没有 </head> 时插入到开头)，可用于 SPAInject
+func InjectHTML(html []byte, snippet string) []byte {
+	pos := bytes.Index(bytes.ToLower(html), []byte(`</head>`))
+	if pos < 0 {
+		return append([]byte(snippet), html...)
+	}
+	r := make([]byte, 0, len(html)+len(snippet))
+	r = append(r, html[:pos]...)
+	r = append(r, snippet...)
+	return append(r, html[pos:]...)
+}
+
 func listDirByCustomFS(absFile string, file string, c echo.Context, render func(echo.Context, interface{}) error, opener func(string) (http.File, error)) error {
-	d, err := opener(file)
+	d, err := opener(absFile)
 	if err != nil {
 		return echo.ErrNotFound
 	}
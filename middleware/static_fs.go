@@ -0,0 +1,17 @@
+// +build go1.16
+
+package middleware
+
+import (
+	"io/fs"
+	"net/http"
+)
+
+// SetFS 使用 fs.FS(如 embed.FS)读取静态文件，需要在 Init 之前调用。
+// 此时 Root 和 Fallback 为 fsys 中的目录
+func (s *StaticOptions) SetFS(fsys fs.FS) *StaticOptions {
+	s.FS = http.FS(fsys)
+	s.fsRooted = true
+	s.open = nil
+	return s
+}
@@ -0,0 +1,186 @@
+package middleware
+
+import (
+	"io/ioutil"
+	"net/http"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/webx-top/echo"
+	test "github.com/webx-top/echo/testing"
+)
+
+func writeStaticFiles(t *testing.T, dir string, files map[string]string) {
+	for name, content := range files {
+		file := filepath.Join(dir, filepath.FromSlash(name))
+		assert.NoError(t, os.MkdirAll(filepath.Dir(file), 0755))
+		assert.NoError(t, ioutil.WriteFile(file, []byte(content), 0644))
+	}
+}
+
+func TestStaticPrecompressed(t *testing.T) {
+	dir, err := ioutil.TempDir(``, `echo-static-test`)
+	assert.NoError(t, err)
+	defer os.RemoveAll(dir)
+	writeStaticFiles(t, dir, map[string]string{
+		`app.js`:     `plain`,
+		`app.js.gz`:  `gzipped`,
+		`app.js.br`:  `brotli`,
+		`style.css`:  `css`,
+		`only.js`:    `plain`,
+		`only.js.gz`: `gzipped`,
+	})
+	e := echo.New()
+	e.Use(Static(&StaticOptions{
+		Root:          dir,
+		Path:          `/static`,
+		Precompressed: []string{`br`, `gzip`},
+	}))
+	e.RebuildRouter()
+
+	// 按 Precompressed 的顺序选择客户端支持的编码
+	rec := test.Request(echo.GET, `/static/app.js`, e, acceptEncoding(`gzip, br`))
+	assert.Equal(t, `brotli`, rec.Body.String())
+	assert.Equal(t, `br`, rec.Header().Get(echo.HeaderContentEncoding))
+	assert.Contains(t, rec.Header().Get(echo.HeaderContentType), `javascript`)
+	assert.Equal(t, echo.HeaderAcceptEncoding, rec.Header().Get(echo.HeaderVary))
+	assert.Contains(t, rec.Header().Get(echo.HeaderETag), `-br"`)
+
+	rec = test.Request(echo.GET, `/static/app.js`, e, acceptEncoding(`gzip`))
+	assert.Equal(t, `gzipped`, rec.Body.String())
+	assert.Equal(t, `gzip`, rec.Header().Get(echo.HeaderContentEncoding))
+	assert.Contains(t, rec.Header().Get(echo.HeaderETag), `-gzip"`)
+
+	rec = test.Request(echo.GET, `/static/only.js`, e, acceptEncoding(`br, gzip;q=0.5`))
+	assert.Equal(t, `gzipped`, rec.Body.String())
+	assert.Equal(t, `gzip`, rec.Header().Get(echo.HeaderContentEncoding))
+
+	// 不支持压缩的客户端得到原文件，但响应仍然随 Accept-Encoding 变化
+	rec = test.Request(echo.GET, `/static/app.js`, e)
+	assert.Equal(t, `plain`, rec.Body.String())
+	assert.Empty(t, rec.Header().Get(echo.HeaderContentEncoding))
+	assert.Equal(t, echo.HeaderAcceptEncoding, rec.Header().Get(echo.HeaderVary))
+
+	// 没有预压缩文件时不设置 Vary
+	rec = test.Request(echo.GET, `/static/style.css`, e, acceptEncoding(`gzip`))
+	assert.Equal(t, `css`, rec.Body.String())
+	assert.Empty(t, rec.Header().Get(echo.HeaderContentEncoding))
+	assert.Empty(t, rec.Header().Get(echo.HeaderVary))
+}
+
+func TestStaticNotModified(t *testing.T) {
+	dir, err := ioutil.TempDir(``, `echo-static-test`)
+	assert.NoError(t, err)
+	defer os.RemoveAll(dir)
+	writeStaticFiles(t, dir, map[string]string{
+		`app.js`:    `plain`,
+		`app.js.gz`: `gzipped`,
+	})
+	modTime := time.Now().Add(-time.Hour).Truncate(time.Second)
+	assert.NoError(t, os.Chtimes(filepath.Join(dir, `app.js`), modTime, modTime))
+	e := echo.New()
+	e.Use(Static(&StaticOptions{
+		Root:          dir,
+		Precompressed: []string{`gzip`},
+		CacheRules: []*StaticCacheRule{
+			{Pattern: `*.js`, MaxAge: time.Hour},
+		},
+	}))
+	e.RebuildRouter()
+
+	rec := test.Request(echo.GET, `/app.js`, e, acceptEncoding(`gzip`))
+	assert.Equal(t, http.StatusOK, rec.Code)
+	etag := rec.Header().Get(echo.HeaderETag)
+	assert.NotEmpty(t, etag)
+	assert.Equal(t, `public, max-age=3600`, rec.Header().Get(echo.HeaderCacheControl))
+
+	rec = test.Request(echo.GET, `/app.js`, e, func(req *http.Request) {
+		req.Header.Set(echo.HeaderAcceptEncoding, `gzip`)
+		req.Header.Set(echo.HeaderIfNoneMatch, etag)
+	})
+	assert.Equal(t, http.StatusNotModified, rec.Code)
+	assert.Empty(t, rec.Body.String())
+	assert.Equal(t, `public, max-age=3600`, rec.Header().Get(echo.HeaderCacheControl))
+
+	// 预压缩文件和原文件的 ETag 不同
+	rec = test.Request(echo.GET, `/app.js`, e, func(req *http.Request) {
+		req.Header.Set(echo.HeaderIfNoneMatch, etag)
+	})
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.Equal(t, `plain`, rec.Body.String())
+
+	rec = test.Request(echo.GET, `/app.js`, e, func(req *http.Request) {
+		req.Header.Set(echo.HeaderIfModifiedSince, modTime.UTC().Format(http.TimeFormat))
+	})
+	assert.Equal(t, http.StatusNotModified, rec.Code)
+	assert.Empty(t, rec.Body.String())
+
+	rec = test.Request(echo.GET, `/app.js`, e, func(req *http.Request) {
+		req.Header.Set(echo.HeaderIfModifiedSince, modTime.Add(-time.Minute).UTC().Format(http.TimeFormat))
+	})
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.Equal(t, `plain`, rec.Body.String())
+}
+
+func TestStaticImmutable(t *testing.T) {
+	dir, err := ioutil.TempDir(``, `echo-static-test`)
+	assert.NoError(t, err)
+	defer os.RemoveAll(dir)
+	writeStaticFiles(t, dir, map[string]string{
+		`app.3f2a9c1e12.css`:    `css`,
+		`vendor-0123abcd.js`:    `js`,
+		`app.css`:               `css`,
+		`jquery-3.6.0.min.js`:   `js`,
+		`sub/index.html`:        `<p>hi</p>`,
+		`sub/logo.deadbeef.png`: `png`,
+	})
+	e := echo.New()
+	e.Use(Static(&StaticOptions{
+		Root:      dir,
+		Index:     `index.html`,
+		Immutable: true,
+		CacheRules: []*StaticCacheRule{
+			{Pattern: `*.html`, NoCache: true},
+		},
+	}))
+	e.RebuildRouter()
+
+	immutable := `public, max-age=31536000, immutable`
+	for _, file := range []string{`/app.3f2a9c1e12.css`, `/vendor-0123abcd.js`, `/sub/logo.deadbeef.png`} {
+		rec := test.Request(echo.GET, file, e)
+		assert.Equal(t, http.StatusOK, rec.Code, file)
+		assert.Equal(t, immutable, rec.Header().Get(echo.HeaderCacheControl), file)
+		assert.NotEmpty(t, rec.Header().Get(echo.HeaderExpires), file)
+	}
+	for _, file := range []string{`/app.css`, `/jquery-3.6.0.min.js`} {
+		rec := test.Request(echo.GET, file, e)
+		assert.Equal(t, http.StatusOK, rec.Code, file)
+		assert.Empty(t, rec.Header().Get(echo.HeaderCacheControl), file)
+	}
+	rec := test.Request(echo.GET, `/sub/`, e)
+	assert.Equal(t, `<p>hi</p>`, rec.Body.String())
+	assert.Equal(t, `no-cache`, rec.Header().Get(echo.HeaderCacheControl))
+}
+
+func TestStaticFileSystem(t *testing.T) {
+	// http.FileSystem 与操作系统路径的 Root 一起使用时，Root 仍然解析为绝对路径
+	dir, err := ioutil.TempDir(`.`, `echo-static-test`)
+	assert.NoError(t, err)
+	defer os.RemoveAll(dir)
+	writeStaticFiles(t, dir, map[string]string{
+		`app.js`: `js`,
+	})
+	e := echo.New()
+	e.Use(Static(&StaticOptions{
+		Root: filepath.Base(dir),
+		FS:   http.Dir(`/`),
+	}))
+	e.RebuildRouter()
+
+	rec := test.Request(echo.GET, `/app.js`, e)
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.Equal(t, `js`, rec.Body.String())
+}
@@ -0,0 +1,231 @@
+/*
+
+   Copyright 2016-present Wenhui Shen <www.webx.top>
+
+   Licensed under the Apache License, Version 2.0 (the "License");
+   you may not use this file except in compliance with the License.
+   You may obtain a copy of the License at
+
+       http://www.apache.org/licenses/LICENSE-2.0
+
+   Unless required by applicable law or agreed to in writing, software
+   distributed under the License is distributed on an "AS IS" BASIS,
+   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+   See the License for the specific language governing permissions and
+   limitations under the License.
+
+*/
+
+package echo
+
+import (
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/webx-top/echo/engine"
+)
+
+// AcceptQuality 带 q 值的 Accept 类头部条目
+type AcceptQuality struct {
+	Value string
+	Q     float64
+	index int
+}
+
+// ParseAcceptQuality 解析 Accept、Accept-Language、Accept-Encoding 等头部，
+// 按 q 值从高到低排序(q 值相同时保持头部中的顺序)
+func ParseAcceptQuality(header string) []*AcceptQuality {
+	parts := strings.Split(header, `,`)
+	r := make([]*AcceptQuality, 0, len(parts))
+	for i, part := range parts {
+		params := strings.Split(part, `;`)
+		value := strings.ToLower(strings.TrimSpace(params[0]))
+		if len(value) == 0 {
+			continue
+		}
+		q := 1.0
+		for _, p := range params[1:] {
+			kv := strings.SplitN(p, `=`, 2)
+			if len(kv) != 2 || strings.TrimSpace(kv[0]) != `q` {
+				continue
+			}
+			if f, err := strconv.ParseFloat(strings.TrimSpace(kv[1]), 64); err == nil {
+				if f < 0 {
+					f = 0
+				} else if f > 1 {
+					f = 1
+				}
+				q = f
+			}
+		}
+		r = append(r, &AcceptQuality{Value: value, Q: q, index: i})
+	}
+	sort.SliceStable(r, func(i, j int) bool {
+		return r[i].Q > r[j].Q
+	})
+	return r
+}
+
+// mediaRangeMatch 返回媒体范围与 mime 的匹配程度：
+// -1 不匹配；0 为 `*/*`；1 为 `type/*` 或结构化后缀(如 `+json`)；2 为完全匹配
+func mediaRangeMatch(mediaRange string, mime string) int {
+	if mediaRange == mime {
+		return 2
+	}
+	if mediaRange == `*/*` || mediaRange == `*` {
+		return 0
+	}
+	pos := strings.Index(mediaRange, `/`)
+	if pos < 0 {
+		return -1
+	}
+	typ, subtype := mediaRange[0:pos], mediaRange[pos+1:]
+	if !strings.HasPrefix(mime, typ+`/`) {
+		return -1
+	}
+	if subtype == `*` {
+		return 1
+	}
+	if plus := strings.LastIndex(subtype, `+`); plus > -1 {
+		if mime == typ+`/`+subtype[0:plus] {
+			return 2
+		}
+		if mime == typ+`/`+subtype[plus+1:] {
+			return 1
+		}
+	}
+	return -1
+}
+
+// SetFormatWeight 设置格式在内容协商中的服务端权重(默认为1)
+func (e *Echo) SetFormatWeight(format string, weight float64) *Echo {
+	if e.formatWeights == nil {
+		e.formatWeights = map[string]float64{}
+	}
+	e.formatWeights[format] = weight
+	return e
+}
+
+// StrictAccept 开启后，当 Accept 头部不接受任何已注册的格式时 Format() 返回空字符串，
+// 按协商的格式输出(自动渲染和 typed handler)时返回 406 错误
+func (e *Echo) StrictAccept(on bool) *Echo {
+	e.strictAccept = on
+	return e
+}
+
+func (e *Echo) defaultFormat() string {
+	if format, ok := e.acceptFormats[`*`]; ok {
+		return format
+	}
+	return `html`
+}
+
+func (e *Echo) formatWeight(format string) float64 {
+	if weight, ok := e.formatWeights[format]; ok {
+		return weight
+	}
+	return 1
+}
+
+type formatCandidate struct {
+	format string
+	mime   string
+	score  float64
+	spec   int
+	index  int
+}
+
+// better 比较两个候选格式：得分高者优先，其次是匹配更精确的、在 Accept 中更靠前的、默认格式
+func (a *formatCandidate) better(b *formatCandidate, defaultFormat string) bool {
+	if b == nil {
+		return true
+	}
+	if a.score != b.score {
+		return a.score > b.score
+	}
+	if a.spec != b.spec {
+		return a.spec > b.spec
+	}
+	if a.index != b.index {
+		return a.index < b.index
+	}
+	if aIsDefault, bIsDefault := a.format == defaultFormat, b.format == defaultFormat; aIsDefault != bIsDefault {
+		return aIsDefault
+	}
+	return a.mime < b.mime
+}
+
+// NegotiateFormat 根据 Accept 头部从 acceptFormats 中选择输出格式(RFC 7231 5.3.2)。
+// 第二个返回值为 false 时表示客户端不接受任何已注册的格式，此时返回默认格式
+func (e *Echo) NegotiateFormat(accept string) (string, bool) {
+	defaultFormat := e.defaultFormat()
+	ranges := ParseAcceptQuality(accept)
+	if len(ranges) == 0 {
+		return defaultFormat, true
+	}
+	var best *formatCandidate
+	for mime, format := range e.acceptFormats {
+		if mime == `*` || mime == `*/*` {
+			continue
+		}
+		var matched *AcceptQuality
+		spec := -1
+		for _, r := range ranges {
+			s := mediaRangeMatch(r.Value, mime)
+			if s > spec || (s == spec && s > -1 && r.index < matched.index) {
+				spec = s
+				matched = r
+			}
+		}
+		if matched == nil {
+			continue
+		}
+		c := &formatCandidate{
+			format: format,
+			mime:   mime,
+			score:  matched.Q * e.formatWeight(format),
+			spec:   spec,
+			index:  matched.index,
+		}
+		if c.score <= 0 {
+			continue
+		}
+		if c.better(best, defaultFormat) {
+			best = c
+		}
+	}
+	if best == nil {
+		return defaultFormat, false
+	}
+	return best.format, true
+}
+
+// AddVary 向 Vary 头部添加字段(已存在的字段不会重复添加)
+func AddVary(header engine.Header, fields ...string) {
+	var existing []string
+	for _, v := range header.Std()[HeaderVary] {
+		for _, f := range strings.Split(v, `,`) {
+			f = strings.TrimSpace(f)
+			if f == `*` {
+				return
+			}
+			existing = append(existing, strings.ToLower(f))
+		}
+	}
+	for _, field := range fields {
+		lower := strings.ToLower(field)
+		var found bool
+		for _, f := range existing {
+			if f == lower {
+				found = true
+				break
+			}
+		}
+		if found {
+			continue
+		}
+		header.Add(HeaderVary, field)
+		existing = append(existing, lower)
+	}
+}
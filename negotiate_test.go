@@ -0,0 +1,80 @@
+package echo_test
+
+import (
+	"io/ioutil"
+	"net/http"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	. "github.com/webx-top/echo"
+	test "github.com/webx-top/echo/testing"
+)
+
+func TestStrictAccept(t *testing.T) {
+	dir, err := ioutil.TempDir(``, `echo-negotiate-test`)
+	assert.NoError(t, err)
+	defer os.RemoveAll(dir)
+	assert.NoError(t, ioutil.WriteFile(filepath.Join(dir, `app.js`), []byte(`js`), 0644))
+
+	e := New()
+	e.StrictAccept(true)
+	e.Get(`/data`, func(c Context) error {
+		c.SetAuto(true)
+		return c.Render(``, H{`ok`: true})
+	})
+	e.Get(`/redirect`, func(c Context) error {
+		return c.Redirect(`/data`)
+	})
+	var resolved int
+	e.Get(`/format`, func(c Context) error {
+		for i := 0; i < 3; i++ {
+			if len(c.Format()) > 0 {
+				resolved++
+			}
+		}
+		return c.String(c.Format())
+	})
+	e.Static(`/static`, dir)
+	e.RebuildRouter()
+	accept := func(value string) func(*http.Request) {
+		return func(req *http.Request) {
+			req.Header.Set(HeaderAccept, value)
+		}
+	}
+
+	rec := test.Request(GET, `/data`, e, accept(`application/pdf`))
+	assert.Equal(t, http.StatusNotAcceptable, rec.Code)
+	assert.Equal(t, HeaderAccept, rec.Header().Get(HeaderVary))
+	rec = test.Request(GET, `/data`, e, accept(`application/json`))
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.Contains(t, rec.Header().Get(HeaderContentType), MIMEApplicationJSON)
+
+	// 不使用协商格式的响应不受影响
+	rec = test.Request(GET, `/redirect`, e, accept(`application/pdf`))
+	assert.Equal(t, http.StatusFound, rec.Code)
+	rec = test.Request(GET, `/static/app.js`, e, accept(`application/pdf`))
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.Equal(t, `js`, rec.Body.String())
+	assert.Empty(t, rec.Header().Get(HeaderVary))
+	rec = test.Request(GET, `/none`, e, accept(`application/pdf`))
+	assert.Equal(t, http.StatusNotFound, rec.Code)
+	assert.Empty(t, rec.Header().Get(HeaderVary))
+
+	// 按 q 值协商，每个请求只协商一次
+	rec = test.Request(GET, `/format`, e, accept(`application/xml;q=0.1, application/json`))
+	assert.Equal(t, `json`, rec.Body.String())
+	assert.Equal(t, []string{HeaderAccept}, rec.Header()[HeaderVary])
+	assert.Equal(t, 3, resolved)
+	assert.Empty(t, test.Request(GET, `/format`, e, accept(`application/pdf`)).Body.String())
+	assert.Equal(t, `html`, test.Request(GET, `/format`, e, accept(`application/pdf, */*`)).Body.String())
+
+	// ParseHeaderAccept 不影响格式的协商
+	e.ParseHeaderAccept(true)
+	rec = test.Request(GET, `/format`, e, accept(`application/xml;q=0.1, application/json`))
+	assert.Equal(t, `json`, rec.Body.String())
+	rec = test.Request(GET, `/format`, e, accept(`text/*;q=0.5, application/xml`))
+	assert.Equal(t, `xml`, rec.Body.String())
+}
@@ -0,0 +1,665 @@
+/*
+
+   Copyright 2016-present Wenhui Shen <www.webx.top>
+
+   Licensed under the Apache License, Version 2.0 (the "License");
+   you may not use this file except in compliance with the License.
+   You may obtain a copy of the License at
+
+       http://www.apache.org/licenses/LICENSE-2.0
+
+   Unless required by applicable law or agreed to in writing, software
+   distributed under the License is distributed on an "AS IS" BASIS,
+   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+   See the License for the specific language governing permissions and
+   limitations under the License.
+
+*/
+
+package echo
+
+import (
+	"bytes"
+	stdjson "encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/webx-top/echo/encoding/json"
+)
+
+// patchChangesKey PatchChanges 在 Context.Internal() 中的键名
+const patchChangesKey = `__patchChanges`
+
+// PatchOperation RFC 6902 JSON Patch 中的一项操作
+type PatchOperation struct {
+	Op    string             `json:"op"`
+	Path  string             `json:"path"`
+	From  string             `json:"from,omitempty"`
+	Value stdjson.RawMessage `json:"value,omitempty"`
+}
+
+// PatchChanges 打补丁后发生变化的位置(JSON Pointer 格式，例如 `/address/city`)
+type PatchChanges []string
+
+// Has 指定位置或其下级是否发生了变化
+func (p PatchChanges) Has(pointer string) bool {
+	for _, v := range p {
+		if v == pointer || strings.HasPrefix(v, pointer+`/`) {
+			return true
+		}
+	}
+	return false
+}
+
+// Fields 发生变化的顶级字段名(JSON 字段名)
+func (p PatchChanges) Fields() []string {
+	var fields []string
+	seen := map[string]struct{}{}
+	for _, v := range p {
+		tokens, _ := parseJSONPointer(v)
+		if len(tokens) == 0 {
+			continue
+		}
+		if _, ok := seen[tokens[0]]; ok {
+			continue
+		}
+		seen[tokens[0]] = struct{}{}
+		fields = append(fields, tokens[0])
+	}
+	return fields
+}
+
+// GetPatchChanges 获取本次请求中通过 JSON Patch 或 Merge Patch 绑定时发生变化的位置
+func GetPatchChanges(c Context) PatchChanges {
+	v, _ := c.Internal().Get(patchChangesKey).(PatchChanges)
+	return v
+}
+
+// PatchObject 将对象(结构体指针或 map)转换为 JSON 文档，调用 apply 打补丁后再写回对象，返回发生变化的位置。
+// 补丁应用失败时对象保持不变
+func PatchObject(target interface{}, apply func(doc interface{}) (interface{}, error)) (PatchChanges, error) {
+	rv := reflect.ValueOf(target)
+	if (rv.Kind() != reflect.Map && rv.Kind() != reflect.Ptr) || rv.IsNil() {
+		return nil, fmt.Errorf(`patch: unsupported target %T`, target)
+	}
+	b, err := json.Marshal(target)
+	if err != nil {
+		return nil, err
+	}
+	doc, err := decodeJSONDocument(bytes.NewReader(b))
+	if err != nil {
+		return nil, err
+	}
+	patched, err := apply(patchDeepCopy(doc))
+	if err != nil {
+		return nil, err
+	}
+	var changes, removed PatchChanges
+	patchDiff(doc, patched, ``, &changes, &removed)
+	if len(changes) == 0 {
+		return changes, nil
+	}
+	b, err = json.Marshal(patched)
+	if err != nil {
+		return nil, err
+	}
+	// 在副本上打补丁，成功后再写回对象，避免失败时对象只被修改了一部分
+	ptr := rv
+	if rv.Kind() == reflect.Map {
+		ptr = reflect.New(rv.Type())
+		ptr.Elem().Set(rv)
+	}
+	copied := reflect.New(ptr.Type().Elem())
+	copied.Elem().Set(patchCopyValue(ptr.Elem()))
+	for _, pointer := range removed {
+		tokens, _ := parseJSONPointer(pointer)
+		zeroJSONPath(copied.Elem(), tokens)
+	}
+	if err = json.Unmarshal(b, copied.Interface()); err != nil {
+		return nil, NewHTTPError(http.StatusUnprocessableEntity, err.Error())
+	}
+	if rv.Kind() == reflect.Map {
+		for _, key := range rv.MapKeys() {
+			rv.SetMapIndex(key, reflect.Value{})
+		}
+		iter := copied.Elem().MapRange()
+		for iter.Next() {
+			rv.SetMapIndex(iter.Key(), iter.Value())
+		}
+	} else {
+		rv.Elem().Set(copied.Elem())
+	}
+	return changes, nil
+}
+
+// patchCopyValue 深拷贝值中的指针、map 和 slice，未导出的字段按值复制
+func patchCopyValue(v reflect.Value) reflect.Value {
+	switch v.Kind() {
+	case reflect.Ptr:
+		if v.IsNil() {
+			return v
+		}
+		r := reflect.New(v.Type().Elem())
+		r.Elem().Set(patchCopyValue(v.Elem()))
+		return r
+	case reflect.Interface:
+		if v.IsNil() {
+			return v
+		}
+		r := reflect.New(v.Type()).Elem()
+		r.Set(patchCopyValue(v.Elem()))
+		return r
+	case reflect.Map:
+		if v.IsNil() {
+			return v
+		}
+		r := reflect.MakeMapWithSize(v.Type(), v.Len())
+		iter := v.MapRange()
+		for iter.Next() {
+			r.SetMapIndex(iter.Key(), patchCopyValue(iter.Value()))
+		}
+		return r
+	case reflect.Slice:
+		if v.IsNil() {
+			return v
+		}
+		r := reflect.MakeSlice(v.Type(), v.Len(), v.Len())
+		for i := 0; i < v.Len(); i++ {
+			r.Index(i).Set(patchCopyValue(v.Index(i)))
+		}
+		return r
+	case reflect.Array:
+		r := reflect.New(v.Type()).Elem()
+		for i := 0; i < v.Len(); i++ {
+			r.Index(i).Set(patchCopyValue(v.Index(i)))
+		}
+		return r
+	case reflect.Struct:
+		r := reflect.New(v.Type()).Elem()
+		r.Set(v)
+		for i := 0; i < v.NumField(); i++ {
+			if field := r.Field(i); field.CanSet() {
+				field.Set(patchCopyValue(v.Field(i)))
+			}
+		}
+		return r
+	}
+	return v
+}
+
+// ApplyJSONPatch 对 JSON 文档(由 map[string]interface{}、[]interface{} 等组成)应用 RFC 6902 JSON Patch
+func ApplyJSONPatch(doc interface{}, ops []*PatchOperation) (interface{}, error) {
+	var err error
+	for _, op := range ops {
+		doc, err = applyPatchOperation(doc, op)
+		if err != nil {
+			return nil, err
+		}
+	}
+	return doc, nil
+}
+
+// ApplyMergePatch 对 JSON 文档应用 RFC 7396 JSON Merge Patch
+func ApplyMergePatch(doc interface{}, patch interface{}) interface{} {
+	pm, ok := patch.(map[string]interface{})
+	if !ok {
+		return patch
+	}
+	dm, ok := doc.(map[string]interface{})
+	if !ok {
+		dm = map[string]interface{}{}
+	}
+	for k, v := range pm {
+		if v == nil {
+			delete(dm, k)
+			continue
+		}
+		dm[k] = ApplyMergePatch(dm[k], v)
+	}
+	return dm
+}
+
+func bindJSONPatch(i interface{}, ctx Context, filter ...FormDataFilter) error {
+	body := ctx.Request().Body()
+	if body == nil {
+		return NewHTTPError(http.StatusBadRequest, "Request body can't be nil")
+	}
+	defer body.Close()
+	var ops []*PatchOperation
+	if err := json.NewDecoder(body).Decode(&ops); err != nil {
+		return NewHTTPError(http.StatusBadRequest, err.Error())
+	}
+	changes, err := PatchObject(i, func(doc interface{}) (interface{}, error) {
+		return ApplyJSONPatch(doc, ops)
+	})
+	if err != nil {
+		return err
+	}
+	ctx.Internal().Set(patchChangesKey, changes)
+	return nil
+}
+
+func bindMergePatch(i interface{}, ctx Context, filter ...FormDataFilter) error {
+	body := ctx.Request().Body()
+	if body == nil {
+		return NewHTTPError(http.StatusBadRequest, "Request body can't be nil")
+	}
+	defer body.Close()
+	patch, err := decodeJSONDocument(body)
+	if err != nil {
+		return NewHTTPError(http.StatusBadRequest, err.Error())
+	}
+	changes, err := PatchObject(i, func(doc interface{}) (interface{}, error) {
+		return ApplyMergePatch(doc, patch), nil
+	})
+	if err != nil {
+		return err
+	}
+	ctx.Internal().Set(patchChangesKey, changes)
+	return nil
+}
+
+// decodeJSONDocument 使用标准库解码，数字保留为 json.Number 以免丢失精度
+func decodeJSONDocument(r io.Reader) (interface{}, error) {
+	var doc interface{}
+	dec := stdjson.NewDecoder(r)
+	dec.UseNumber()
+	err := dec.Decode(&doc)
+	return doc, err
+}
+
+func patchError(op *PatchOperation, format string, args ...interface{}) error {
+	return NewHTTPError(http.StatusUnprocessableEntity, fmt.Sprintf(`patch %s %q: `, op.Op, op.Path)+fmt.Sprintf(format, args...))
+}
+
+func applyPatchOperation(doc interface{}, op *PatchOperation) (interface{}, error) {
+	path, err := parseJSONPointer(op.Path)
+	if err != nil {
+		return nil, patchError(op, `%v`, err)
+	}
+	var value interface{}
+	switch op.Op {
+	case `add`, `replace`, `test`:
+		if op.Value == nil {
+			return nil, patchError(op, `missing value`)
+		}
+		if value, err = decodeJSONDocument(bytes.NewReader(op.Value)); err != nil {
+			return nil, patchError(op, `%v`, err)
+		}
+	}
+	switch op.Op {
+	case `add`:
+		return patchAdd(doc, path, value)
+	case `remove`:
+		return patchRemove(doc, path)
+	case `replace`:
+		if _, err = patchGet(doc, path); err != nil {
+			return nil, patchError(op, `%v`, err)
+		}
+		return patchSet(doc, path, value)
+	case `move`, `copy`:
+		from, err := parseJSONPointer(op.From)
+		if err != nil {
+			return nil, patchError(op, `%v`, err)
+		}
+		value, err = patchGet(doc, from)
+		if err != nil {
+			return nil, patchError(op, `%v`, err)
+		}
+		if op.Op == `copy` {
+			return patchAdd(doc, path, patchDeepCopy(value))
+		}
+		if op.Path == op.From {
+			return doc, nil
+		}
+		if strings.HasPrefix(op.Path, op.From+`/`) {
+			return nil, patchError(op, `can not move a value into one of its children`)
+		}
+		if doc, err = patchRemove(doc, from); err != nil {
+			return nil, err
+		}
+		return patchAdd(doc, path, value)
+	case `test`:
+		actual, err := patchGet(doc, path)
+		if err != nil {
+			return nil, patchError(op, `%v`, err)
+		}
+		if !jsonValueEqual(actual, value) {
+			return nil, NewHTTPError(http.StatusConflict, fmt.Sprintf(`patch test %q: value mismatch`, op.Path))
+		}
+		return doc, nil
+	}
+	return nil, NewHTTPError(http.StatusBadRequest, fmt.Sprintf(`patch: invalid operation %q`, op.Op))
+}
+
+// parseJSONPointer 解析 RFC 6901 JSON Pointer
+func parseJSONPointer(pointer string) ([]string, error) {
+	if len(pointer) == 0 {
+		return nil, nil
+	}
+	if pointer[0] != '/' {
+		return nil, fmt.Errorf(`invalid JSON pointer %q`, pointer)
+	}
+	tokens := strings.Split(pointer[1:], `/`)
+	for i, token := range tokens {
+		tokens[i] = strings.Replace(strings.Replace(token, `~1`, `/`, -1), `~0`, `~`, -1)
+	}
+	return tokens, nil
+}
+
+func formatJSONPointer(prefix string, token string) string {
+	return prefix + `/` + strings.Replace(strings.Replace(token, `~`, `~0`, -1), `/`, `~1`, -1)
+}
+
+func patchIndex(token string, length int, allowEnd bool) (int, error) {
+	if allowEnd && token == `-` {
+		return length, nil
+	}
+	idx, err := strconv.Atoi(token)
+	if err != nil || idx < 0 || (len(token) > 1 && token[0] == '0') {
+		return 0, fmt.Errorf(`invalid array index %q`, token)
+	}
+	max := length - 1
+	if allowEnd {
+		max = length
+	}
+	if idx > max {
+		return 0, fmt.Errorf(`array index %d out of bounds`, idx)
+	}
+	return idx, nil
+}
+
+func patchGet(doc interface{}, path []string) (interface{}, error) {
+	for _, token := range path {
+		switch v := doc.(type) {
+		case map[string]interface{}:
+			var ok bool
+			if doc, ok = v[token]; !ok {
+				return nil, fmt.Errorf(`member %q not found`, token)
+			}
+		case []interface{}:
+			idx, err := patchIndex(token, len(v), false)
+			if err != nil {
+				return nil, err
+			}
+			doc = v[idx]
+		default:
+			return nil, fmt.Errorf(`can not resolve %q`, token)
+		}
+	}
+	return doc, nil
+}
+
+// patchSet 替换指定位置的值(map 中不存在的成员会被添加)
+func patchSet(doc interface{}, path []string, value interface{}) (interface{}, error) {
+	if len(path) == 0 {
+		return value, nil
+	}
+	parent, err := patchGet(doc, path[:len(path)-1])
+	if err != nil {
+		return nil, NewHTTPError(http.StatusUnprocessableEntity, err.Error())
+	}
+	key := path[len(path)-1]
+	switch p := parent.(type) {
+	case map[string]interface{}:
+		p[key] = value
+	case []interface{}:
+		idx, err := patchIndex(key, len(p), false)
+		if err != nil {
+			return nil, NewHTTPError(http.StatusUnprocessableEntity, err.Error())
+		}
+		p[idx] = value
+	default:
+		return nil, NewHTTPError(http.StatusUnprocessableEntity, fmt.Sprintf(`can not set %q`, key))
+	}
+	return doc, nil
+}
+
+func patchAdd(doc interface{}, path []string, value interface{}) (interface{}, error) {
+	if len(path) == 0 {
+		return value, nil
+	}
+	parentPath := path[:len(path)-1]
+	parent, err := patchGet(doc, parentPath)
+	if err != nil {
+		return nil, NewHTTPError(http.StatusUnprocessableEntity, err.Error())
+	}
+	p, ok := parent.([]interface{})
+	if !ok {
+		return patchSet(doc, path, value)
+	}
+	idx, err := patchIndex(path[len(path)-1], len(p), true)
+	if err != nil {
+		return nil, NewHTTPError(http.StatusUnprocessableEntity, err.Error())
+	}
+	s := make([]interface{}, 0, len(p)+1)
+	s = append(s, p[:idx]...)
+	s = append(s, value)
+	s = append(s, p[idx:]...)
+	return patchSet(doc, parentPath, s)
+}
+
+func patchRemove(doc interface{}, path []string) (interface{}, error) {
+	if len(path) == 0 {
+		return nil, nil
+	}
+	parentPath := path[:len(path)-1]
+	parent, err := patchGet(doc, parentPath)
+	if err != nil {
+		return nil, NewHTTPError(http.StatusUnprocessableEntity, err.Error())
+	}
+	key := path[len(path)-1]
+	switch p := parent.(type) {
+	case map[string]interface{}:
+		if _, ok := p[key]; !ok {
+			return nil, NewHTTPError(http.StatusUnprocessableEntity, fmt.Sprintf(`member %q not found`, key))
+		}
+		delete(p, key)
+		return doc, nil
+	case []interface{}:
+		idx, err := patchIndex(key, len(p), false)
+		if err != nil {
+			return nil, NewHTTPError(http.StatusUnprocessableEntity, err.Error())
+		}
+		s := make([]interface{}, 0, len(p)-1)
+		s = append(s, p[:idx]...)
+		s = append(s, p[idx+1:]...)
+		return patchSet(doc, parentPath, s)
+	}
+	return nil, NewHTTPError(http.StatusUnprocessableEntity, fmt.Sprintf(`can not remove %q`, key))
+}
+
+func patchDeepCopy(doc interface{}) interface{} {
+	switch v := doc.(type) {
+	case map[string]interface{}:
+		m := make(map[string]interface{}, len(v))
+		for k, val := range v {
+			m[k] = patchDeepCopy(val)
+		}
+		return m
+	case []interface{}:
+		s := make([]interface{}, len(v))
+		for k, val := range v {
+			s[k] = patchDeepCopy(val)
+		}
+		return s
+	}
+	return doc
+}
+
+// jsonValueEqual 比较两个 JSON 值是否相等(数值按大小比较)
+func jsonValueEqual(a, b interface{}) bool {
+	switch x := a.(type) {
+	case map[string]interface{}:
+		y, ok := b.(map[string]interface{})
+		if !ok || len(x) != len(y) {
+			return false
+		}
+		for k, v := range x {
+			w, ok := y[k]
+			if !ok || !jsonValueEqual(v, w) {
+				return false
+			}
+		}
+		return true
+	case []interface{}:
+		y, ok := b.([]interface{})
+		if !ok || len(x) != len(y) {
+			return false
+		}
+		for k := range x {
+			if !jsonValueEqual(x[k], y[k]) {
+				return false
+			}
+		}
+		return true
+	case stdjson.Number:
+		y, ok := b.(stdjson.Number)
+		if !ok {
+			return false
+		}
+		if x == y {
+			return true
+		}
+		fx, errX := x.Float64()
+		fy, errY := y.Float64()
+		return errX == nil && errY == nil && fx == fy
+	}
+	return a == b
+}
+
+func patchDiff(before, after interface{}, prefix string, changes *PatchChanges, removed *PatchChanges) {
+	bm, ok1 := before.(map[string]interface{})
+	am, ok2 := after.(map[string]interface{})
+	if !ok1 || !ok2 {
+		if !jsonValueEqual(before, after) {
+			*changes = append(*changes, prefix)
+		}
+		return
+	}
+	keys := make([]string, 0, len(bm)+len(am))
+	for k := range bm {
+		keys = append(keys, k)
+	}
+	for k := range am {
+		if _, ok := bm[k]; !ok {
+			keys = append(keys, k)
+		}
+	}
+	sort.Strings(keys)
+	for _, k := range keys {
+		pointer := formatJSONPointer(prefix, k)
+		bv, inBefore := bm[k]
+		av, inAfter := am[k]
+		switch {
+		case !inAfter:
+			*changes = append(*changes, pointer)
+			*removed = append(*removed, pointer)
+		case !inBefore:
+			*changes = append(*changes, pointer)
+		default:
+			patchDiff(bv, av, pointer, changes, removed)
+		}
+	}
+}
+
+// zeroJSONPath 将 JSON 路径对应的结构体字段设置为零值或删除 map 中的成员。
+// json.Unmarshal 不会清除文档中不存在的字段，所以被删除的成员需要单独处理
+func zeroJSONPath(v reflect.Value, path []string) {
+	for len(path) > 0 {
+		for v.Kind() == reflect.Ptr || v.Kind() == reflect.Interface {
+			if v.IsNil() {
+				return
+			}
+			v = v.Elem()
+		}
+		token := path[0]
+		switch v.Kind() {
+		case reflect.Map:
+			key := reflect.ValueOf(token)
+			if !key.Type().ConvertibleTo(v.Type().Key()) {
+				return
+			}
+			key = key.Convert(v.Type().Key())
+			if len(path) == 1 {
+				v.SetMapIndex(key, reflect.Value{})
+				return
+			}
+			elem := v.MapIndex(key)
+			if !elem.IsValid() {
+				return
+			}
+			if elem.Kind() != reflect.Ptr && elem.Kind() != reflect.Map && elem.Kind() != reflect.Interface {
+				// map 中的结构体值不可寻址
+				copied := reflect.New(elem.Type()).Elem()
+				copied.Set(elem)
+				zeroJSONPath(copied, path[1:])
+				v.SetMapIndex(key, copied)
+				return
+			}
+			v = elem
+		case reflect.Struct:
+			field, ok := jsonField(v, token)
+			if !ok {
+				return
+			}
+			if len(path) == 1 {
+				if field.CanSet() {
+					field.Set(reflect.Zero(field.Type()))
+				}
+				return
+			}
+			v = field
+		default:
+			return
+		}
+		path = path[1:]
+	}
+}
+
+// jsonField 按 JSON 字段名查找结构体字段(与 encoding/json 一样支持匿名嵌入字段和不区分大小写的匹配)
+func jsonField(v reflect.Value, name string) (reflect.Value, bool) {
+	t := v.Type()
+	var fold reflect.Value
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		tag := f.Tag.Get(`json`)
+		if tag == `-` {
+			continue
+		}
+		if len(f.PkgPath) > 0 && !f.Anonymous {
+			continue
+		}
+		fieldName := strings.SplitN(tag, `,`, 2)[0]
+		if f.Anonymous && len(fieldName) == 0 {
+			fv := v.Field(i)
+			if fv.Kind() == reflect.Ptr {
+				if fv.IsNil() {
+					continue
+				}
+				fv = fv.Elem()
+			}
+			if fv.Kind() == reflect.Struct {
+				if r, ok := jsonField(fv, name); ok {
+					return r, true
+				}
+			}
+			continue
+		}
+		if len(fieldName) == 0 {
+			fieldName = f.Name
+		}
+		if fieldName == name {
+			return v.Field(i), true
+		}
+		if !fold.IsValid() && strings.EqualFold(fieldName, name) {
+			fold = v.Field(i)
+		}
+	}
+	return fold, fold.IsValid()
+}
@@ -0,0 +1,95 @@
+package echo
+
+import (
+	"encoding/json"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type testPatchAddress struct {
+	City string `json:"city"`
+	Zip  string `json:"zip,omitempty"`
+}
+
+type testPatchUser struct {
+	ID      int               `json:"-"`
+	Name    string            `json:"name"`
+	Age     int               `json:"age"`
+	Tags    []string          `json:"tags"`
+	Address *testPatchAddress `json:"address,omitempty"`
+}
+
+func TestApplyJSONPatch(t *testing.T) {
+	doc, _ := decodeJSONDocument(strings.NewReader(`{"foo":["bar","baz"],"a":{"b":{"c":1}}}`))
+	var ops []*PatchOperation
+	json.Unmarshal([]byte(`[
+		{"op":"test","path":"/a/b/c","value":1.0},
+		{"op":"add","path":"/foo/1","value":"qux"},
+		{"op":"remove","path":"/foo/0"},
+		{"op":"replace","path":"/a/b/c","value":42},
+		{"op":"move","from":"/a/b/c","path":"/a/d"},
+		{"op":"copy","from":"/foo","path":"/bar"},
+		{"op":"add","path":"/bar/-","value":"m~n"}
+	]`), &ops)
+	doc, err := ApplyJSONPatch(doc, ops)
+	assert.NoError(t, err)
+	b, _ := json.Marshal(doc)
+	assert.Equal(t, `{"a":{"b":{},"d":42},"bar":["qux","baz","m~n"],"foo":["qux","baz"]}`, string(b))
+
+	_, err = ApplyJSONPatch(doc, []*PatchOperation{{Op: `test`, Path: `/a/d`, Value: json.RawMessage(`1`)}})
+	assert.Equal(t, http.StatusConflict, err.(*HTTPError).Code)
+	_, err = ApplyJSONPatch(doc, []*PatchOperation{{Op: `replace`, Path: `/none`, Value: json.RawMessage(`1`)}})
+	assert.Equal(t, http.StatusUnprocessableEntity, err.(*HTTPError).Code)
+	_, err = ApplyJSONPatch(doc, []*PatchOperation{{Op: `add`, Path: `/foo/5`, Value: json.RawMessage(`1`)}})
+	assert.Error(t, err)
+	_, err = ApplyJSONPatch(doc, []*PatchOperation{{Op: `move`, From: `/a`, Path: `/a/b/x`}})
+	assert.Error(t, err)
+
+	tokens, _ := parseJSONPointer(`/a~1b/m~0n`)
+	assert.Equal(t, []string{`a/b`, `m~n`}, tokens)
+}
+
+func TestPatchObject(t *testing.T) {
+	user := &testPatchUser{ID: 7, Name: `echo`, Age: 3, Tags: []string{`a`}, Address: &testPatchAddress{City: `x`, Zip: `1`}}
+	patch, _ := decodeJSONDocument(strings.NewReader(`{"age":4,"tags":["a","b"],"address":{"zip":null},"name":"echo"}`))
+	changes, err := PatchObject(user, func(doc interface{}) (interface{}, error) {
+		return ApplyMergePatch(doc, patch), nil
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, PatchChanges{`/address/zip`, `/age`, `/tags`}, changes)
+	assert.Equal(t, []string{`address`, `age`, `tags`}, changes.Fields())
+	assert.True(t, changes.Has(`/address`))
+	assert.False(t, changes.Has(`/name`))
+	assert.Equal(t, &testPatchUser{ID: 7, Name: `echo`, Age: 4, Tags: []string{`a`, `b`}, Address: &testPatchAddress{City: `x`}}, user)
+
+	_, err = PatchObject(user, func(doc interface{}) (interface{}, error) {
+		return ApplyJSONPatch(doc, []*PatchOperation{{Op: `remove`, Path: `/address`}, {Op: `test`, Path: `/age`, Value: json.RawMessage(`5`)}})
+	})
+	assert.Error(t, err)
+	assert.NotNil(t, user.Address)
+
+	// 类型不匹配时对象保持不变(包括被删除的字段和嵌套的结构体)
+	before := &testPatchUser{ID: 7, Name: `echo`, Age: 4, Tags: []string{`a`, `b`}, Address: &testPatchAddress{City: `x`}}
+	address := user.Address
+	_, err = PatchObject(user, func(doc interface{}) (interface{}, error) {
+		return ApplyJSONPatch(doc, []*PatchOperation{
+			{Op: `remove`, Path: `/tags`},
+			{Op: `replace`, Path: `/address/city`, Value: json.RawMessage(`"y"`)},
+			{Op: `replace`, Path: `/age`, Value: json.RawMessage(`"old"`)},
+		})
+	})
+	assert.Equal(t, http.StatusUnprocessableEntity, err.(*HTTPError).Code)
+	assert.Equal(t, before, user)
+	assert.True(t, address == user.Address)
+
+	m := H{`a`: 1, `b`: 2}
+	changes, err = PatchObject(m, func(doc interface{}) (interface{}, error) {
+		return ApplyJSONPatch(doc, []*PatchOperation{{Op: `remove`, Path: `/a`}})
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, PatchChanges{`/a`}, changes)
+	assert.Equal(t, H{`b`: float64(2)}, m)
+}
@@ -0,0 +1,282 @@
+/*
+
+   Copyright 2016-present Wenhui Shen <www.webx.top>
+
+   Licensed under the Apache License, Version 2.0 (the "License");
+   you may not use this file except in compliance with the License.
+   You may obtain a copy of the License at
+
+       http://www.apache.org/licenses/LICENSE-2.0
+
+   Unless required by applicable law or agreed to in writing, software
+   distributed under the License is distributed on an "AS IS" BASIS,
+   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+   See the License for the specific language governing permissions and
+   limitations under the License.
+
+*/
+
+package echo
+
+import (
+	"encoding/xml"
+	"net/http"
+	"sort"
+
+	"github.com/webx-top/echo/encoding/json"
+	"github.com/webx-top/echo/param"
+)
+
+const (
+	// ProblemXMLNamespace RFC 7807 XML 格式的命名空间
+	ProblemXMLNamespace = `urn:ietf:rfc:7807`
+	// ProblemTypeValidation 验证错误的问题类型名称，与 ProblemTypeBase 拼接为完整的 URI
+	ProblemTypeValidation = `validation-error`
+)
+
+// ProblemTypeBase 问题类型 URI 的前缀(例如 `https://example.com/problems/`)，
+// 应指向说明问题类型的文档。为空时不输出内置问题的 type，按 RFC 7807 等同于 `about:blank`
+var ProblemTypeBase = ``
+
+// ProblemType 返回 ProblemTypeBase 与 name 拼接的问题类型 URI，ProblemTypeBase 为空时返回空字符串
+func ProblemType(name string) string {
+	if len(ProblemTypeBase) == 0 {
+		return ``
+	}
+	return ProblemTypeBase + name
+}
+
+// NewProblem 创建 RFC 7807 问题详情错误
+func NewProblem(status int, detail ...string) *ProblemError {
+	p := &ProblemError{
+		Status: status,
+		Title:  http.StatusText(status),
+	}
+	if len(detail) > 0 {
+		p.Detail = detail[0]
+	}
+	return p
+}
+
+// ProblemError RFC 7807 问题详情(application/problem+json)
+type ProblemError struct {
+	Type       string // 问题类型 URI
+	Title      string
+	Status     int
+	Detail     string
+	Instance   string // 本次问题的 URI
+	Code       string // 业务错误码
+	Extensions map[string]interface{}
+	err        error
+}
+
+// Error returns detail or title.
+func (p *ProblemError) Error() string {
+	if len(p.Detail) > 0 {
+		return p.Detail
+	}
+	if len(p.Title) > 0 {
+		return p.Title
+	}
+	return http.StatusText(p.Status)
+}
+
+// Unwrap returns the wrapped error.
+func (p *ProblemError) Unwrap() error {
+	return p.err
+}
+
+func (p *ProblemError) Wrap(err error) *ProblemError {
+	p.err = err
+	return p
+}
+
+func (p *ProblemError) SetType(typ string) *ProblemError {
+	p.Type = typ
+	return p
+}
+
+func (p *ProblemError) SetTitle(title string) *ProblemError {
+	p.Title = title
+	return p
+}
+
+func (p *ProblemError) SetDetail(detail string) *ProblemError {
+	p.Detail = detail
+	return p
+}
+
+func (p *ProblemError) SetInstance(instance string) *ProblemError {
+	p.Instance = instance
+	return p
+}
+
+func (p *ProblemError) SetCode(code string) *ProblemError {
+	p.Code = code
+	return p
+}
+
+// Set 设置扩展字段
+func (p *ProblemError) Set(key string, value interface{}) *ProblemError {
+	if p.Extensions == nil {
+		p.Extensions = map[string]interface{}{}
+	}
+	p.Extensions[key] = value
+	return p
+}
+
+// Map 转换为 map，扩展字段不会覆盖标准字段
+func (p *ProblemError) Map() map[string]interface{} {
+	m := make(map[string]interface{}, len(p.Extensions)+6)
+	for k, v := range p.Extensions {
+		m[k] = v
+	}
+	if len(p.Type) > 0 {
+		m[`type`] = p.Type
+	}
+	if len(p.Title) > 0 {
+		m[`title`] = p.Title
+	}
+	if p.Status > 0 {
+		m[`status`] = p.Status
+	}
+	if len(p.Detail) > 0 {
+		m[`detail`] = p.Detail
+	}
+	if len(p.Instance) > 0 {
+		m[`instance`] = p.Instance
+	}
+	if len(p.Code) > 0 {
+		m[`code`] = p.Code
+	}
+	return m
+}
+
+func (p *ProblemError) MarshalJSON() ([]byte, error) {
+	return json.Marshal(p.Map())
+}
+
+func (p *ProblemError) MarshalXML(e *xml.Encoder, start xml.StartElement) error {
+	start.Name = xml.Name{Space: ProblemXMLNamespace, Local: `problem`}
+	if err := e.EncodeToken(start); err != nil {
+		return err
+	}
+	m := p.Map()
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	for _, k := range keys {
+		v := m[k]
+		if mv, ok := v.(map[string]interface{}); ok {
+			v = param.Store(mv)
+		}
+		if err := e.EncodeElement(v, xml.StartElement{Name: xml.Name{Local: k}}); err != nil {
+			return err
+		}
+	}
+	return e.EncodeToken(xml.EndElement{Name: start.Name})
+}
+
+// AsProblem 将错误转换为问题详情。
+// 验证错误转换为 422 并在 `errors` 扩展字段中列出全部字段错误；
+// 未知错误转换为 500，仅在调试模式下输出错误信息
+func AsProblem(err error, debug ...bool) *ProblemError {
+	if err == nil {
+		return nil
+	}
+	for e := err; e != nil; {
+		switch v := e.(type) {
+		case *ProblemError:
+			return v
+		case FieldErrors:
+			return validationProblem(v).Wrap(err)
+		case *FieldError:
+			// ValidateResult.Error() 只返回第一个字段错误，使用同一次验证的全部错误
+			errs := v.all
+			if len(errs) == 0 {
+				errs = FieldErrors{v}
+			}
+			return validationProblem(errs).Wrap(err)
+		case *HTTPError:
+			p := NewProblem(v.Code).Wrap(err)
+			if v.Message != p.Title {
+				p.Detail = v.Message
+			}
+			return p
+		}
+		u, ok := e.(interface {
+			Unwrap() error
+		})
+		if !ok {
+			break
+		}
+		e = u.Unwrap()
+	}
+	p := NewProblem(http.StatusInternalServerError).Wrap(err)
+	if len(debug) > 0 && debug[0] {
+		p.Detail = err.Error()
+	}
+	return p
+}
+
+func validationProblem(errs FieldErrors) *ProblemError {
+	p := NewProblem(http.StatusUnprocessableEntity).SetType(ProblemType(ProblemTypeValidation))
+	if len(errs) > 0 {
+		p.Detail = errs[0].Error()
+	}
+	return p.Set(`errors`, errs)
+}
+
+// RenderProblem 以 application/problem+json 或 application/problem+xml 格式输出问题详情
+func RenderProblem(c Context, p *ProblemError, format string) error {
+	var (
+		b           []byte
+		err         error
+		contentType string
+	)
+	switch format {
+	case `xml`:
+		b, err = xml.Marshal(p)
+		b = append([]byte(xml.Header), b...)
+		contentType = MIMEApplicationProblemXMLCharsetUTF8
+	default:
+		b, err = json.Marshal(p)
+		contentType = MIMEApplicationProblemJSONCharsetUTF8
+	}
+	if err != nil {
+		return err
+	}
+	status := p.Status
+	if status < 1 {
+		status = http.StatusInternalServerError
+	}
+	c.Response().Header().Set(HeaderContentType, contentType)
+	return c.Blob(b, status)
+}
+
+// ProblemHTTPErrorHandler 当客户端协商到 json 或 xml 格式时输出 RFC 7807 问题详情，
+// 否则交给 fallback 处理(例如 render.HTTPErrorHandler 输出的 HTML 错误页面)
+func ProblemHTTPErrorHandler(fallback HTTPErrorHandler) HTTPErrorHandler {
+	return func(err error, c Context) {
+		format := c.Format()
+		if format != `json` && format != `xml` {
+			if fallback != nil {
+				fallback(err, c)
+			} else {
+				c.Echo().DefaultHTTPErrorHandler(err, c)
+			}
+			return
+		}
+		if !c.Response().Committed() {
+			p := AsProblem(err, c.Echo().Debug())
+			if c.Request().Method() == HEAD {
+				c.NoContent(p.Status)
+			} else if e := RenderProblem(c, p, format); e != nil {
+				c.Logger().Error(e)
+			}
+		}
+		c.Logger().Debug(err)
+	}
+}
@@ -0,0 +1,51 @@
+package echo
+
+import (
+	"encoding/xml"
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/webx-top/echo/encoding/json"
+)
+
+func TestAsProblem(t *testing.T) {
+	p := AsProblem(NewHTTPError(http.StatusNotFound))
+	assert.Equal(t, http.StatusNotFound, p.Status)
+	assert.Equal(t, `Not Found`, p.Title)
+	assert.Equal(t, ``, p.Detail)
+
+	p = AsProblem(NewHTTPError(http.StatusBadRequest, `invalid id`))
+	assert.Equal(t, `invalid id`, p.Detail)
+
+	err := http.ErrBodyNotAllowed
+	p = AsProblem(err)
+	assert.Equal(t, http.StatusInternalServerError, p.Status)
+	assert.Equal(t, ``, p.Detail)
+	assert.Equal(t, err, p.Unwrap())
+	p = AsProblem(err, true)
+	assert.Equal(t, err.Error(), p.Detail)
+
+	errs := FieldErrors{NewFieldError(`Name`, `Required`, nil, `Can not be empty`)}
+	p = AsProblem(errs)
+	assert.Equal(t, http.StatusUnprocessableEntity, p.Status)
+	b, _ := json.Marshal(p)
+	assert.Equal(t, `{"detail":"Can not be empty","errors":{"Name":[{"field":"Name","rule":"Required","message":"Can not be empty"}]},"status":422,"title":"Unprocessable Entity"}`, string(b))
+
+	// ValidateResult.Error() 返回的第一个字段错误包含全部字段错误
+	errs = append(errs, NewFieldError(`Email`, `Email`, nil, `Invalid email`))
+	result := NewValidateResult().SetErrors(errs)
+	result.SetError(errs[0])
+	ProblemTypeBase = `https://example.com/problems/`
+	p = AsProblem(result.Error())
+	ProblemTypeBase = ``
+	assert.Equal(t, `https://example.com/problems/validation-error`, p.Type)
+	assert.Equal(t, `Can not be empty`, p.Detail)
+	assert.Equal(t, errs, p.Extensions[`errors`])
+
+	wrapped := NewProblem(http.StatusConflict, `version mismatch`).SetCode(`E1001`).Set(`version`, 3)
+	assert.Equal(t, wrapped, AsProblem(NewProblem(http.StatusBadGateway).Wrap(wrapped)).Unwrap())
+	b, _ = xml.Marshal(wrapped)
+	assert.Equal(t, `<problem xmlns="urn:ietf:rfc:7807"><code>E1001</code><detail>version mismatch</detail><status>409</status><title>Conflict</title><version>3</version></problem>`, string(b))
+}
@@ -20,6 +20,7 @@ package echo
 
 import (
 	"log"
+	"time"
 )
 
 var (
@@ -47,6 +48,10 @@ type SessionOptions struct {
 	Engine string //Store Engine
 	Name   string //Session Name
 	*CookieOptions
+
+	// RefreshInterval 滑动过期的刷新间隔。大于 0 时，距离上次保存超过此间隔的会话
+	// 即使没有修改也会重新保存，以延长存储引擎和 cookie 的有效期
+	RefreshInterval time.Duration
 }
 
 func (s *SessionOptions) Clone() *SessionOptions {
@@ -80,6 +85,11 @@ type Sessioner interface {
 	Flashes(vars ...string) []interface{}
 	// Save saves all sessions used during the current request.
 	Save() error
+	// Regenerate 更换会话 ID 并保留会话数据，同时删除存储引擎中旧 ID 的数据。
+	// 用于登录等权限变更之后，防止会话固定攻击
+	Regenerate() error
+	// Destroy 删除存储引擎中的会话数据并使 cookie 过期
+	Destroy() error
 }
 
 type NopSession struct {
@@ -125,6 +135,14 @@ func (n *NopSession) Save() error {
 	return nil
 }
 
+func (n *NopSession) Regenerate() error {
+	return nil
+}
+
+func (n *NopSession) Destroy() error {
+	return nil
+}
+
 type DebugSession struct {
 }
 
@@ -177,3 +195,13 @@ func (n *DebugSession) Save() error {
 	log.Println(`DebugSession.Save`)
 	return nil
 }
+
+func (n *DebugSession) Regenerate() error {
+	log.Println(`DebugSession.Regenerate`)
+	return nil
+}
+
+func (n *DebugSession) Destroy() error {
+	log.Println(`DebugSession.Destroy`)
+	return nil
+}
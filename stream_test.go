@@ -0,0 +1,69 @@
+package echo_test
+
+import (
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	. "github.com/webx-top/echo"
+	test "github.com/webx-top/echo/testing"
+)
+
+// flushRecorder 每次 Flush 时发送已写入的内容
+type flushRecorder struct {
+	*httptest.ResponseRecorder
+	flushed chan string
+}
+
+func (r *flushRecorder) Flush() {
+	r.ResponseRecorder.Flush()
+	r.flushed <- r.Body.String()
+}
+
+type streamRow struct {
+	ID int `csv:"id" json:"id"`
+}
+
+func testStreaming(t *testing.T, render func(Context, <-chan streamRow) error, first string, all string) {
+	e := New()
+	rows := make(chan streamRow)
+	e.Get(`/`, func(c Context) error {
+		return render(c, rows)
+	})
+	e.RebuildRouter()
+	rec := &flushRecorder{ResponseRecorder: httptest.NewRecorder(), flushed: make(chan string, 16)}
+	received := make(chan string, 1)
+	go func() {
+		rows <- streamRow{ID: 1}
+		// 慢速数据源：在发送下一行之前，第一行必须已经发送到客户端
+		select {
+		case body := <-rec.flushed:
+			received <- body
+		case <-time.After(time.Second):
+			received <- ``
+		}
+		rows <- streamRow{ID: 2}
+		close(rows)
+		for range rec.flushed {
+		}
+	}()
+	req := test.NewStdRequest(GET, `/`)
+	e.ServeHTTP(test.WrapRequest(req), test.WrapResponse(req, rec))
+	close(rec.flushed)
+	assert.Equal(t, first, <-received)
+	assert.Equal(t, all, rec.Body.String())
+}
+
+func TestNDJSONFlush(t *testing.T) {
+	testStreaming(t, func(c Context, rows <-chan streamRow) error {
+		return c.NDJSON(rows)
+	}, "{\"id\":1}\n", "{\"id\":1}\n{\"id\":2}\n")
+}
+
+func TestCSVFlush(t *testing.T) {
+	testStreaming(t, func(c Context, rows <-chan streamRow) error {
+		return c.CSV(rows)
+	}, "id\n1\n", "id\n1\n2\n")
+}
@@ -0,0 +1,188 @@
+/*
+
+   Copyright 2016-present Wenhui Shen <www.webx.top>
+
+   Licensed under the Apache License, Version 2.0 (the "License");
+   you may not use this file except in compliance with the License.
+   You may obtain a copy of the License at
+
+       http://www.apache.org/licenses/LICENSE-2.0
+
+   Unless required by applicable law or agreed to in writing, software
+   distributed under the License is distributed on an "AS IS" BASIS,
+   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+   See the License for the specific language governing permissions and
+   limitations under the License.
+
+*/
+
+package echo
+
+import (
+	"errors"
+	"net/http"
+	"reflect"
+)
+
+// MetaKeySignature 类型化 handler 的签名在 Route.Meta 中的键名
+const MetaKeySignature = `signature`
+
+var (
+	typeOfContext = reflect.TypeOf((*Context)(nil)).Elem()
+	typeOfError   = reflect.TypeOf((*error)(nil)).Elem()
+
+	// TypedInternalErrors 绑定时返回这些错误属于程序错误，原样返回而不转换为 400 错误
+	TypedInternalErrors = []error{ErrNotProtoMessage}
+)
+
+// HandlerSignature 类型化 handler 的签名信息(用于生成接口文档)
+type HandlerSignature struct {
+	Input      reflect.Type `json:"-" xml:"-"`
+	Output     reflect.Type `json:"-" xml:"-"`
+	InputName  string       `json:"input,omitempty" xml:"input,omitempty"`
+	OutputName string       `json:"output,omitempty" xml:"output,omitempty"`
+}
+
+// ParseHandlerSignature 解析类型化 handler 的签名。支持以下形式(In 必须为结构体指针)：
+//   func(echo.Context, *In) (Out, error)
+//   func(echo.Context, *In) error
+//   func(echo.Context) (Out, error)
+// 不匹配时返回 nil
+func ParseHandlerSignature(h interface{}) *HandlerSignature {
+	t := reflect.TypeOf(h)
+	if t == nil || t.Kind() != reflect.Func || t.IsVariadic() {
+		return nil
+	}
+	if t.NumIn() < 1 || t.NumIn() > 2 || t.In(0) != typeOfContext {
+		return nil
+	}
+	if t.NumOut() < 1 || t.NumOut() > 2 || t.Out(t.NumOut()-1) != typeOfError {
+		return nil
+	}
+	if t.NumIn() == 1 && t.NumOut() == 1 { // func(Context) error
+		return nil
+	}
+	sig := &HandlerSignature{}
+	if t.NumIn() == 2 {
+		in := t.In(1)
+		if in.Kind() != reflect.Ptr || in.Elem().Kind() != reflect.Struct {
+			return nil
+		}
+		sig.Input = in
+		sig.InputName = in.Elem().String()
+	}
+	if t.NumOut() == 2 {
+		sig.Output = t.Out(0)
+		out := sig.Output
+		if out.Kind() == reflect.Ptr {
+			out = out.Elem()
+		}
+		sig.OutputName = out.String()
+	}
+	return sig
+}
+
+// TypedHandler 将类型化 handler 包装为 Handler，不匹配时返回 nil，因此可以用作 Echo.AddHandlerWrapper 的参数。
+// 调用前会自动绑定并验证输入结构体，返回值会根据内容协商的格式输出
+func TypedHandler(h interface{}) Handler {
+	sig := ParseHandlerSignature(h)
+	if sig == nil {
+		return nil
+	}
+	return &typedHandler{
+		fn:        reflect.ValueOf(h),
+		raw:       h,
+		signature: sig,
+	}
+}
+
+type typedHandler struct {
+	fn        reflect.Value
+	raw       interface{}
+	signature *HandlerSignature
+}
+
+func (h *typedHandler) Name() string {
+	return HandlerName(h.raw)
+}
+
+func (h *typedHandler) Meta() H {
+	return H{MetaKeySignature: h.signature}
+}
+
+func (h *typedHandler) Handle(c Context) error {
+	args := []reflect.Value{reflect.ValueOf(c)}
+	if h.signature.Input != nil {
+		in := reflect.New(h.signature.Input.Elem())
+		if err := bindTyped(c, in.Interface()); err != nil {
+			return err
+		}
+		result := c.Validate(in.Interface())
+		if errs := result.Errors(); len(errs) > 0 {
+			return errs
+		}
+		if err := result.Error(); err != nil {
+			return err
+		}
+		args = append(args, in)
+	}
+	results := h.fn.Call(args)
+	if err, _ := results[len(results)-1].Interface().(error); err != nil {
+		return err
+	}
+	if len(results) == 1 || c.Response().Committed() {
+		return nil
+	}
+	out := results[0]
+	switch out.Kind() {
+	case reflect.Ptr, reflect.Interface, reflect.Map, reflect.Slice:
+		if out.IsNil() {
+			return c.NoContent(http.StatusNoContent)
+		}
+	}
+	return renderTyped(c, out.Interface())
+}
+
+// bindTyped 绑定查询参数以及请求体(请求中含有 Content-Type 时)
+func bindTyped(c Context, i interface{}) error {
+	if queries := c.Queries(); len(queries) > 0 {
+		if err := NamedStructMap(c.Echo(), i, queries, ``); err != nil {
+			return NewHTTPError(http.StatusBadRequest, err.Error())
+		}
+	}
+	if len(c.Header(HeaderContentType)) == 0 {
+		return nil
+	}
+	err := c.MustBind(i)
+	if err == nil {
+		return nil
+	}
+	if _, ok := err.(*HTTPError); ok {
+		return err
+	}
+	for _, internal := range TypedInternalErrors {
+		if errors.Is(err, internal) {
+			return err
+		}
+	}
+	return NewHTTPError(http.StatusBadRequest, err.Error())
+}
+
+// renderTyped 按内容协商的格式输出结果，没有对应的格式渲染器(例如 html)时输出 JSON
+func renderTyped(c Context, data interface{}) error {
+	format := c.Format()
+	if len(format) == 0 {
+		return ErrNotAcceptable
+	}
+	render, ok := c.Echo().formatRenderers[format]
+	if !ok || render == nil {
+		render = c.Echo().formatRenderers[`json`]
+		if render == nil {
+			return c.JSON(data)
+		}
+	}
+	if _, ok := data.(Data); !ok {
+		c.Data().SetData(data, c.Data().GetCode().Int())
+	}
+	return render(c, data)
+}
@@ -0,0 +1,95 @@
+package echo_test
+
+import (
+	"errors"
+	"io/ioutil"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	. "github.com/webx-top/echo"
+	test "github.com/webx-top/echo/testing"
+)
+
+type typedUserInput struct {
+	Name string `json:"name"`
+}
+
+type typedUser struct {
+	ID   int    `json:"id"`
+	Name string `json:"name"`
+}
+
+func TestTypedHandler(t *testing.T) {
+	e := New()
+	e.Post("/users", func(c Context, in *typedUserInput) (*typedUser, error) {
+		if in.Name == `admin` {
+			return nil, NewHTTPError(http.StatusConflict, `name already exists`)
+		}
+		return &typedUser{ID: 1, Name: in.Name}, nil
+	})
+	e.Get("/users", func(c Context, in *typedUserInput) (*typedUser, error) {
+		return nil, errors.New(`unexpected`)
+	})
+	e.Delete("/users", func(c Context) (*typedUser, error) {
+		return nil, nil
+	})
+	g := e.Group("/admin")
+	g.Put("/users", g.MetaHandler(H{"doc": "update user"}, func(c Context, in *typedUserInput) (*typedUser, error) {
+		return &typedUser{ID: 2, Name: in.Name}, nil
+	}))
+	e.RebuildRouter()
+	jsonBody := func(body string) func(*http.Request) {
+		return func(req *http.Request) {
+			req.Header.Set(HeaderContentType, MIMEApplicationJSON)
+			req.Header.Set(HeaderAccept, MIMEApplicationJSON)
+			req.Body = ioutil.NopCloser(strings.NewReader(body))
+		}
+	}
+
+	rec := test.Request(POST, `/users`, e, jsonBody(`{"name":"echo"}`))
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.Contains(t, rec.Body.String(), `"Data":{"id":1,"name":"echo"}`)
+
+	rec = test.Request(POST, `/users`, e, jsonBody(`{"name":"admin"}`))
+	assert.Equal(t, http.StatusConflict, rec.Code)
+
+	rec = test.Request(POST, `/users`, e, jsonBody(`{"name":`))
+	assert.Equal(t, http.StatusBadRequest, rec.Code)
+
+	rec = test.Request(GET, `/users?name=echo`, e)
+	assert.Equal(t, http.StatusInternalServerError, rec.Code)
+
+	// 绑定目标不是 proto.Message 属于程序错误
+	rec = test.Request(POST, `/users`, e, func(req *http.Request) {
+		req.Header.Set(HeaderContentType, MIMEApplicationProtobuf)
+		req.Body = ioutil.NopCloser(strings.NewReader(`x`))
+	})
+	assert.Equal(t, http.StatusInternalServerError, rec.Code)
+
+	rec = test.Request(PUT, `/admin/users`, e, jsonBody(`{"name":"group"}`))
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.Contains(t, rec.Body.String(), `"Data":{"id":2,"name":"group"}`)
+
+	rec = test.Request(DELETE, `/users`, e)
+	assert.Equal(t, http.StatusNoContent, rec.Code)
+
+	for _, r := range e.Routes() {
+		sig, ok := r.Meta[MetaKeySignature].(*HandlerSignature)
+		assert.True(t, ok)
+		assert.Equal(t, `echo_test.typedUser`, sig.OutputName)
+		if r.Method == DELETE {
+			assert.Nil(t, sig.Input)
+		} else {
+			assert.Equal(t, `echo_test.typedUserInput`, sig.InputName)
+		}
+		if r.Prefix == `/admin` {
+			assert.Equal(t, `update user`, r.Meta[`doc`])
+		}
+	}
+
+	assert.Nil(t, TypedHandler(func(c Context) error { return nil }))
+	assert.Nil(t, TypedHandler(func(c Context, in typedUserInput) error { return nil }))
+}
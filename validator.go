@@ -20,7 +20,10 @@ package echo
 
 import (
 	"errors"
+	"reflect"
+	"strings"
 
+	"github.com/webx-top/echo/encoding/json"
 	"github.com/webx-top/validation"
 )
 
@@ -34,11 +37,13 @@ type ValidateResult interface {
 	Error() error
 	Field() string
 	Raw() interface{}
+	Errors() FieldErrors
 
 	//setter
 	SetError(error) ValidateResult
 	SetField(string) ValidateResult
 	SetRaw(interface{}) ValidateResult
+	SetErrors(FieldErrors) ValidateResult
 }
 
 func NewValidateResult() ValidateResult {
@@ -47,8 +52,9 @@ func NewValidateResult() ValidateResult {
 
 type ValidatorResult struct {
 	error
-	field string
-	raw   interface{}
+	field  string
+	raw    interface{}
+	errors FieldErrors
 }
 
 func (v *ValidatorResult) Ok() bool {
@@ -67,6 +73,11 @@ func (v *ValidatorResult) Raw() interface{} {
 	return v.raw
 }
 
+// Errors 返回全部字段错误
+func (v *ValidatorResult) Errors() FieldErrors {
+	return v.errors
+}
+
 func (v *ValidatorResult) SetError(err error) ValidateResult {
 	v.error = err
 	return v
@@ -82,6 +93,132 @@ func (v *ValidatorResult) SetRaw(raw interface{}) ValidateResult {
 	return v
 }
 
+func (v *ValidatorResult) SetErrors(errs FieldErrors) ValidateResult {
+	for _, e := range errs {
+		e.all = errs
+	}
+	v.errors = errs
+	return v
+}
+
+// FieldError 字段验证错误
+type FieldError struct {
+	Field   string      `json:"field" xml:"field"`             //字段路径(例如：Profile.Email)
+	Rule    string      `json:"rule" xml:"rule"`               //验证规则名称(例如：Required)
+	Params  interface{} `json:"params,omitempty" xml:"params"` //验证规则参数
+	Message string      `json:"message" xml:"message"`
+	tmpl    string      //带参数占位符的信息模板
+	text    string      //没有模板时用于翻译的原始信息
+	all     FieldErrors //同一次验证的全部错误
+}
+
+// NewFieldError 创建字段错误
+func NewFieldError(field string, rule string, params interface{}, message string) *FieldError {
+	return &FieldError{
+		Field:   field,
+		Rule:    rule,
+		Params:  params,
+		Message: message,
+		text:    message,
+	}
+}
+
+func (f *FieldError) Error() string {
+	return f.Message
+}
+
+// Translate 用翻译器翻译错误信息
+func (f *FieldError) Translate(t Translator) *FieldError {
+	if len(f.tmpl) > 0 {
+		f.Message = t.T(f.tmpl, validateParams(f.Params)...)
+	} else if len(f.text) > 0 {
+		f.Message = t.T(f.text)
+	}
+	return f
+}
+
+func validateParams(params interface{}) []interface{} {
+	if params == nil {
+		return nil
+	}
+	v := reflect.ValueOf(params)
+	switch v.Kind() {
+	case reflect.Slice, reflect.Array:
+		args := make([]interface{}, v.Len())
+		for i := 0; i < v.Len(); i++ {
+			args[i] = v.Index(i).Interface()
+		}
+		return args
+	default:
+		return []interface{}{params}
+	}
+}
+
+// FieldErrors 全部字段的验证错误
+type FieldErrors []*FieldError
+
+func (f FieldErrors) Error() string {
+	msgs := make([]string, len(f))
+	for i, e := range f {
+		msgs[i] = e.Error()
+	}
+	return strings.Join(msgs, "\n")
+}
+
+// Field 返回某个字段的所有错误
+func (f FieldErrors) Field(field string) FieldErrors {
+	var r FieldErrors
+	for _, e := range f {
+		if e.Field == field {
+			r = append(r, e)
+		}
+	}
+	return r
+}
+
+// Map 按字段路径分组
+func (f FieldErrors) Map() map[string][]*FieldError {
+	r := map[string][]*FieldError{}
+	for _, e := range f {
+		r[e.Field] = append(r[e.Field], e)
+	}
+	return r
+}
+
+// Translate 用翻译器翻译全部错误信息
+func (f FieldErrors) Translate(t Translator) FieldErrors {
+	for _, e := range f {
+		e.Translate(t)
+	}
+	return f
+}
+
+func (f FieldErrors) MarshalJSON() ([]byte, error) {
+	return json.Marshal(f.Map())
+}
+
+// NewFieldErrors 将 validation 的错误列表转换为 FieldErrors
+func NewFieldErrors(errs []*validation.ValidationError) FieldErrors {
+	r := make(FieldErrors, len(errs))
+	for i, e := range errs {
+		field := e.Field
+		if len(e.Key) > 0 && len(e.Name) > 0 {
+			field = strings.TrimSuffix(e.Key, `.`+e.Name)
+		}
+		r[i] = &FieldError{
+			Field:   field,
+			Rule:    e.Name,
+			Params:  e.LimitValue,
+			Message: e.Message,
+			tmpl:    e.Tmpl,
+		}
+		if len(r[i].tmpl) == 0 {
+			r[i].text = e.Message
+		}
+	}
+	return r
+}
+
 var (
 	DefaultNopValidate     Validator = &NopValidation{}
 	defaultValidatorResult           = NewValidateResult()
@@ -134,10 +271,11 @@ func (v *Validation) Validate(i interface{}, args ...string) ValidateResult {
 		return e.SetError(err)
 	}
 	if v.validator.HasError() {
-		vErr := v.validator.Errors[0]
-		e.SetError(vErr)
-		e.SetField(vErr.Field)
+		errs := NewFieldErrors(v.validator.Errors)
+		e.SetError(errs[0])
+		e.SetField(errs[0].Field)
 		e.SetRaw(v.validator.Errors)
+		e.SetErrors(errs)
 		v.validator.Errors = nil
 	}
 	return e
@@ -0,0 +1,41 @@
+package echo
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/webx-top/echo/encoding/json"
+)
+
+type testTranslate struct {
+	NopTranslate
+}
+
+func (t *testTranslate) T(format string, args ...interface{}) string {
+	return fmt.Sprintf(`[zh]`+format, args...)
+}
+
+func TestFieldErrors(t *testing.T) {
+	errs := FieldErrors{
+		{Field: `Name`, Rule: `Required`, Message: `Can not be empty`, tmpl: `Can not be empty`},
+		{Field: `Profile.Age`, Rule: `Range`, Params: []int{1, 140}, Message: `Range is 1 to 140`, tmpl: `Range is %d to %d`},
+		{Field: `Profile.Age`, Rule: `Numeric`, Message: `Must be valid numeric characters`},
+	}
+	assert.Equal(t, "Can not be empty\nRange is 1 to 140\nMust be valid numeric characters", errs.Error())
+	assert.Equal(t, 2, len(errs.Field(`Profile.Age`)))
+
+	b, err := json.Marshal(errs)
+	assert.NoError(t, err)
+	assert.Equal(t, `{"Name":[{"field":"Name","rule":"Required","message":"Can not be empty"}],"Profile.Age":[{"field":"Profile.Age","rule":"Range","params":[1,140],"message":"Range is 1 to 140"},{"field":"Profile.Age","rule":"Numeric","message":"Must be valid numeric characters"}]}`, string(b))
+
+	errs.Translate(&testTranslate{})
+	assert.Equal(t, `[zh]Can not be empty`, errs[0].Message)
+	assert.Equal(t, `[zh]Range is 1 to 140`, errs[1].Message)
+	assert.Equal(t, `Must be valid numeric characters`, errs[2].Message)
+
+	// 没有模板时不使用参数
+	plain := NewFieldError(`Age`, `Min`, 18, `Too young`).Translate(&testTranslate{})
+	assert.Equal(t, `[zh]Too young`, plain.Message)
+}
@@ -53,9 +53,13 @@ var (
 	ErrForbidden                   error = NewHTTPError(http.StatusForbidden)
 	ErrStatusRequestEntityTooLarge error = NewHTTPError(http.StatusRequestEntityTooLarge)
 	ErrMethodNotAllowed            error = NewHTTPError(http.StatusMethodNotAllowed)
+	ErrNotAcceptable               error = NewHTTPError(http.StatusNotAcceptable)
+	ErrNotModified                 error = NewHTTPError(http.StatusNotModified)
+	ErrPreconditionFailed          error = NewHTTPError(http.StatusPreconditionFailed)
 	ErrRendererNotRegistered             = errors.New("renderer not registered")
 	ErrInvalidRedirectCode               = errors.New("invalid redirect status code")
 	ErrNotFoundFileInput                 = errors.New("The specified name file input was not found")
+	ErrNotProtoMessage                   = errors.New("bind target must implement proto.Message")
 
 	//----------------
 	// Error handlers
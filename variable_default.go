@@ -21,8 +21,12 @@ package echo
 import (
 	"encoding/xml"
 	"fmt"
+	"io/ioutil"
 	"net/http"
 
+	"github.com/golang/protobuf/proto"
+	"github.com/vmihailenco/msgpack"
+
 	"github.com/webx-top/echo/encoding/json"
 )
 
@@ -37,6 +41,32 @@ var (
 		`application/xml`: `xml`,
 		`text/xml`:        `xml`,
 
+		//problem details
+		MIMEApplicationProblemJSON: `json`,
+		MIMEApplicationProblemXML:  `xml`,
+
+		//protobuf
+		MIMEApplicationProtobuf:  `protobuf`,
+		MIMEApplicationXProtobuf: `protobuf`,
+
+		//msgpack
+		MIMEApplicationMsgpack:  `msgpack`,
+		MIMEApplicationXMsgpack: `msgpack`,
+
+		//yaml
+		MIMEApplicationYAML: `yaml`,
+		`application/yaml`:  `yaml`,
+		`text/yaml`:         `yaml`,
+		`text/x-yaml`:       `yaml`,
+
+		//csv
+		MIMETextCSV: `csv`,
+
+		//ndjson
+		MIMEApplicationNDJSON:   `ndjson`,
+		`application/ndjson`:    `ndjson`,
+		`application/jsonlines`: `ndjson`,
+
 		//text
 		`text/plain`: `text`,
 
@@ -58,6 +88,30 @@ var (
 		`xml`: func(c Context, data interface{}) error {
 			return c.XML(c.Data())
 		},
+		`protobuf`: func(c Context, data interface{}) error {
+			if m, ok := data.(proto.Message); ok {
+				return c.ProtoBuf(m)
+			}
+			if m, ok := c.Data().GetData().(proto.Message); ok {
+				return c.ProtoBuf(m)
+			}
+			return NewHTTPError(http.StatusNotAcceptable, "Response data is not a protobuf message")
+		},
+		`msgpack`: func(c Context, data interface{}) error {
+			return c.MsgPack(c.Data())
+		},
+		`yaml`: func(c Context, data interface{}) error {
+			return c.YAML(c.Data())
+		},
+		`yml`: func(c Context, data interface{}) error {
+			return c.YAML(c.Data())
+		},
+		`csv`: func(c Context, data interface{}) error {
+			return c.CSV(formatRows(c, data))
+		},
+		`ndjson`: func(c Context, data interface{}) error {
+			return c.NDJSON(formatRows(c, data))
+		},
 		`text`: func(c Context, data interface{}) error {
 			return c.String(fmt.Sprint(data))
 		},
@@ -79,6 +133,12 @@ var (
 			defer body.Close()
 			return xml.NewDecoder(body).Decode(i)
 		},
+		MIMEApplicationProtobuf:   bindProtobuf,
+		MIMEApplicationXProtobuf:  bindProtobuf,
+		MIMEApplicationMsgpack:    bindMsgpack,
+		MIMEApplicationXMsgpack:   bindMsgpack,
+		MIMEApplicationJSONPatch:  bindJSONPatch,
+		MIMEApplicationMergePatch: bindMergePatch,
 		MIMEApplicationForm: func(i interface{}, ctx Context, filter ...FormDataFilter) error {
 			body := ctx.Request().Body()
 			if body == nil {
@@ -101,3 +161,40 @@ var (
 		return v
 	}
 )
+
+func bindProtobuf(i interface{}, ctx Context, filter ...FormDataFilter) error {
+	m, ok := i.(proto.Message)
+	if !ok {
+		return ErrNotProtoMessage
+	}
+	body := ctx.Request().Body()
+	if body == nil {
+		return NewHTTPError(http.StatusBadRequest, "Request body can't be nil")
+	}
+	defer body.Close()
+	b, err := ioutil.ReadAll(body)
+	if err != nil {
+		return err
+	}
+	return proto.Unmarshal(b, m)
+}
+
+func bindMsgpack(i interface{}, ctx Context, filter ...FormDataFilter) error {
+	body := ctx.Request().Body()
+	if body == nil {
+		return NewHTTPError(http.StatusBadRequest, "Request body can't be nil")
+	}
+	defer body.Close()
+	return msgpack.NewDecoder(body).Decode(i)
+}
+
+// formatRows 取得用于逐行输出的数据
+func formatRows(c Context, data interface{}) interface{} {
+	if IsIterable(data) {
+		return data
+	}
+	if rows := c.Data().GetData(); rows != nil {
+		return rows
+	}
+	return data
+}
@@ -51,6 +51,9 @@ func WrapHandler(h interface{}) Handler {
 			return v(ctx.Response().StdResponseWriter(), ctx.Request().StdRequest())
 		})
 	}
+	if v := TypedHandler(h); v != nil {
+		return v
+	}
 	panic(fmt.Sprintf(`unknown handler: %T`, h))
 }
 